@@ -0,0 +1,487 @@
+package iflytek
+
+import (
+	"errors"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dglogger "github.com/darwinOrg/go-logger"
+	"sync"
+	"time"
+)
+
+type AgentState int
+
+const (
+	AgentStateUnknown  AgentState = 0
+	AgentStateOffline  AgentState = 1
+	AgentStateOnline   AgentState = 2
+	AgentStateBusy     AgentState = 3
+	AgentStateUnbound  AgentState = 4
+	AgentStateInactive AgentState = 5
+
+	cnoStatusOnline = 1
+	cnoActive       = 1
+
+	defaultAgentReconcileInterval = 30 * time.Second
+	defaultAgentRetryTimes        = 3
+	defaultAgentRetryInterval     = time.Second
+	defaultAgentEventBufferSize   = 128
+)
+
+var (
+	AgentNotManagedErr = errors.New("agent not managed")
+	AgentOfflineErr    = errors.New("agent is offline")
+	AgentUnboundErr    = errors.New("agent has no bound tel")
+	AgentInactiveErr   = errors.New("agent is not active")
+)
+
+func (s AgentState) String() string {
+	switch s {
+	case AgentStateOffline:
+		return "offline"
+	case AgentStateOnline:
+		return "online"
+	case AgentStateBusy:
+		return "busy"
+	case AgentStateUnbound:
+		return "unbound"
+	case AgentStateInactive:
+		return "inactive"
+	default:
+		return "unknown"
+	}
+}
+
+// AgentEvent 坐席状态变化事件
+type AgentEvent struct {
+	Cno  string     `json:"cno"`
+	From AgentState `json:"from"`
+	To   AgentState `json:"to"`
+	Time time.Time  `json:"time"`
+	Err  error      `json:"-"`
+}
+
+type AgentManagerConfig struct {
+	ReconcileInterval time.Duration `json:"reconcileInterval"` // 定时对账间隔，默认30秒
+	RetryTimes        int           `json:"retryTimes"`        // 可重试错误的重试次数，默认3次
+	RetryInterval     time.Duration `json:"retryInterval"`     // 首次重试间隔，之后每次翻倍，默认1秒
+	EventBufferSize   int           `json:"eventBufferSize"`   // 事件缓冲大小，缓冲满时丢弃新事件，默认128
+}
+
+type agentEntry struct {
+	cno        string
+	online     bool
+	onlineReq  *OnlineReq
+	offlineReq *OfflineReq
+	state      AgentState
+	calls      int
+	checkedAt  time.Time
+}
+
+// AgentManager 维护坐席的期望状态，并与 DetailByCno 查询到的实际状态对账
+type AgentManager struct {
+	client *Client
+	config *AgentManagerConfig
+	mu     sync.Mutex
+	agents map[string]*agentEntry
+	events chan *AgentEvent
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func NewAgentManager(client *Client, config *AgentManagerConfig) *AgentManager {
+	if config == nil {
+		config = &AgentManagerConfig{}
+	}
+	if config.ReconcileInterval <= 0 {
+		config.ReconcileInterval = defaultAgentReconcileInterval
+	}
+	if config.RetryTimes <= 0 {
+		config.RetryTimes = defaultAgentRetryTimes
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = defaultAgentRetryInterval
+	}
+	if config.EventBufferSize <= 0 {
+		config.EventBufferSize = defaultAgentEventBufferSize
+	}
+
+	return &AgentManager{
+		client: client,
+		config: config,
+		agents: map[string]*agentEntry{},
+		events: make(chan *AgentEvent, config.EventBufferSize),
+	}
+}
+
+// Events 坐席状态变化事件流
+func (m *AgentManager) Events() <-chan *AgentEvent {
+	return m.events
+}
+
+// SetOnline 设置坐席期望在线，下一次对账时生效
+func (m *AgentManager) SetOnline(onlineReq *OnlineReq) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.getOrCreateEntry(onlineReq.Cno)
+	entry.online = true
+	entry.onlineReq = onlineReq
+}
+
+// SetOffline 设置坐席期望离线，下一次对账时生效
+func (m *AgentManager) SetOffline(offlineReq *OfflineReq) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.getOrCreateEntry(offlineReq.Cno)
+	entry.online = false
+	entry.offlineReq = offlineReq
+}
+
+// Remove 不再管理该坐席，不会改变坐席的实际状态
+func (m *AgentManager) Remove(cno string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.agents, cno)
+}
+
+// State 最近一次对账得到的坐席状态
+func (m *AgentManager) State(cno string) AgentState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.agents[cno]
+	if !ok {
+		return AgentStateUnknown
+	}
+
+	return entry.state
+}
+
+// States 所有受管坐席的状态
+func (m *AgentManager) States() map[string]AgentState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	states := make(map[string]AgentState, len(m.agents))
+	for cno, entry := range m.agents {
+		states[cno] = entry.state
+	}
+
+	return states
+}
+
+// Start 启动后台定时对账
+func (m *AgentManager) Start(ctx *dgctx.DgContext) {
+	m.mu.Lock()
+	if m.stop != nil {
+		m.mu.Unlock()
+		return
+	}
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	stop, done := m.stop, m.done
+	m.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(m.config.ReconcileInterval)
+		defer ticker.Stop()
+
+		for {
+			m.Reconcile(ctx)
+
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop 停止后台对账并等待当前一轮结束
+func (m *AgentManager) Stop() {
+	m.mu.Lock()
+	stop, done := m.stop, m.done
+	m.stop, m.done = nil, nil
+	m.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Reconcile 对所有受管坐席执行一次对账
+func (m *AgentManager) Reconcile(ctx *dgctx.DgContext) {
+	m.mu.Lock()
+	cnos := make([]string, 0, len(m.agents))
+	for cno := range m.agents {
+		cnos = append(cnos, cno)
+	}
+	m.mu.Unlock()
+
+	for _, cno := range cnos {
+		_, err := m.ReconcileAgent(ctx, cno)
+		if err != nil {
+			dglogger.Errorf(ctx, "AgentManager reconcile cno[%s] err: %v", cno, err)
+		}
+	}
+}
+
+// ReconcileAgent 查询坐席实际状态，必要时调用 Online/Offline 使其与期望状态一致
+func (m *AgentManager) ReconcileAgent(ctx *dgctx.DgContext, cno string) (AgentState, error) {
+	m.mu.Lock()
+	entry, ok := m.agents[cno]
+	if !ok {
+		m.mu.Unlock()
+		return AgentStateUnknown, AgentNotManagedErr
+	}
+	online, onlineReq, offlineReq := entry.online, entry.onlineReq, entry.offlineReq
+	m.mu.Unlock()
+
+	detail, err := m.detail(ctx, cno)
+	if err != nil {
+		return m.updateState(cno, AgentStateUnknown, err), err
+	}
+
+	observed := observeAgentState(detail)
+	switch {
+	case online && (observed == AgentStateOffline || observed == AgentStateUnbound && onlineReq.BindTel != ""):
+		err = m.retry(ctx, "Online", func() error {
+			_, err := m.client.Online(ctx, onlineReq)
+			return err
+		})
+	case !online && (observed == AgentStateOnline || observed == AgentStateUnbound) && !m.isBusy(cno):
+		if offlineReq == nil {
+			offlineReq = &OfflineReq{Cno: cno}
+		}
+		err = m.retry(ctx, "Offline", func() error {
+			_, err := m.client.Offline(ctx, offlineReq)
+			return err
+		})
+	default:
+		return m.updateState(cno, observed, nil), nil
+	}
+
+	if err != nil {
+		return m.updateState(cno, observed, err), err
+	}
+
+	detail, err = m.detail(ctx, cno)
+	if err != nil {
+		return m.updateState(cno, AgentStateUnknown, err), err
+	}
+
+	return m.updateState(cno, observeAgentState(detail), nil), nil
+}
+
+// Callout 坐席在线且已绑定电话时发起外呼，并将坐席标记为忙碌，通话结束后需调用 CallEnded；
+// 返回超时、连接中断或 5xx 等不确定的错误时坐席同样保持忙碌
+func (m *AgentManager) Callout(ctx *dgctx.DgContext, calloutReq *CalloutReq) (*CalloutResp, error) {
+	if err := m.checkCallable(ctx, calloutReq.Cno); err != nil {
+		return nil, err
+	}
+
+	m.callStarted(calloutReq.Cno)
+	calloutResp, err := m.client.Callout(ctx, calloutReq)
+	if err != nil {
+		// 结果不确定时外呼可能已经发出，坐席保持忙碌，由调用方在挂机或确认未接通后调用 CallEnded
+		var e *Error
+		if !errors.As(err, &e) || !e.ambiguous() {
+			m.CallEnded(calloutReq.Cno)
+		}
+		return nil, err
+	}
+
+	return calloutResp, nil
+}
+
+// CallEnded 通话结束（挂机、取消或未接通）后释放坐席
+func (m *AgentManager) CallEnded(cno string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.agents[cno]
+	if !ok || entry.calls == 0 {
+		return
+	}
+
+	entry.calls--
+	if entry.calls == 0 && entry.state == AgentStateBusy {
+		m.transit(entry, AgentStateOnline, nil)
+	}
+}
+
+func (m *AgentManager) checkCallable(ctx *dgctx.DgContext, cno string) error {
+	m.mu.Lock()
+	entry, ok := m.agents[cno]
+	if !ok {
+		m.mu.Unlock()
+		return AgentNotManagedErr
+	}
+	state, checkedAt := entry.state, entry.checkedAt
+	m.mu.Unlock()
+
+	if state == AgentStateUnknown || time.Since(checkedAt) > m.config.ReconcileInterval {
+		detail, err := m.detail(ctx, cno)
+		if err != nil {
+			m.updateState(cno, AgentStateUnknown, err)
+			return err
+		}
+		state = m.updateState(cno, observeAgentState(detail), nil)
+	}
+
+	switch state {
+	case AgentStateOnline, AgentStateBusy:
+		return nil
+	case AgentStateUnbound:
+		return AgentUnboundErr
+	case AgentStateInactive:
+		return AgentInactiveErr
+	default:
+		return AgentOfflineErr
+	}
+}
+
+func (m *AgentManager) callStarted(cno string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.agents[cno]
+	if !ok {
+		return
+	}
+
+	entry.calls++
+	if entry.state == AgentStateOnline {
+		m.transit(entry, AgentStateBusy, nil)
+	}
+}
+
+// calls 坐席当前占用的通话数
+func (m *AgentManager) calls(cno string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry, ok := m.agents[cno]; ok {
+		return entry.calls
+	}
+
+	return 0
+}
+
+func (m *AgentManager) isBusy(cno string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.agents[cno]
+	return ok && entry.calls > 0
+}
+
+func (m *AgentManager) detail(ctx *dgctx.DgContext, cno string) (*CnoDetailResp, error) {
+	var detail *CnoDetailResp
+	err := m.retry(ctx, "DetailByCno", func() error {
+		var err error
+		detail, err = m.client.DetailByCno(ctx, &CnoReq{Cno: cno})
+		return err
+	})
+
+	return detail, err
+}
+
+// retry 只重试 IsRetryable 的错误，ctx 关联的 context 取消时停止重试
+func (m *AgentManager) retry(ctx *dgctx.DgContext, name string, fn func() error) error {
+	interval := m.config.RetryInterval
+	done := GetCancelContext(ctx)
+	var err error
+
+	for i := 0; i <= m.config.RetryTimes; i++ {
+		if i > 0 {
+			dglogger.Warnf(ctx, "AgentManager %s retry %d after err: %v", name, i, err)
+			timer := time.NewTimer(interval)
+			select {
+			case <-timer.C:
+			case <-done.Done():
+				timer.Stop()
+				return err
+			}
+			interval *= 2
+		}
+
+		err = fn()
+		if err == nil || !IsRetryable(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+func (m *AgentManager) updateState(cno string, observed AgentState, err error) AgentState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.agents[cno]
+	if !ok {
+		return observed
+	}
+
+	if observed == AgentStateOnline && entry.calls > 0 {
+		observed = AgentStateBusy
+	}
+	if observed != AgentStateOnline && observed != AgentStateBusy {
+		entry.calls = 0
+	}
+	if err == nil {
+		entry.checkedAt = time.Now()
+	}
+	if entry.state != observed || err != nil {
+		m.transit(entry, observed, err)
+	}
+
+	return observed
+}
+
+// transit 需在持有锁时调用
+func (m *AgentManager) transit(entry *agentEntry, to AgentState, err error) {
+	event := &AgentEvent{Cno: entry.cno, From: entry.state, To: to, Time: time.Now(), Err: err}
+	entry.state = to
+
+	select {
+	case m.events <- event:
+	default:
+	}
+}
+
+func (m *AgentManager) getOrCreateEntry(cno string) *agentEntry {
+	entry, ok := m.agents[cno]
+	if !ok {
+		entry = &agentEntry{cno: cno}
+		m.agents[cno] = entry
+	}
+
+	return entry
+}
+
+func observeAgentState(detail *CnoDetailResp) AgentState {
+	if detail == nil {
+		return AgentStateUnknown
+	}
+
+	client := detail.Client
+	if client.Active != cnoActive {
+		return AgentStateInactive
+	}
+	if client.Status != cnoStatusOnline {
+		return AgentStateOffline
+	}
+	if client.BindTel == "" {
+		return AgentStateUnbound
+	}
+
+	return AgentStateOnline
+}
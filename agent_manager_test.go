@@ -0,0 +1,131 @@
+package iflytek_test
+
+import (
+	dgctx "github.com/darwinOrg/go-common/context"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAgentManager(t *testing.T) {
+	var mu sync.Mutex
+	status := 0
+	calloutStatus := http.StatusOK
+	details := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/cc/describe_client":
+			cno := r.URL.Query().Get("cno")
+			details[cno]++
+			if cno == "2002" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"requestId":"5","error":{"code":"InvalidParameter","message":"cno"}}`))
+				return
+			}
+			if cno == "3003" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			bindTel := ""
+			if status == 1 {
+				bindTel = "13800000000"
+			}
+			_, _ = w.Write([]byte(`{"requestId":"1","client":{"cno":"1001","bindTel":"` + bindTel + `","active":1,"status":` + strconv.Itoa(status) + `}}`))
+		case "/cc/online":
+			status = 1
+			_, _ = w.Write([]byte(`{"requestId":"2"}`))
+		case "/cc/offline":
+			status = 0
+			_, _ = w.Write([]byte(`{"requestId":"3"}`))
+		case "/cc/callout":
+			if calloutStatus != http.StatusOK {
+				w.WriteHeader(calloutStatus)
+				return
+			}
+			_, _ = w.Write([]byte(`{"requestId":"4","result":{"cno":"1001"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := &dgctx.DgContext{TraceId: "123"}
+	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{Host: server.URL})
+	manager := dgkdxf.NewAgentManager(client, &dgkdxf.AgentManagerConfig{RetryInterval: time.Millisecond})
+
+	manager.SetOffline(&dgkdxf.OfflineReq{Cno: "1001"})
+	state, err := manager.ReconcileAgent(ctx, "1001")
+	if err != nil || state != dgkdxf.AgentStateOffline {
+		t.Fatalf("state: %s, err: %v", state, err)
+	}
+
+	if _, err = manager.Callout(ctx, &dgkdxf.CalloutReq{Cno: "1001", CustomerNumber: "13900000000"}); err != dgkdxf.AgentOfflineErr {
+		t.Fatalf("callout offline agent err: %v", err)
+	}
+
+	manager.SetOnline(&dgkdxf.OnlineReq{Cno: "1001", BindType: 1, BindTel: "13800000000"})
+	state, err = manager.ReconcileAgent(ctx, "1001")
+	if err != nil || state != dgkdxf.AgentStateOnline {
+		t.Fatalf("state: %s, err: %v", state, err)
+	}
+
+	if _, err = manager.Callout(ctx, &dgkdxf.CalloutReq{Cno: "1001", CustomerNumber: "13900000000"}); err != nil {
+		t.Fatal(err)
+	}
+	if manager.State("1001") != dgkdxf.AgentStateBusy {
+		t.Fatalf("state after callout: %s", manager.State("1001"))
+	}
+
+	manager.CallEnded("1001")
+	if manager.State("1001") != dgkdxf.AgentStateOnline {
+		t.Fatalf("state after call ended: %s", manager.State("1001"))
+	}
+
+	var transitions []dgkdxf.AgentState
+	for len(manager.Events()) > 0 {
+		transitions = append(transitions, (<-manager.Events()).To)
+	}
+	expected := []dgkdxf.AgentState{dgkdxf.AgentStateOffline, dgkdxf.AgentStateOnline, dgkdxf.AgentStateBusy, dgkdxf.AgentStateOnline}
+	if len(transitions) != len(expected) {
+		t.Fatalf("transitions: %v", transitions)
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Fatalf("transitions: %v", transitions)
+		}
+	}
+
+	// 外呼结果不确定时坐席保持忙碌，不会短暂回到在线被其他外呼选中
+	mu.Lock()
+	calloutStatus = http.StatusBadGateway
+	mu.Unlock()
+	if _, err = manager.Callout(ctx, &dgkdxf.CalloutReq{Cno: "1001", CustomerNumber: "13900000000"}); err == nil {
+		t.Fatal("callout should fail")
+	}
+	if manager.State("1001") != dgkdxf.AgentStateBusy || len(manager.Events()) != 1 {
+		t.Fatalf("state after ambiguous callout: %s, events: %d", manager.State("1001"), len(manager.Events()))
+	}
+	<-manager.Events()
+
+	// 只重试可重试的错误
+	manager.SetOffline(&dgkdxf.OfflineReq{Cno: "2002"})
+	manager.SetOffline(&dgkdxf.OfflineReq{Cno: "3003"})
+	if _, err := manager.ReconcileAgent(ctx, "2002"); err == nil {
+		t.Fatal("invalid cno should fail")
+	}
+	if _, err := manager.ReconcileAgent(ctx, "3003"); !dgkdxf.IsRetryable(err) {
+		t.Fatalf("unavailable: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if details["2002"] != 1 || details["3003"] != 4 {
+		t.Fatalf("describe_client calls: %v", details)
+	}
+}
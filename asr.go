@@ -3,16 +3,13 @@ package iflytek
 import (
 	"bufio"
 	"encoding/json"
-	"errors"
 	"fmt"
 	dgcoll "github.com/darwinOrg/go-common/collection"
 	dgctx "github.com/darwinOrg/go-common/context"
-	dgerr "github.com/darwinOrg/go-common/enums/error"
 	"github.com/darwinOrg/go-common/model"
 	"github.com/darwinOrg/go-common/utils"
 	dghttp "github.com/darwinOrg/go-httpclient"
 	dglogger "github.com/darwinOrg/go-logger"
-	"github.com/google/uuid"
 	"io"
 	"net/http"
 	"os"
@@ -90,6 +87,11 @@ type Json1best struct {
 	} `json:"st"`
 }
 
+// Finished 订单是否已完成转写
+func (r *AsrResult) Finished() bool {
+	return r.Content.OrderInfo.Status == orderFinishedStatus
+}
+
 func (r *AsrResult) String() string {
 	j, err := json.Marshal(r)
 	if err != nil {
@@ -108,11 +110,7 @@ func (o *OrderResult) Convert2Subtitles() []*Subtitles {
 	var subtitlesBuilder strings.Builder
 	var subtitlesBegin int
 
-	for _, lattice := range o.Lattice {
-		if lattice.Json1best == "" {
-			continue
-		}
-		json1best := utils.MustConvertJsonStringToBean[Json1best](lattice.Json1best)
+	for _, json1best := range o.json1bests() {
 		latticeBegin, _ := strconv.ParseInt(json1best.St.Bg, 10, 0)
 
 		for _, rt := range json1best.St.Rt {
@@ -145,24 +143,38 @@ func (o *OrderResult) Convert2Subtitles() []*Subtitles {
 
 func (o *OrderResult) String() string {
 	var totalContent strings.Builder
+	for _, json1best := range o.json1bests() {
+		totalContent.WriteString(fmt.Sprintf("发言人%s: %s\n", json1best.St.Rl, json1best.text()))
+	}
+
+	return totalContent.String()
+}
+
+// json1bests 解析每个 lattice 的 json_1best，跳过为空的
+func (o *OrderResult) json1bests() []*Json1best {
+	var list []*Json1best
 	for _, lattice := range o.Lattice {
 		if lattice.Json1best == "" {
 			continue
 		}
-		json1best := utils.MustConvertJsonStringToBean[Json1best](lattice.Json1best)
-		var itemStr strings.Builder
-		rl := json1best.St.Rl
-		for _, rt := range json1best.St.Rt {
-			for _, ws := range rt.Ws {
-				for _, cw := range ws.Cw {
-					itemStr.WriteString(cw.W)
-				}
+		list = append(list, utils.MustConvertJsonStringToBean[Json1best](lattice.Json1best))
+	}
+
+	return list
+}
+
+// text 拼接一句中的全部词
+func (j *Json1best) text() string {
+	var text strings.Builder
+	for _, rt := range j.St.Rt {
+		for _, ws := range rt.Ws {
+			for _, cw := range ws.Cw {
+				text.WriteString(cw.W)
 			}
 		}
-		totalContent.WriteString(fmt.Sprintf("发言人%s: %s\n", rl, itemStr.String()))
 	}
 
-	return totalContent.String()
+	return text.String()
 }
 
 type sizedReader struct {
@@ -193,13 +205,64 @@ func (c *Client) AsrUpload(dc *dgctx.DgContext, filePath string, duration int64,
 		}
 	}()
 
-	uploadFileName := filepath.Base(filePath)
-	params := c.buildUploadParams(uploadFileName, fileSize, duration, callbackUrl)
+	return c.AsrUploadReader(dc, file, filepath.Base(filePath), duration, fileSize, callbackUrl)
+}
+
+type AsrUploadOptions struct {
+	FileName    string // 上传使用的文件名，默认为文件路径的文件名
+	CallbackUrl string // 转写完成的回调地址
+}
+
+// AsrUploadFile 上传录音文件，时长和文件大小通过 ProbeAudio 自动获取
+func (c *Client) AsrUploadFile(ctx *dgctx.DgContext, filePath string, opts *AsrUploadOptions) (*AsrUploadResult, error) {
+	if opts == nil {
+		opts = &AsrUploadOptions{}
+	}
+	fileName := opts.FileName
+	if fileName == "" {
+		fileName = filepath.Base(filePath)
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		dglogger.Errorf(ctx, "AsrUploadFile open file err: %v", err)
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := ProbeAudio(file)
+	if err != nil {
+		dglogger.Errorf(ctx, "AsrUploadFile probe %s err: %v", filePath, err)
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	dglogger.Infof(ctx, "AsrUploadFile %s: %s/%s, %dms, %d bytes", fileName, info.Container, info.Codec, info.DurationMillis, info.Size)
+
+	return c.AsrUploadReader(ctx, file, fileName, info.DurationMillis, info.Size, opts.CallbackUrl)
+}
+
+// AsrUploadReader 从 reader 读取录音内容上传到科大讯飞，fileSize 需与 reader 的内容长度一致
+func (c *Client) AsrUploadReader(dc *dgctx.DgContext, r io.Reader, uploadFileName string, duration int64, fileSize int64, callbackUrl string) (_ *AsrUploadResult, err error) {
+	call, err := c.startCall(dc, ApiAsrUpload)
+	if err != nil {
+		dglogger.Errorf(dc, "sdk Upload rate limit err: %v", err)
+		return nil, err
+	}
+	defer func() { err = call.end(err) }()
+
+	creds, err := c.credentials()
+	if err != nil {
+		dglogger.Errorf(dc, "sdk Upload credentials err: %v", err)
+		return nil, err
+	}
+	params := c.buildUploadParams(creds, uploadFileName, fileSize, duration, callbackUrl)
 	parameters := utils.FormUrlEncodedParams(params)
-	signature := c.GenerateSignature(params)
-	uploadUrl := c.Config.Host + "/v2/upload?" + parameters
+	signature := creds.sign(asrStringToSign(params))
+	uploadUrl := c.Config.ServiceHost(ServiceAsr) + "/v2/upload?" + parameters
 	reader := &sizedReader{
-		r: bufio.NewReaderSize(file, defaultBufferSize),
+		r: bufio.NewReaderSize(r, defaultBufferSize),
 	}
 
 	req, err := http.NewRequest(http.MethodPost, uploadUrl, reader)
@@ -207,6 +270,7 @@ func (c *Client) AsrUpload(dc *dgctx.DgContext, filePath string, duration int64,
 		dglogger.Errorf(dc, "sdk Upload http.NewRequest err: %v", err)
 		return nil, err
 	}
+	call.setHeaders(req.Header)
 	req.Header.Set("Content-Type", "application/octet-stream")
 	req.Header["signature"] = []string{signature}
 
@@ -215,12 +279,14 @@ func (c *Client) AsrUpload(dc *dgctx.DgContext, filePath string, duration int64,
 		dglogger.Errorf(dc, "sdk Upload Client.Do err: %v", err)
 		return nil, err
 	}
+	call.statusCode = response.StatusCode
 
-	dglogger.Infof(dc, "sdk Upload %s file success, uploaded bytes size: %d, file size is:%d,url %s", uploadFileName, reader.readSize, fileSize, uploadUrl)
+	call.bytesSent = reader.readSize
+	dglogger.Infof(dc, "sdk Upload %s file success, uploaded bytes size: %d, file size is:%d,url %s", uploadFileName, reader.readSize, fileSize, c.redactUrl(uploadUrl))
 
 	if response.StatusCode != http.StatusOK {
 		dglogger.Errorf(dc, "sdk Upload http.Post statusCode: %d", response.StatusCode)
-		return nil, errors.New("upload call asr-service failed")
+		return nil, call.statusErr(response)
 	}
 
 	ret, err := dghttp.ConvertResponse2Struct[AsrUploadResult](response)
@@ -229,47 +295,65 @@ func (c *Client) AsrUpload(dc *dgctx.DgContext, filePath string, duration int64,
 		return nil, err
 	}
 
+	call.code = ret.Code
 	if ret.Code != apiSuccessCode {
 		dglogger.Errorf(dc, "sdk Upload asr-service failed: %s", ret.String())
-		return ret, ApiNoSuccessErr
+		return ret, call.err(ApiNoSuccessErr, ret.DescInfo)
 	}
 
 	return ret, nil
 }
 
 // GetAsrResult 获取科大讯飞的识别结果 api结果内容,音频识别内容,失败原因,error
-func (c *Client) GetAsrResult(ctx *dgctx.DgContext, orderId string) (*AsrResult, error) {
-	params := c.buildGetResultParams(orderId)
+func (c *Client) GetAsrResult(ctx *dgctx.DgContext, orderId string) (_ *AsrResult, err error) {
+	call, err := c.startCall(ctx, ApiAsrGetResult)
+	if err != nil {
+		dglogger.Errorf(ctx, "sdk GetResult rate limit err: %v", err)
+		return nil, err
+	}
+	defer func() { err = call.end(err) }()
+
+	creds, err := c.credentials()
+	if err != nil {
+		dglogger.Errorf(ctx, "sdk GetResult credentials err: %v", err)
+		return nil, err
+	}
+	params := c.buildGetResultParams(creds, orderId)
 	formUrlString := utils.FormUrlEncodedParams(params)
-	signature := c.GenerateSignature(params)
-	resultUrl := c.Config.Host + "/v2/getResult?" + formUrlString
+	signature := creds.sign(asrStringToSign(params))
+	resultUrl := c.Config.ServiceHost(ServiceAsr) + "/v2/getResult?" + formUrlString
 
 	dghttp.SetHttpClient(ctx, dghttp.Client11)
 	defer dghttp.SetHttpClient(ctx, nil)
-	ret, err := dghttp.DoGetToStruct[AsrResult](ctx, resultUrl, nil, map[string]string{"signature": signature})
+	headers := call.headers()
+	headers["signature"] = signature
+	ret, err := dghttp.DoGetToStruct[AsrResult](ctx, resultUrl, nil, headers)
 	if err != nil {
-		dglogger.Errorf(ctx, "dghttp.DoGetToStruct error | resultUrl: %s | err: %v", resultUrl, err)
+		dglogger.Errorf(ctx, "dghttp.DoGetToStruct error | resultUrl: %s | err: %v", c.redactUrl(resultUrl), err)
 		return nil, err
 	}
 	if ret == nil {
-		return nil, dgerr.SYSTEM_ERROR
+		return nil, call.err(EmptyResponseErr, "")
 	}
 
+	call.code = ret.Code
 	if ret.Code != apiSuccessCode {
 		dglogger.Errorf(ctx, "sdk GetResult asr-service failed: %s", ret.String())
-		return ret, ApiNoSuccessErr
+		return ret, call.err(ApiNoSuccessErr, ret.DescInfo)
 	}
 
 	orderInfo := ret.Content.OrderInfo
 	if orderInfo.FailType != 0 {
 		dglogger.Errorf(ctx, "sdk GetResult asr-service failed: %s", ret.String())
-		reason := "order failType: " + strconv.FormatInt(int64(orderInfo.FailType), 10)
-		return ret, errors.New(reason)
+		failErr := call.err(ApiGetResultFailTypeErr, ret.DescInfo)
+		failErr.Code = strconv.Itoa(orderInfo.FailType)
+		return ret, failErr
 	}
 
 	dglogger.Infof(ctx, "sdk GetResult orderId: %s,orderStatus: %d", orderId, orderInfo.Status)
 	// 订单已完成的时候,解析识别结果
 	if orderInfo.Status == orderFinishedStatus {
+		c.usage.addOrder(orderId, orderInfo.RealDuration)
 		ret.Content.OrderResult, err = utils.ConvertJsonStringToBean[OrderResult](ret.Content.OrderResultString)
 		if err != nil {
 			dglogger.Errorf(ctx, "sdk GetResult json.Unmarshal orderResult err: %v", err)
@@ -280,19 +364,19 @@ func (c *Client) GetAsrResult(ctx *dgctx.DgContext, orderId string) (*AsrResult,
 	return ret, nil
 }
 
-func (c *Client) buildUploadParams(filename string, filesize int64, duration int64, callbackUrl string) []*model.KeyValuePair[string, any] {
+func (c *Client) buildUploadParams(creds *Credentials, filename string, filesize int64, duration int64, callbackUrl string) []*model.KeyValuePair[string, any] {
 	params := []*model.KeyValuePair[string, any]{
 		{
 			Key:   "dateTime",
-			Value: getDateTimeString(),
+			Value: c.getDateTimeString(),
 		},
 		{
 			Key:   "accessKeyId",
-			Value: c.Config.AccessKeyId,
+			Value: creds.AccessKeyId,
 		},
 		{
 			Key:   "signatureRandom",
-			Value: uuid.NewString(),
+			Value: c.randomId(),
 		},
 		{
 			Key:   "fileName",
@@ -332,19 +416,19 @@ func (c *Client) buildUploadParams(filename string, filesize int64, duration int
 	return params
 }
 
-func (c *Client) buildGetResultParams(orderId string) []*model.KeyValuePair[string, any] {
+func (c *Client) buildGetResultParams(creds *Credentials, orderId string) []*model.KeyValuePair[string, any] {
 	params := []*model.KeyValuePair[string, any]{
 		{
 			Key:   "dateTime",
-			Value: getDateTimeString(),
+			Value: c.getDateTimeString(),
 		},
 		{
 			Key:   "accessKeyId",
-			Value: c.Config.AccessKeyId,
+			Value: creds.AccessKeyId,
 		},
 		{
 			Key:   "signatureRandom",
-			Value: uuid.NewString(),
+			Value: c.randomId(),
 		},
 		{
 			Key:   "orderId",
@@ -0,0 +1,774 @@
+package iflytek
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	dgcoll "github.com/darwinOrg/go-common/collection"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dglogger "github.com/darwinOrg/go-logger"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type AsrJobStatus string
+
+const (
+	AsrJobStatusPending  AsrJobStatus = "pending"  // 等待上传
+	AsrJobStatusUploaded AsrJobStatus = "uploaded" // 已上传，等待转写完成
+	AsrJobStatusDone     AsrJobStatus = "done"     // 结果已写入输出目录
+	AsrJobStatusFailed   AsrJobStatus = "failed"
+
+	defaultAsrBatchUploadConcurrency = 4
+	defaultAsrBatchPollConcurrency   = 4
+	defaultAsrBatchPollInterval      = 30 * time.Second
+	defaultAsrBatchMaxUploadAttempts = 3
+
+	// journal 中的行数超过任务数的倍数时，打开时压缩为每个任务一行
+	asrJobJournalCompactRatio = 2
+)
+
+var (
+	AsrBatchStoppedErr  = errors.New("asr batch stopped")
+	AsrUploadUnknownErr = errors.New("asr upload result unknown")
+	DuplicateAsrJobErr  = errors.New("duplicate asr job")
+)
+
+type AsrJob struct {
+	Id       string       `json:"id"` // 任务标识，同时作为输出文件名
+	FilePath string       `json:"filePath"`
+	Status   AsrJobStatus `json:"status"`
+	OrderId  string       `json:"orderId,omitempty"`
+	Attempts int          `json:"attempts"` // 上传次数
+	Error    string       `json:"error,omitempty"`
+	// MaybeUploaded 最后一次上传已发出但没有明确结果，讯飞可能已创建订单，重新上传前需要确认以免重复转写和计费；
+	// 上传前先保存为 true，进程在上传过程中退出时重启后可以识别
+	MaybeUploaded bool      `json:"maybeUploaded,omitempty"`
+	UploadedAt    time.Time `json:"uploadedAt,omitempty"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// AsrJobStore 保存批量转写任务的状态，Get 在没有记录时返回 nil, nil，实现方需保证并发安全
+type AsrJobStore interface {
+	Get(id string) (*AsrJob, error)
+	Put(job *AsrJob) error
+	List() ([]*AsrJob, error)
+}
+
+type MemoryAsrJobStore struct {
+	mu   sync.RWMutex
+	jobs map[string]*AsrJob
+}
+
+func NewMemoryAsrJobStore() *MemoryAsrJobStore {
+	return &MemoryAsrJobStore{jobs: map[string]*AsrJob{}}
+}
+
+func (s *MemoryAsrJobStore) Get(id string) (*AsrJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *job
+
+	return &copied, nil
+}
+
+func (s *MemoryAsrJobStore) Put(job *AsrJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *job
+	s.jobs[job.Id] = &copied
+
+	return nil
+}
+
+func (s *MemoryAsrJobStore) List() ([]*AsrJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedAsrJobs(s.jobs), nil
+}
+
+// FileAsrJobStore 将全部任务以 json 保存在 Path 文件中，每次写入时整体替换，适合几百个以内的任务
+type FileAsrJobStore struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (s *FileAsrJobStore) Get(id string) (*AsrJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs[id], nil
+}
+
+func (s *FileAsrJobStore) Put(job *AsrJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs, err := s.load()
+	if err != nil {
+		return err
+	}
+	copied := *job
+	jobs[job.Id] = &copied
+
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.Path, data)
+}
+
+func (s *FileAsrJobStore) List() ([]*AsrJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return sortedAsrJobs(jobs), nil
+}
+
+// load 文件不存在时没有任务；文件为空或内容损坏时返回错误，避免下一次 Put 覆盖掉全部任务
+func (s *FileAsrJobStore) load() (map[string]*AsrJob, error) {
+	jobs := map[string]*AsrJob{}
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return jobs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("asr job file %s is empty", s.Path)
+	}
+
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("parse asr job file %s: %w", s.Path, err)
+	}
+
+	return jobs, nil
+}
+
+// JournalAsrJobStore 内嵌的追加日志存储，每次 Put 追加一行 json 并 fsync，打开时回放日志，
+// 不依赖数据库，适合一次处理上万个任务；进程崩溃导致的末尾不完整行在打开时截掉
+type JournalAsrJobStore struct {
+	path string
+	mu   sync.RWMutex
+	file *os.File
+	jobs map[string]*AsrJob
+}
+
+// OpenJournalAsrJobStore 打开或创建日志文件，日志过长时先压缩
+func OpenJournalAsrJobStore(path string) (*JournalAsrJobStore, error) {
+	s := &JournalAsrJobStore{path: path, jobs: map[string]*AsrJob{}}
+
+	lines, err := s.replay()
+	if err != nil {
+		return nil, err
+	}
+	if lines > asrJobJournalCompactRatio*len(s.jobs) {
+		if err := s.compact(); err != nil {
+			return nil, err
+		}
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	s.file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *JournalAsrJobStore) replay() (int, error) {
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	lines := 0
+	var complete int64 // 最后一个完整行的结束位置
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// 没有换行符的末尾是崩溃时写了一半的行，截掉后追加的行才能从行首开始
+			if len(line) > 0 {
+				return lines, os.Truncate(s.path, complete)
+			}
+			return lines, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		complete += int64(len(line))
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		lines++
+		job := &AsrJob{}
+		if err := json.Unmarshal(line, job); err != nil || job.Id == "" {
+			continue
+		}
+		s.jobs[job.Id] = job
+	}
+}
+
+func (s *JournalAsrJobStore) compact() error {
+	var buf bytes.Buffer
+	for _, job := range sortedAsrJobs(s.jobs) {
+		data, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	return writeFileAtomic(s.path, buf.Bytes())
+}
+
+func (s *JournalAsrJobStore) Get(id string) (*AsrJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *job
+
+	return &copied, nil
+}
+
+func (s *JournalAsrJobStore) Put(job *AsrJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	copied := *job
+	s.jobs[job.Id] = &copied
+
+	return nil
+}
+
+func (s *JournalAsrJobStore) List() ([]*AsrJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedAsrJobs(s.jobs), nil
+}
+
+func (s *JournalAsrJobStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+func sortedAsrJobs(jobs map[string]*AsrJob) []*AsrJob {
+	list := make([]*AsrJob, 0, len(jobs))
+	for _, job := range jobs {
+		copied := *job
+		list = append(list, &copied)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Id < list[j].Id })
+
+	return list
+}
+
+// writeFileAtomic 写入同目录的临时文件并 fsync 后重命名，再 fsync 目录使重命名在断电后也能保留
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := file.Chmod(0644); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return file.Sync()
+}
+
+type AsrBatchOptions struct {
+	OutputDir         string            `json:"outputDir" binding:"required"` // 每个任务输出 <id>.json 和各格式的字幕
+	SubtitlesFormats  []SubtitlesFormat `json:"subtitlesFormats"`             // 默认 srt
+	UploadConcurrency int               `json:"uploadConcurrency"`            // 同时上传的文件数，默认4，限流使用 ClientConfig.RateLimits
+	PollConcurrency   int               `json:"pollConcurrency"`              // 同时查询的订单数，默认4
+	PollInterval      time.Duration     `json:"pollInterval"`                 // 两轮查询之间的间隔，默认30秒
+	Timeout           time.Duration     `json:"timeout"`                      // 上传后等待转写完成的最长时间，默认2小时
+	MaxUploadAttempts int               `json:"maxUploadAttempts"`            // 可重试错误的最大上传次数，默认3
+	// OrderNotFoundCodes 查询结果时表示订单不存在的业务码，返回这些码时任务失败；
+	// 其他业务码和可重试的错误继续查询，直到超过 Timeout
+	OrderNotFoundCodes []string          `json:"orderNotFoundCodes"`
+	CallbackUrl        string            `json:"callbackUrl"`
+	OnJobUpdate        func(job *AsrJob) `json:"-"` // 任务状态变化后执行，用于输出进度
+}
+
+type AsrBatchSummary struct {
+	Total    int `json:"total"`
+	Pending  int `json:"pending"`
+	Uploaded int `json:"uploaded"`
+	Done     int `json:"done"`
+	Failed   int `json:"failed"`
+}
+
+// AsrJobResult 写入输出目录的 <id>.json
+type AsrJobResult struct {
+	Id           string       `json:"id"`
+	FilePath     string       `json:"filePath"`
+	OrderId      string       `json:"orderId"`
+	RealDuration int          `json:"realDuration"` // 毫秒
+	Text         string       `json:"text"`
+	Subtitles    []*Subtitles `json:"subtitles"`
+}
+
+// AsrBatch 批量上传录音并等待转写结果，任务状态保存在 AsrJobStore 中；
+// 重启后已上传的任务继续按 orderId 查询结果，不会重新上传。
+// asr.upload 不是幂等接口，请求已发出但没有明确结果（超时、连接中断、5xx，或上传过程中进程退出）时不重试，
+// 任务失败并设置 AsrJob.MaybeUploaded，避免重复创建订单。保存任务状态失败时停止整个批次
+type AsrBatch struct {
+	client   *Client
+	store    AsrJobStore
+	opts     *AsrBatchOptions
+	mu       sync.Mutex
+	stop     chan struct{}
+	storeErr error
+}
+
+func NewAsrBatch(client *Client, store AsrJobStore, opts *AsrBatchOptions) (*AsrBatch, error) {
+	if opts == nil || opts.OutputDir == "" {
+		return nil, errors.New("outputDir is required")
+	}
+	if len(opts.SubtitlesFormats) == 0 {
+		opts.SubtitlesFormats = []SubtitlesFormat{SubtitlesFormatSrt}
+	}
+	for _, format := range opts.SubtitlesFormats {
+		if err := WriteSubtitles(&bytes.Buffer{}, nil, format); err != nil {
+			return nil, err
+		}
+	}
+	if opts.UploadConcurrency <= 0 {
+		opts.UploadConcurrency = defaultAsrBatchUploadConcurrency
+	}
+	if opts.PollConcurrency <= 0 {
+		opts.PollConcurrency = defaultAsrBatchPollConcurrency
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultAsrBatchPollInterval
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTranscribeTimeout
+	}
+	if opts.MaxUploadAttempts <= 0 {
+		opts.MaxUploadAttempts = defaultAsrBatchMaxUploadAttempts
+	}
+
+	return &AsrBatch{client: client, store: store, opts: opts, stop: make(chan struct{})}, nil
+}
+
+// Add 以文件名（不含扩展名）作为任务标识添加待上传的文件，已存在的任务保持原状态；
+// 不同路径得到相同标识时返回 DuplicateAsrJobErr
+func (b *AsrBatch) Add(filePaths ...string) error {
+	for _, filePath := range filePaths {
+		id := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+		if err := b.AddJob(id, filePath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddJob 添加待上传的文件，id 用作输出文件名
+func (b *AsrBatch) AddJob(id string, filePath string) error {
+	if id == "" || id != filepath.Base(id) {
+		return fmt.Errorf("invalid asr job id %q", id)
+	}
+
+	job, err := b.store.Get(id)
+	if err != nil {
+		return err
+	}
+	if job != nil {
+		if job.FilePath != filePath {
+			return fmt.Errorf("%w: %s is used by %s and %s", DuplicateAsrJobErr, id, job.FilePath, filePath)
+		}
+		return nil
+	}
+
+	return b.store.Put(&AsrJob{Id: id, FilePath: filePath, Status: AsrJobStatusPending, UpdatedAt: time.Now()})
+}
+
+// Run 上传全部待上传的任务并查询已上传任务的结果，所有任务进入终态后返回统计，调用 Stop 后返回 AsrBatchStoppedErr，
+// 保存任务状态失败时返回该错误
+func (b *AsrBatch) Run(ctx *dgctx.DgContext) (*AsrBatchSummary, error) {
+	if err := os.MkdirAll(b.opts.OutputDir, 0755); err != nil {
+		return nil, err
+	}
+	jobs, err := b.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	queue := make(chan *AsrJob)
+	uploadsDone := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < b.opts.UploadConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				b.upload(ctx, job)
+			}
+		}()
+	}
+	go func() {
+		defer close(uploadsDone)
+		defer wg.Wait()
+		defer close(queue)
+		for _, job := range jobs {
+			if job.Status != AsrJobStatusPending {
+				continue
+			}
+			select {
+			case queue <- job:
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(b.opts.PollInterval)
+	defer ticker.Stop()
+	uploading := true
+	for {
+		// 先查询重启前已上传的任务，不等待上传完成
+		if err := b.pollRound(ctx); err != nil {
+			dglogger.Errorf(ctx, "AsrBatch poll err: %v", err)
+		}
+
+		summary, err := b.Summary()
+		if err != nil {
+			dglogger.Errorf(ctx, "AsrBatch summary err: %v", err)
+		} else if !uploading && summary.Uploaded == 0 {
+			return summary, b.err()
+		}
+
+		select {
+		case <-b.stop:
+			if uploading {
+				<-uploadsDone
+			}
+			summary, _ := b.Summary()
+			if err := b.err(); err != nil {
+				return summary, err
+			}
+			return summary, AsrBatchStoppedErr
+		case <-uploadsDone:
+			if uploading {
+				uploading = false
+				uploadsDone = nil
+				// 上传结束后立即检查是否还有需要查询的任务
+				continue
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop 停止发起新的上传和查询，正在进行的上传完成后 Run 返回
+func (b *AsrBatch) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	select {
+	case <-b.stop:
+	default:
+		close(b.stop)
+	}
+}
+
+// fail 记录第一个保存失败的错误并停止批次
+func (b *AsrBatch) fail(err error) {
+	b.mu.Lock()
+	if b.storeErr == nil {
+		b.storeErr = err
+	}
+	b.mu.Unlock()
+
+	b.Stop()
+}
+
+func (b *AsrBatch) err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.storeErr
+}
+
+func (b *AsrBatch) stopped() bool {
+	select {
+	case <-b.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *AsrBatch) Summary() (*AsrBatchSummary, error) {
+	jobs, err := b.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &AsrBatchSummary{Total: len(jobs)}
+	for _, job := range jobs {
+		switch job.Status {
+		case AsrJobStatusPending:
+			summary.Pending++
+		case AsrJobStatusUploaded:
+			summary.Uploaded++
+		case AsrJobStatusDone:
+			summary.Done++
+		case AsrJobStatusFailed:
+			summary.Failed++
+		}
+	}
+
+	return summary, nil
+}
+
+// upload 可重试的错误按 PollInterval 间隔重试，次数用尽或不可重试时任务失败
+func (b *AsrBatch) upload(ctx *dgctx.DgContext, job *AsrJob) {
+	if job.MaybeUploaded {
+		// 上次上传过程中进程退出，讯飞可能已创建订单
+		dglogger.Warnf(ctx, "AsrBatch upload job[%s] result is unknown, iflytek may have created an order", job.Id)
+		job.Status, job.Error = AsrJobStatusFailed, AsrUploadUnknownErr.Error()
+		_ = b.save(ctx, job)
+		return
+	}
+
+	for !b.stopped() {
+		job.Attempts++
+		job.MaybeUploaded = true
+		if err := b.save(ctx, job); err != nil {
+			return
+		}
+		ret, err := b.client.AsrUploadFile(ctx, job.FilePath, &AsrUploadOptions{CallbackUrl: b.opts.CallbackUrl})
+		if err == nil {
+			job.Status, job.OrderId, job.Error, job.UploadedAt = AsrJobStatusUploaded, ret.Content.OrderId, "", time.Now()
+			job.MaybeUploaded = false
+			_ = b.save(ctx, job)
+			return
+		}
+
+		dglogger.Errorf(ctx, "AsrBatch upload job[%s] attempt %d err: %v", job.Id, job.Attempts, err)
+		job.Error = err.Error()
+		var e *Error
+		job.MaybeUploaded = errors.As(err, &e) && e.ambiguous()
+		if job.MaybeUploaded {
+			dglogger.Warnf(ctx, "AsrBatch upload job[%s] result is unknown, iflytek may have created an order", job.Id)
+		}
+		if !IsRetryable(err) || job.Attempts >= b.opts.MaxUploadAttempts {
+			job.Status = AsrJobStatusFailed
+			_ = b.save(ctx, job)
+			return
+		}
+		if err := b.save(ctx, job); err != nil {
+			return
+		}
+
+		select {
+		case <-b.stop:
+		case <-time.After(b.opts.PollInterval):
+		}
+	}
+}
+
+// pollRound 查询一轮全部已上传的任务
+func (b *AsrBatch) pollRound(ctx *dgctx.DgContext) error {
+	jobs, err := b.store.List()
+	if err != nil {
+		return err
+	}
+
+	queue := make(chan *AsrJob)
+	var wg sync.WaitGroup
+	for i := 0; i < b.opts.PollConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				b.poll(ctx, job)
+			}
+		}()
+	}
+	for _, job := range jobs {
+		if job.Status != AsrJobStatusUploaded || b.stopped() {
+			continue
+		}
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+
+	return nil
+}
+
+func (b *AsrBatch) poll(ctx *dgctx.DgContext, job *AsrJob) {
+	ret, err := b.client.GetAsrResult(ctx, job.OrderId)
+	switch {
+	case err == nil && ret.Finished():
+		if err := b.writeResult(job, ret); err != nil {
+			dglogger.Errorf(ctx, "AsrBatch write job[%s] result err: %v", job.Id, err)
+			return
+		}
+		job.Status, job.Error = AsrJobStatusDone, ""
+	case errors.Is(err, ApiGetResultFailTypeErr) || b.orderNotFound(err):
+		job.Status, job.Error = AsrJobStatusFailed, err.Error()
+	case time.Since(job.UploadedAt) > b.opts.Timeout:
+		job.Status, job.Error = AsrJobStatusFailed, TranscribeTimeoutErr.Error()
+	default:
+		if err != nil {
+			dglogger.Errorf(ctx, "AsrBatch poll job[%s] order[%s] err: %v", job.Id, job.OrderId, err)
+		}
+		return
+	}
+
+	_ = b.save(ctx, job)
+}
+
+// orderNotFound 订单级别的错误，再查询也不会成功
+func (b *AsrBatch) orderNotFound(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) || !errors.Is(err, ApiNoSuccessErr) || IsRetryable(err) {
+		return false
+	}
+
+	return dgcoll.Contains(b.opts.OrderNotFoundCodes, e.Code)
+}
+
+func (b *AsrBatch) writeResult(job *AsrJob, ret *AsrResult) error {
+	orderResult := ret.Content.OrderResult
+	if orderResult == nil {
+		orderResult = &OrderResult{}
+	}
+	subtitles := orderResult.Convert2Subtitles()
+
+	for _, format := range b.opts.SubtitlesFormats {
+		var buf bytes.Buffer
+		if err := WriteSubtitles(&buf, subtitles, format); err != nil {
+			return err
+		}
+		if err := writeFileAtomic(filepath.Join(b.opts.OutputDir, job.Id+"."+string(format)), buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(&AsrJobResult{
+		Id:           job.Id,
+		FilePath:     job.FilePath,
+		OrderId:      job.OrderId,
+		RealDuration: ret.Content.OrderInfo.RealDuration,
+		Text:         orderResult.String(),
+		Subtitles:    subtitles,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// json 最后写入，存在即表示该任务的输出完整
+	return writeFileAtomic(filepath.Join(b.opts.OutputDir, job.Id+".json"), data)
+}
+
+// save 保存任务状态，失败时停止批次，Run 返回该错误
+func (b *AsrBatch) save(ctx *dgctx.DgContext, job *AsrJob) error {
+	job.UpdatedAt = time.Now()
+	if err := b.store.Put(job); err != nil {
+		dglogger.Errorf(ctx, "AsrBatch save job[%s] err: %v", job.Id, err)
+		b.fail(fmt.Errorf("save asr job %s: %w", job.Id, err))
+		return err
+	}
+	if b.opts.OnJobUpdate != nil {
+		copied := *job
+		b.opts.OnJobUpdate(&copied)
+	}
+
+	return nil
+}
@@ -0,0 +1,352 @@
+package iflytek_test
+
+import (
+	"encoding/json"
+	"errors"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAsrBatch(t *testing.T) {
+	var mu sync.Mutex
+	uploads := map[string]int{}
+	polls := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/v2/upload":
+			fileName := r.URL.Query().Get("fileName")
+			uploads[fileName]++
+			_, _ = w.Write([]byte(`{"code":"000000","content":{"orderId":"order-` + strings.TrimSuffix(fileName, ".wav") + `"}}`))
+		case "/v2/getResult":
+			orderId := r.URL.Query().Get("orderId")
+			polls[orderId]++
+			if orderId == "order-bad" {
+				_, _ = w.Write([]byte(`{"code":"000000","content":{"orderInfo":{"status":-1,"failType":6}}}`))
+				return
+			}
+			if polls[orderId] == 1 {
+				_, _ = w.Write([]byte(`{"code":"000000","content":{"orderInfo":{"status":3}}}`))
+				return
+			}
+			json1best, _ := json.Marshal(map[string]any{"st": map[string]any{
+				"bg": "500", "ed": "2000", "rl": "1", "rt": []any{map[string]any{"ws": []any{
+					map[string]any{"wb": 0, "we": 100, "cw": []any{map[string]any{"w": "你好"}}},
+					map[string]any{"wb": 100, "we": 150, "cw": []any{map[string]any{"w": "。"}}},
+				}}},
+			}})
+			orderResult, _ := json.Marshal(map[string]any{"lattice": []any{map[string]string{"json_1best": string(json1best)}}})
+			resp, _ := json.Marshal(map[string]any{"code": "000000", "content": map[string]any{
+				"orderInfo":   map[string]any{"orderId": orderId, "status": 4, "realDuration": 1000},
+				"orderResult": string(orderResult),
+			}})
+			_, _ = w.Write(resp)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "bad"} {
+		if err := os.WriteFile(filepath.Join(dir, name+".wav"), testWav(16000, 16000), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 模拟崩溃前 resumed 已上传
+	store, err := dgkdxf.OpenJournalAsrJobStore(filepath.Join(dir, "jobs.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(&dgkdxf.AsrJob{Id: "resumed", FilePath: filepath.Join(dir, "resumed.wav"), Status: dgkdxf.AsrJobStatusUploaded, OrderId: "order-resumed", UploadedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := &dgctx.DgContext{TraceId: "123"}
+	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{Host: server.URL})
+	outputDir := filepath.Join(dir, "out")
+	batch, err := dgkdxf.NewAsrBatch(client, store, &dgkdxf.AsrBatchOptions{
+		OutputDir:        outputDir,
+		SubtitlesFormats: []dgkdxf.SubtitlesFormat{dgkdxf.SubtitlesFormatSrt, dgkdxf.SubtitlesFormatVtt},
+		PollInterval:     10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Add(filepath.Join(dir, "a.wav"), filepath.Join(dir, "b.wav"), filepath.Join(dir, "bad.wav")); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Add(filepath.Join(dir, "other", "a.wav")); err == nil {
+		t.Fatal("duplicate job id should fail")
+	}
+
+	summary, err := batch.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Total != 4 || summary.Done != 3 || summary.Failed != 1 {
+		t.Fatalf("summary: %+v", summary)
+	}
+
+	mu.Lock()
+	if uploads["resumed.wav"] != 0 || uploads["a.wav"] != 1 || polls["order-resumed"] != 2 {
+		t.Fatalf("uploads: %v, polls: %v", uploads, polls)
+	}
+	mu.Unlock()
+
+	var result dgkdxf.AsrJobResult
+	data, err := os.ReadFile(filepath.Join(outputDir, "a.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &result); err != nil || result.OrderId != "order-a" || result.RealDuration != 1000 || len(result.Subtitles) != 1 {
+		t.Fatalf("result: %s, %v", data, err)
+	}
+	vtt, err := os.ReadFile(filepath.Join(outputDir, "a.vtt"))
+	if err != nil || !strings.Contains(string(vtt), "00:00:00.500 --> 00:00:02.000") {
+		t.Fatalf("vtt: %q, %v", vtt, err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "bad.json")); !os.IsNotExist(err) {
+		t.Fatalf("failed job should not have output: %v", err)
+	}
+
+	// 重新打开后状态不变，再次运行不会重复上传
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	store, err = dgkdxf.OpenJournalAsrJobStore(filepath.Join(dir, "jobs.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	job, err := store.Get("bad")
+	if err != nil || job == nil || job.Status != dgkdxf.AsrJobStatusFailed || job.Attempts != 1 {
+		t.Fatalf("bad job: %+v, %v", job, err)
+	}
+	batch, _ = dgkdxf.NewAsrBatch(client, store, &dgkdxf.AsrBatchOptions{OutputDir: outputDir, PollInterval: 10 * time.Millisecond})
+	if summary, err := batch.Run(ctx); err != nil || summary.Done != 3 {
+		t.Fatalf("rerun: %+v, %v", summary, err)
+	}
+	mu.Lock()
+	if uploads["a.wav"] != 1 {
+		t.Fatalf("rerun uploads: %v", uploads)
+	}
+	mu.Unlock()
+}
+
+func TestAsrBatchErrors(t *testing.T) {
+	var mu sync.Mutex
+	uploads, polls := 0, map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/v2/upload":
+			uploads++
+			w.WriteHeader(http.StatusBadGateway)
+		case "/v2/getResult":
+			orderId := r.URL.Query().Get("orderId")
+			polls[orderId]++
+			switch {
+			case orderId == "order-gone":
+				_, _ = w.Write([]byte(`{"code":"26600","descInfo":"order not found"}`))
+			case polls[orderId] == 1:
+				w.WriteHeader(http.StatusInternalServerError)
+			case polls[orderId] == 2:
+				_, _ = w.Write([]byte(`{"code":"99999","descInfo":"system busy"}`))
+			default:
+				_, _ = w.Write([]byte(`{"code":"000000","content":{"orderInfo":{"orderId":"order-flaky","status":4},"orderResult":"{\"lattice\":[]}"}}`))
+			}
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ambiguous.wav"), testWav(16000, 16000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store := dgkdxf.NewMemoryAsrJobStore()
+	for _, id := range []string{"flaky", "gone"} {
+		_ = store.Put(&dgkdxf.AsrJob{Id: id, Status: dgkdxf.AsrJobStatusUploaded, OrderId: "order-" + id, UploadedAt: time.Now()})
+	}
+	// 模拟上传过程中进程退出
+	_ = store.Put(&dgkdxf.AsrJob{Id: "crashed", FilePath: filepath.Join(dir, "crashed.wav"), Status: dgkdxf.AsrJobStatusPending, Attempts: 1, MaybeUploaded: true})
+
+	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{Host: server.URL})
+	batch, err := dgkdxf.NewAsrBatch(client, store, &dgkdxf.AsrBatchOptions{
+		OutputDir:          filepath.Join(dir, "out"),
+		PollInterval:       10 * time.Millisecond,
+		OrderNotFoundCodes: []string{"26600"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Add(filepath.Join(dir, "ambiguous.wav")); err != nil {
+		t.Fatal(err)
+	}
+	summary, err := batch.Run(&dgctx.DgContext{TraceId: "123"})
+	if err != nil || summary.Done != 1 || summary.Failed != 3 {
+		t.Fatalf("summary: %+v, %v", summary, err)
+	}
+
+	// 上传 502 时讯飞可能已创建订单，不重试
+	if job, _ := store.Get("ambiguous"); job.Status != dgkdxf.AsrJobStatusFailed || !job.MaybeUploaded || uploads != 1 {
+		t.Fatalf("ambiguous job: %+v, uploads: %d", job, uploads)
+	}
+	// 上传结果未知的任务重启后不再上传
+	if job, _ := store.Get("crashed"); job.Status != dgkdxf.AsrJobStatusFailed || job.Error != dgkdxf.AsrUploadUnknownErr.Error() || job.Attempts != 1 {
+		t.Fatalf("crashed job: %+v", job)
+	}
+	// 5xx 和未知业务码继续查询，订单不存在时失败
+	if polls["order-flaky"] != 3 || polls["order-gone"] != 1 {
+		t.Fatalf("polls: %v", polls)
+	}
+}
+
+func TestJournalAsrJobStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	store, err := dgkdxf.OpenJournalAsrJobStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if err := store.Put(&dgkdxf.AsrJob{Id: "a", Status: dgkdxf.AsrJobStatusPending, Attempts: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Put(&dgkdxf.AsrJob{Id: "b", Status: dgkdxf.AsrJobStatusUploaded, OrderId: "o1"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+
+	// 模拟写入一半时崩溃
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	_, _ = file.WriteString(`{"id":"b","status":"do`)
+	_ = file.Close()
+
+	store, err = dgkdxf.OpenJournalAsrJobStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	jobs, err := store.List()
+	if err != nil || len(jobs) != 2 || jobs[0].Attempts != 3 || jobs[1].OrderId != "o1" || jobs[1].Status != dgkdxf.AsrJobStatusUploaded {
+		t.Fatalf("jobs: %+v, %v", jobs, err)
+	}
+
+	// 5 个完整行 2 个任务，打开时已压缩
+	data, _ := os.ReadFile(path)
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Fatalf("journal lines: %d", lines)
+	}
+}
+
+func TestJournalAsrJobStoreTruncatePartialLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	store, err := dgkdxf.OpenJournalAsrJobStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if err := store.Put(&dgkdxf.AsrJob{Id: id, Status: dgkdxf.AsrJobStatusPending}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = store.Close()
+
+	// 3 行 3 个任务不会压缩，写了一半的行需要在打开时截掉
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	_, _ = file.WriteString(`{"id":"c","status":"do`)
+	_ = file.Close()
+
+	store, err = dgkdxf.OpenJournalAsrJobStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(&dgkdxf.AsrJob{Id: "c", Status: dgkdxf.AsrJobStatusUploaded, OrderId: "o3"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+
+	store, err = dgkdxf.OpenJournalAsrJobStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	job, err := store.Get("c")
+	if err != nil || job == nil || job.Status != dgkdxf.AsrJobStatusUploaded || job.OrderId != "o3" {
+		t.Fatalf("job c: %+v, %v", job, err)
+	}
+	data, _ := os.ReadFile(path)
+	if lines := strings.Count(string(data), "\n"); lines != 4 || strings.Contains(string(data), `"do`+"\n") {
+		t.Fatalf("journal: %s", data)
+	}
+}
+
+func TestFileAsrJobStoreCorrupt(t *testing.T) {
+	store := &dgkdxf.FileAsrJobStore{Path: filepath.Join(t.TempDir(), "jobs.json")}
+	if jobs, err := store.List(); err != nil || len(jobs) != 0 {
+		t.Fatalf("missing file: %v, %v", jobs, err)
+	}
+	if err := store.Put(&dgkdxf.AsrJob{Id: "a", Status: dgkdxf.AsrJobStatusPending}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, content := range []string{"", `{"a":{"id":"a"`} {
+		_ = os.WriteFile(store.Path, []byte(content), 0644)
+		if err := store.Put(&dgkdxf.AsrJob{Id: "b"}); err == nil {
+			t.Fatalf("put should fail for %q", content)
+		}
+		if data, _ := os.ReadFile(store.Path); string(data) != content {
+			t.Fatalf("file overwritten: %s", data)
+		}
+	}
+}
+
+type failingAsrJobStore struct {
+	*dgkdxf.MemoryAsrJobStore
+	err error
+}
+
+func (s *failingAsrJobStore) Put(*dgkdxf.AsrJob) error {
+	return s.err
+}
+
+func TestAsrBatchStoreErr(t *testing.T) {
+	var uploads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uploads.Add(1)
+		_, _ = w.Write([]byte(`{"code":"000000","content":{"orderId":"order-a"}}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	memory := dgkdxf.NewMemoryAsrJobStore()
+	_ = memory.Put(&dgkdxf.AsrJob{Id: "a", FilePath: filepath.Join(dir, "a.wav"), Status: dgkdxf.AsrJobStatusPending})
+	store := &failingAsrJobStore{MemoryAsrJobStore: memory, err: errors.New("disk full")}
+
+	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{Host: server.URL})
+	batch, err := dgkdxf.NewAsrBatch(client, store, &dgkdxf.AsrBatchOptions{OutputDir: filepath.Join(dir, "out"), PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 无法保存上传标记时不上传
+	summary, err := batch.Run(&dgctx.DgContext{TraceId: "123"})
+	if !errors.Is(err, store.err) || summary == nil || summary.Pending != 1 || uploads.Load() != 0 {
+		t.Fatalf("summary: %+v, err: %v, uploads: %d", summary, err, uploads.Load())
+	}
+}
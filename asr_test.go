@@ -11,17 +11,9 @@ import (
 
 func TestAsrUpload(t *testing.T) {
 	host := "https://api.iflyrec.com"
-	appId := os.Getenv("appId")
-	accessKeyId := os.Getenv("accessKeyId")
-	accessKeySecret := os.Getenv("accessKeySecret")
 	ctx := &dgctx.DgContext{TraceId: "123"}
 
-	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{
-		AppId:           appId,
-		Host:            host,
-		AccessKeyId:     accessKeyId,
-		AccessKeySecret: accessKeySecret,
-	})
+	client := newEnvClient(t, host, dgkdxf.ServiceAsr)
 
 	fileBytes, _ := os.ReadFile("test.opus")
 	rt, err := client.AsrUpload(ctx, "test.opus", 45370, int64(len(fileBytes)), "")
@@ -33,17 +25,9 @@ func TestAsrUpload(t *testing.T) {
 
 func TestAsrGetResult(t *testing.T) {
 	host := "https://api.iflyrec.com"
-	appId := os.Getenv("appId")
-	accessKeyId := os.Getenv("accessKeyId")
-	accessKeySecret := os.Getenv("accessKeySecret")
 	ctx := &dgctx.DgContext{TraceId: "123"}
 
-	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{
-		AppId:           appId,
-		Host:            host,
-		AccessKeyId:     accessKeyId,
-		AccessKeySecret: accessKeySecret,
-	})
+	client := newEnvClient(t, host, dgkdxf.ServiceAsr)
 
 	rt, err := client.GetAsrResult(ctx, os.Getenv("orderId"))
 	if err != nil {
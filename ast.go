@@ -3,15 +3,12 @@ package iflytek
 import (
 	"encoding/json"
 	dgctx "github.com/darwinOrg/go-common/context"
-	dgerr "github.com/darwinOrg/go-common/enums/error"
 	"github.com/darwinOrg/go-common/model"
 	"github.com/darwinOrg/go-common/utils"
 	dglogger "github.com/darwinOrg/go-logger"
 	dgws "github.com/darwinOrg/go-websocket"
-	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
-	"net/url"
-	"strings"
+	"net/http"
 	"time"
 )
 
@@ -41,18 +38,19 @@ const (
 )
 
 type AstParamConfig struct {
-	Lang           string        `json:"lang"`
-	Codec          string        `json:"codec"`
-	AudioEncode    string        `json:"audioEncode"`
-	Samplerate     string        `json:"samplerate"`
-	RoleType       RoleType      `json:"roleType"`
-	ContextId      string        `json:"contextId"`
-	FeatureIds     string        `json:"featureIds"`
-	HotWordId      string        `json:"hotWordId"`
-	SourceInfo     string        `json:"sourceInfo"`
-	FilePath       string        `json:"filePath"`
-	ResultFilePath string        `json:"resultFilePath"`
-	EngVadMdn      EngVadMdnType `json:"engVadMdn" remark:"vad 远近场切换。不传此参数或传值1代表远场。若要使用近场，传值2"`
+	Lang           string          `json:"lang"`
+	Codec          string          `json:"codec"`
+	AudioEncode    string          `json:"audioEncode"`
+	Samplerate     string          `json:"samplerate"`
+	RoleType       RoleType        `json:"roleType"`
+	ContextId      string          `json:"contextId"`
+	FeatureIds     string          `json:"featureIds"`
+	HotWordId      string          `json:"hotWordId"`
+	SourceInfo     string          `json:"sourceInfo"`
+	FilePath       string          `json:"filePath"`
+	ResultFilePath string          `json:"resultFilePath"`
+	EngVadMdn      EngVadMdnType   `json:"engVadMdn" remark:"vad 远近场切换。不传此参数或传值1代表远场。若要使用近场，传值2"`
+	AudioSource    *AstAudioSource `json:"-"` // 调用方写入的原始音频格式，AstOpen 和 AstAudioWriter 据此转换为 audioEncode 和 samplerate
 }
 
 type AstReadMessageRequest struct {
@@ -61,6 +59,7 @@ type AstReadMessageRequest struct {
 	BizIdHandler              GetBizIdHandler
 	SaveAstStartedMetaHandler SaveAstStartedMetaHandler
 	ConsumeAstResultHandler   ConsumeAstResultHandler
+	SpeakerResolver           *AstSpeakerResolver // 设置后会在调用 ConsumeAstResultHandler 前填充 AstResult.Words
 }
 
 type AstResult struct {
@@ -83,7 +82,8 @@ type AstResult struct {
 			Type AstResultType `json:"type"`
 		} `json:"st"`
 	} `json:"cn"`
-	Ls bool `json:"ls"`
+	Ls    bool              `json:"ls"`
+	Words []*AstSpeakerWord `json:"-"` // 由 AstSpeakerResolver 解析的逐词说话人
 }
 
 func (ar *AstResult) HasFinalWords() bool {
@@ -114,27 +114,63 @@ func (ar *AstResult) CombineFinalWords(ctx *dgctx.DgContext, roleType RoleType)
 	return finalWords
 }
 
-func (c *Client) AstConnect(ctx *dgctx.DgContext, config *AstParamConfig) (*websocket.Conn, error) {
+func (c *Client) AstConnect(ctx *dgctx.DgContext, config *AstParamConfig) (_ *websocket.Conn, err error) {
 	if int(config.EngVadMdn) == 0 {
 		config.EngVadMdn = EngVadMdnTypeFar
 	}
-	uri := c.BuildAstUri(ctx, config)
-	dglogger.Infof(ctx, "ast config: %s, uri: %s", utils.MustConvertBeanToJsonString(config), uri)
-	cn, _, err := websocket.DefaultDialer.Dial(uri, nil)
+	call, err := c.startCall(ctx, ApiAstConnect)
 	if err != nil {
+		dglogger.Errorf(ctx, "ast rate limit err: %v", err)
 		return nil, err
 	}
+	defer func() { err = call.end(err) }()
+
+	uri, err := c.AstUri(ctx, config)
+	if err != nil {
+		dglogger.Errorf(ctx, "ast credentials err: %v", err)
+		return nil, err
+	}
+	dglogger.Infof(ctx, "ast config: %s, uri: %s", utils.MustConvertBeanToJsonString(config), c.redactUrl(uri))
+
+	// 连接关闭时才归还 ast.connect 的并发名额
+	session := &astSession{release: call.detachRelease()}
+	header := http.Header{}
+	call.setHeaders(header)
+	cn, response, err := astDialer(session).Dial(uri, header)
+	if err != nil {
+		session.close()
+		if response != nil {
+			call.statusCode = response.StatusCode
+			return nil, call.statusErr(response)
+		}
+		return nil, err
+	}
+	call.statusCode = response.StatusCode
+	session.begin(c.instrumentation().StartAstSession(ctx, &AstSessionInfo{TraceId: ctx.TraceId, StartTime: time.Now()}))
 
 	return cn, nil
 }
 
+// BuildAstUri 获取密钥失败时返回空串
+//
+// Deprecated: 使用 AstUri，获取密钥失败时返回错误
 func (c *Client) BuildAstUri(ctx *dgctx.DgContext, config *AstParamConfig) string {
-	parts := []string{"v1.0", c.Config.AppId, c.Config.AccessKeyId, getDateTimeString(), uuid.NewString()}
-	partsStr := strings.Join(parts, ",")
-	baseString := url.QueryEscape(partsStr)
-	signature := utils.Sha1Base64Encode(c.Config.AccessKeySecret, baseString)
-	parts = append(parts, signature)
-	authString := strings.Join(parts, ",")
+	uri, err := c.AstUri(ctx, config)
+	if err != nil {
+		dglogger.Errorf(ctx, "BuildAstUri credentials err: %v", err)
+		return ""
+	}
+
+	return uri
+}
+
+// AstUri 生成带 authString 的连接地址，签名使用当前密钥
+func (c *Client) AstUri(ctx *dgctx.DgContext, config *AstParamConfig) (string, error) {
+	creds, err := c.credentials()
+	if err != nil {
+		return "", err
+	}
+	authString := c.buildAstAuthString(creds, c.getDateTimeString(), c.randomId())
 
 	params := []*model.KeyValuePair[string, any]{
 		{
@@ -183,25 +219,25 @@ func (c *Client) BuildAstUri(ctx *dgctx.DgContext, config *AstParamConfig) strin
 		},
 	}
 
-	return c.Config.Host + "/ast?" + utils.FormUrlEncodedParams(params)
+	return c.Config.ServiceHost(ServiceAst) + "/ast?" + utils.FormUrlEncodedParams(params), nil
 }
 
 func AstWriteStarted(ctx *dgctx.DgContext, cn *websocket.Conn) error {
 	dglogger.Infof(ctx, "send ast started message")
 	if cn == nil {
 		dglogger.Error(ctx, "websocket conn is nil")
-		return dgerr.SYSTEM_ERROR
+		return &Error{Service: ServiceAst, Op: "started", Err: NilConnErr}
 	}
-	return cn.WriteMessage(websocket.TextMessage, []byte("{\"action\":\"started\"}"))
+	return AstSendMessage(cn, websocket.TextMessage, []byte("{\"action\":\"started\"}"))
 }
 
 func AstWriteEnd(ctx *dgctx.DgContext, cn *websocket.Conn) error {
 	dglogger.Infof(ctx, "send ast end message")
 	if cn == nil {
 		dglogger.Error(ctx, "websocket conn is nil")
-		return dgerr.SYSTEM_ERROR
+		return &Error{Service: ServiceAst, Op: "end", Err: NilConnErr}
 	}
-	return cn.WriteMessage(websocket.TextMessage, []byte("{\"end\":true}"))
+	return AstSendMessage(cn, websocket.TextMessage, []byte("{\"end\":true}"))
 }
 
 func IsAstEndMessage(_ *dgctx.DgContext, mt int, data []byte) bool {
@@ -308,7 +344,7 @@ func AstReadMessage(ctx *dgctx.DgContext, req *AstReadMessageRequest) {
 			time.Sleep(time.Second)
 			continue
 		}
-		mt, data, err := forwardConn.ReadMessage()
+		mt, data, err := AstReceiveMessage(forwardConn)
 		if mt == websocket.CloseMessage || mt == -1 {
 			dglogger.Infof(ctx, "[%s: %d, forwardMark: %s] received iflytek ast close message, error: %v", bizKey, bizId, forwardMark, err)
 			dgws.SetForwardWsEnded(ctx, forwardMark)
@@ -339,9 +375,12 @@ func AstReadMessage(ctx *dgctx.DgContext, req *AstReadMessageRequest) {
 				continue
 			}
 
-			code := mp["code"]
-			if code == ExceedUploadSpeedLimitCode {
-				dglogger.Errorf(ctx, "[%s: %d, forwardMark: %s] iflytek ast exceed upload speed limit", bizKey, bizId, forwardMark)
+			if astErr := AstMessageError(data); astErr != nil {
+				if astErr.Code == ExceedUploadSpeedLimitCode {
+					dglogger.Errorf(ctx, "[%s: %d, forwardMark: %s] iflytek ast exceed upload speed limit", bizKey, bizId, forwardMark)
+				} else {
+					dglogger.Errorf(ctx, "[%s: %d, forwardMark: %s] iflytek ast error: %v", bizKey, bizId, forwardMark, astErr)
+				}
 				continue
 			}
 
@@ -351,6 +390,10 @@ func AstReadMessage(ctx *dgctx.DgContext, req *AstReadMessageRequest) {
 				continue
 			}
 
+			if astResult != nil && req.SpeakerResolver != nil {
+				astResult.Words = req.SpeakerResolver.Resolve(astResult)
+			}
+
 			if astResult != nil && req.ConsumeAstResultHandler != nil {
 				err := req.ConsumeAstResultHandler(ctx, astResult, time.Now())
 				if err != nil {
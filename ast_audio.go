@@ -0,0 +1,193 @@
+package iflytek
+
+import (
+	"fmt"
+	dgctx "github.com/darwinOrg/go-common/context"
+	"github.com/gorilla/websocket"
+	"strconv"
+	"sync"
+)
+
+const (
+	AstAudioEncodePcm = "pcm_s16le"
+
+	defaultAstSampleRate = 16000
+	// astAudioChunkMillis 实时转写建议每 40ms 发送一次音频
+	astAudioChunkMillis = 40
+)
+
+type AstChannel int
+
+const (
+	AstChannelDownmix AstChannel = 0 // 多声道混为单声道
+	AstChannelLeft    AstChannel = 1
+	AstChannelRight   AstChannel = 2
+)
+
+// AstAudioSource 写入 AstAudioWriter 的原始音频格式，只支持 pcm_s16le，一般设置在 AstParamConfig.AudioSource
+type AstAudioSource struct {
+	SampleRate int
+	Channels   int
+	Channel    AstChannel // 多声道时使用的声道，默认混音
+}
+
+// NewAstAudioSourceFromWav 根据 wav 头创建音频格式
+func NewAstAudioSourceFromWav(header *WavHeader, channel AstChannel) (*AstAudioSource, error) {
+	if !header.IsPcm() || header.BitsPerSample != pcmSampleSize*8 {
+		return nil, fmt.Errorf("%w: wav format %d with %d bits per sample is not pcm_s16le", InvalidPcmErr, header.AudioFormat, header.BitsPerSample)
+	}
+
+	return &AstAudioSource{SampleRate: header.SampleRate, Channels: header.Channels, Channel: channel}, nil
+}
+
+// AstAudioWriter 将原始音频按 AstParamConfig 的 audioEncode 和 samplerate 转换为单声道后分片发送到 ast 连接。
+// 非 pcm_s16le 编码时不做转换，直接发送
+type AstAudioWriter struct {
+	cn          *websocket.Conn
+	source      *AstAudioSource
+	resampler   *Resampler
+	chunkSize   int
+	pending     []byte // 不足一帧的原始数据
+	buffer      []byte // 转换后不足一个分片的数据
+	passthrough bool
+	mu          sync.Mutex
+}
+
+// NewAstAudioWriter source 为 nil 时使用 config.AudioSource，都未设置时认为已是目标格式
+func NewAstAudioWriter(cn *websocket.Conn, config *AstParamConfig, source *AstAudioSource) (*AstAudioWriter, error) {
+	if cn == nil {
+		return nil, &Error{Service: ServiceAst, Op: "write", Err: NilConnErr}
+	}
+	if source == nil {
+		source = config.AudioSource
+	}
+
+	targetRate := defaultAstSampleRate
+	if config.Samplerate != "" {
+		rate, err := strconv.Atoi(config.Samplerate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid samplerate %q: %v", config.Samplerate, err)
+		}
+		targetRate = rate
+	}
+
+	aw := &AstAudioWriter{cn: cn, source: source}
+	if config.AudioEncode != "" && config.AudioEncode != AstAudioEncodePcm {
+		aw.passthrough = true
+		return aw, nil
+	}
+	if source == nil {
+		source = &AstAudioSource{SampleRate: targetRate, Channels: 1}
+		aw.source = source
+	}
+	if source.Channels <= 0 || source.Channel < 0 || int(source.Channel) > source.Channels {
+		return nil, fmt.Errorf("%w: channel %d of %d", InvalidPcmErr, source.Channel, source.Channels)
+	}
+
+	resampler, err := NewResampler(source.SampleRate, targetRate)
+	if err != nil {
+		return nil, err
+	}
+	aw.resampler = resampler
+	aw.chunkSize = targetRate * pcmSampleSize * astAudioChunkMillis / 1000
+
+	return aw, nil
+}
+
+// Write 转换并发送音频，可写入任意长度的数据
+func (aw *AstAudioWriter) Write(p []byte) (int, error) {
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+
+	if aw.passthrough {
+		if err := AstSendMessage(aw.cn, websocket.BinaryMessage, p); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	data := append(aw.pending, p...)
+	frameSize := aw.source.Channels * pcmSampleSize
+	whole := len(data) - len(data)%frameSize
+	aw.pending = append([]byte{}, data[whole:]...)
+
+	mono, err := aw.toMono(data[:whole])
+	if err != nil {
+		return 0, err
+	}
+	aw.buffer = append(aw.buffer, aw.resampler.Write(mono)...)
+
+	for len(aw.buffer) >= aw.chunkSize {
+		if err := AstSendMessage(aw.cn, websocket.BinaryMessage, aw.buffer[:aw.chunkSize]); err != nil {
+			return 0, err
+		}
+		aw.buffer = aw.buffer[aw.chunkSize:]
+	}
+
+	return len(p), nil
+}
+
+// Flush 发送不足一个分片的剩余数据，结束前需要在 AstWriteEnd 之前调用
+func (aw *AstAudioWriter) Flush() error {
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+
+	if len(aw.buffer) == 0 {
+		return nil
+	}
+	if err := AstSendMessage(aw.cn, websocket.BinaryMessage, aw.buffer); err != nil {
+		return err
+	}
+	aw.buffer = nil
+
+	return nil
+}
+
+func (aw *AstAudioWriter) toMono(pcm []byte) ([]byte, error) {
+	switch {
+	case aw.source.Channels == 1:
+		return pcm, nil
+	case aw.source.Channel == AstChannelDownmix:
+		return Downmix(pcm, aw.source.Channels)
+	default:
+		return ExtractChannel(pcm, aw.source.Channels, int(aw.source.Channel)-1)
+	}
+}
+
+// AstStream 实时转写会话，Write 按 AstParamConfig.AudioSource 自动转换音频后分片发送
+type AstStream struct {
+	Conn   *websocket.Conn // 读取转写结果，可以交给 dgws 作为转发连接
+	writer *AstAudioWriter
+}
+
+// AstOpen 建立 ast 连接，之后通过 AstStream.Write 发送的音频都会按 config.AudioSource 转换
+func (c *Client) AstOpen(ctx *dgctx.DgContext, config *AstParamConfig) (*AstStream, error) {
+	cn, err := c.AstConnect(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := NewAstAudioWriter(cn, config, nil)
+	if err != nil {
+		_ = cn.Close()
+		return nil, err
+	}
+
+	return &AstStream{Conn: cn, writer: writer}, nil
+}
+
+func (s *AstStream) Write(p []byte) (int, error) {
+	return s.writer.Write(p)
+}
+
+// End 发送剩余的音频和结束标记
+func (s *AstStream) End(ctx *dgctx.DgContext) error {
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+
+	return AstWriteEnd(ctx, s.Conn)
+}
+
+func (s *AstStream) Close() error {
+	return s.Conn.Close()
+}
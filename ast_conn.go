@@ -0,0 +1,133 @@
+package iflytek
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/gorilla/websocket"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// astSession 包装 ast 连接的底层 tcp 连接，连接关闭时归还限流名额并结束 span。
+// tls 和代理仍由 websocket.Dialer 处理，收发的消息在 AstSendMessage 和 AstReceiveMessage 中计数
+type astSession struct {
+	net.Conn
+	release        func()
+	framesSent     atomic.Int64
+	framesReceived atomic.Int64
+	bytesSent      atomic.Int64
+	bytesReceived  atomic.Int64
+
+	mu     sync.Mutex
+	span   AstSessionSpan
+	start  time.Time
+	closed bool
+}
+
+func (s *astSession) Close() error {
+	s.close()
+	return s.Conn.Close()
+}
+
+// begin 握手成功后开始统计
+func (s *astSession) begin(span AstSessionSpan) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.span, s.start = span, time.Now()
+	if s.closed {
+		s.end()
+	}
+}
+
+func (s *astSession) close() {
+	s.release()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	if s.span != nil {
+		s.end()
+	}
+}
+
+func (s *astSession) end() {
+	s.span.End(&AstSessionStats{
+		Duration:       time.Since(s.start),
+		FramesSent:     s.framesSent.Load(),
+		FramesReceived: s.framesReceived.Load(),
+		BytesSent:      s.bytesSent.Load(),
+		BytesReceived:  s.bytesReceived.Load(),
+	})
+}
+
+// astDialer 只替换建立 tcp 连接的方式，wss 的 tls 握手和 Proxy 代理仍由 websocket.DefaultDialer 完成
+func astDialer(session *astSession) *websocket.Dialer {
+	dialer := *websocket.DefaultDialer
+	dialer.NetDialContext = func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		session.Conn = conn
+		return session, nil
+	}
+
+	return &dialer
+}
+
+// astSessionOf 取得 AstConnect 建立的连接对应的 session，其他方式建立的连接返回 nil
+func astSessionOf(cn *websocket.Conn) *astSession {
+	conn := cn.NetConn()
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	session, _ := conn.(*astSession)
+
+	return session
+}
+
+// AstSendMessage 发送消息并计入会话统计，直接调用 cn.WriteMessage 发送的消息不计数
+func AstSendMessage(cn *websocket.Conn, messageType int, data []byte) error {
+	if cn == nil {
+		return &Error{Service: ServiceAst, Op: "write", Err: NilConnErr}
+	}
+	if err := cn.WriteMessage(messageType, data); err != nil {
+		e := &Error{Service: ServiceAst, Op: "write", Err: err}
+		e.Retryable = e.retryable()
+		return e
+	}
+
+	if session := astSessionOf(cn); session != nil && isAstDataMessage(messageType) {
+		session.framesSent.Add(1)
+		session.bytesSent.Add(int64(len(data)))
+	}
+
+	return nil
+}
+
+// AstReceiveMessage 读取消息并计入会话统计，直接调用 cn.ReadMessage 读取的消息不计数
+func AstReceiveMessage(cn *websocket.Conn) (int, []byte, error) {
+	if cn == nil {
+		return -1, nil, &Error{Service: ServiceAst, Op: "read", Err: NilConnErr}
+	}
+	mt, data, err := cn.ReadMessage()
+	if err == nil && isAstDataMessage(mt) {
+		if session := astSessionOf(cn); session != nil {
+			session.framesReceived.Add(1)
+			session.bytesReceived.Add(int64(len(data)))
+		}
+	}
+
+	return mt, data, err
+}
+
+func isAstDataMessage(messageType int) bool {
+	return messageType == websocket.TextMessage || messageType == websocket.BinaryMessage
+}
@@ -0,0 +1,92 @@
+package iflytek
+
+import (
+	"sync"
+)
+
+// AstSpeaker 实时转写结果中角色对应的说话人，没有已知的角色映射时 FeatureId 和 Uid 为空
+type AstSpeaker struct {
+	Rl        string `json:"rl"`
+	FeatureId string `json:"featureId"`
+	Uid       string `json:"uid"`
+}
+
+// AstSpeakerWord 实时转写结果中的单个词及其说话人，rl 为空或为 "0" 时 Speaker 为 nil
+type AstSpeakerWord struct {
+	W       string      `json:"w"`
+	Wp      string      `json:"wp"`
+	Speaker *AstSpeaker `json:"speaker,omitempty"`
+}
+
+// AstSpeakerResolver 将 AST 返回的角色编号解析为声纹和用户
+//
+// 讯飞的 rl 只是说话人分离的编号，不保证与 AstParamConfig.FeatureIds 的顺序对应，
+// 只有通过 SetRole 明确登记过的角色才会解析出 FeatureId 和 Uid。
+type AstSpeakerResolver struct {
+	registry *FeatureRegistry
+	mu       sync.RWMutex
+	roles    map[string]string
+	uids     map[string]string
+}
+
+// NewAstSpeakerResolver registry 为空时不解析 uid
+func NewAstSpeakerResolver(registry *FeatureRegistry) *AstSpeakerResolver {
+	return &AstSpeakerResolver{registry: registry, roles: map[string]string{}, uids: map[string]string{}}
+}
+
+// SetRole 登记角色编号对应的声纹，featureId 为空时取消登记
+func (r *AstSpeakerResolver) SetRole(rl string, featureId string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if featureId == "" {
+		delete(r.roles, rl)
+		return
+	}
+	r.roles[rl] = featureId
+}
+
+// Speaker 解析单个角色编号，rl 为空或为 "0" 时返回 nil
+func (r *AstSpeakerResolver) Speaker(rl string) *AstSpeaker {
+	if rl == "" || rl == "0" {
+		return nil
+	}
+
+	r.mu.RLock()
+	featureId := r.roles[rl]
+	uid, cached := r.uids[featureId]
+	r.mu.RUnlock()
+
+	speaker := &AstSpeaker{Rl: rl, FeatureId: featureId}
+	if featureId == "" || r.registry == nil {
+		return speaker
+	}
+
+	if !cached {
+		var err error
+		uid, err = r.registry.Uid(featureId)
+		if err != nil {
+			return speaker
+		}
+		r.mu.Lock()
+		r.uids[featureId] = uid
+		r.mu.Unlock()
+	}
+	speaker.Uid = uid
+
+	return speaker
+}
+
+// Resolve 按顺序返回结果中每个词及其说话人，同一结果中可能包含多个说话人
+func (r *AstSpeakerResolver) Resolve(ar *AstResult) []*AstSpeakerWord {
+	var words []*AstSpeakerWord
+	for _, rt := range ar.Cn.St.Rt {
+		for _, ws := range rt.Ws {
+			for _, cw := range ws.Cw {
+				words = append(words, &AstSpeakerWord{W: cw.W, Wp: cw.Wp, Speaker: r.Speaker(cw.Rl)})
+			}
+		}
+	}
+
+	return words
+}
@@ -1,25 +1,18 @@
 package iflytek_test
 
 import (
+	"encoding/json"
 	dgctx "github.com/darwinOrg/go-common/context"
 	dgkdxf "github.com/darwinOrg/go-iflytek"
 	dglogger "github.com/darwinOrg/go-logger"
-	"os"
+	"path/filepath"
 	"testing"
 )
 
 func TestBuildAstUri(t *testing.T) {
-	host := "wss://api.iflyrec.com"
-	appId := os.Getenv("appId")
-	accessKeyId := os.Getenv("accessKeyId")
-	accessKeySecret := os.Getenv("accessKeySecret")
+	host := "https://api.iflyrec.com" // ast 会自动转换为 wss
 	ctx := &dgctx.DgContext{TraceId: "123"}
-	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{
-		AppId:           appId,
-		Host:            host,
-		AccessKeyId:     accessKeyId,
-		AccessKeySecret: accessKeySecret,
-	})
+	client := newEnvClient(t, host, dgkdxf.ServiceAst)
 	uri := client.BuildAstUri(ctx, &dgkdxf.AstParamConfig{
 		Lang:           "cn",
 		Codec:          "pcm_s16le",
@@ -35,3 +28,36 @@ func TestBuildAstUri(t *testing.T) {
 	})
 	dglogger.Infof(ctx, "uri: %s", uri)
 }
+
+func TestAstUriCredentialsErr(t *testing.T) {
+	ctx := &dgctx.DgContext{TraceId: "123"}
+	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{
+		AppId:               "app",
+		CredentialsProvider: &dgkdxf.FileCredentialsProvider{Path: filepath.Join(t.TempDir(), "missing.json")},
+	})
+	if uri, err := client.AstUri(ctx, &dgkdxf.AstParamConfig{}); err == nil || uri != "" {
+		t.Fatalf("uri: %s, err: %v", uri, err)
+	}
+	if _, err := client.SignParams(nil); err == nil {
+		t.Fatal("sign without credentials should fail")
+	}
+}
+
+func TestAstSpeakerResolver(t *testing.T) {
+	store := dgkdxf.NewMemoryFeatureStore()
+	_ = store.Put(&dgkdxf.FeatureRecord{Uid: "u1", FeatureId: "f1"})
+	resolver := dgkdxf.NewAstSpeakerResolver(dgkdxf.NewFeatureRegistry(nil, store))
+
+	var astResult dgkdxf.AstResult
+	_ = json.Unmarshal([]byte(`{"cn":{"st":{"type":"0","rt":[{"ws":[{"cw":[{"w":"你好","rl":"1"}]},{"cw":[{"w":"在吗","rl":"2"}]},{"cw":[{"w":"。","rl":"0"}]}]}]}}}`), &astResult)
+	words := resolver.Resolve(&astResult)
+	if len(words) != 3 || words[0].Speaker.Rl != "1" || words[0].Speaker.FeatureId != "" || words[1].Speaker.Rl != "2" || words[2].Speaker != nil {
+		t.Fatalf("words: %+v", words)
+	}
+
+	resolver.SetRole("2", "f1")
+	words = resolver.Resolve(&astResult)
+	if words[0].Speaker.FeatureId != "" || words[1].Speaker.FeatureId != "f1" || words[1].Speaker.Uid != "u1" {
+		t.Fatalf("speakers after set role: %+v, %+v", words[0].Speaker, words[1].Speaker)
+	}
+}
@@ -0,0 +1,335 @@
+package iflytek
+
+import (
+	"encoding/json"
+	"errors"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dglogger "github.com/darwinOrg/go-logger"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type CallEventType string
+type CallEventHandler[T any] func(*dgctx.DgContext, *T) error
+
+const (
+	CallEventTypeRinging  CallEventType = "ringing"
+	CallEventTypeAnswered CallEventType = "answered"
+	CallEventTypeHangup   CallEventType = "hangup"
+	CallEventTypeCdr      CallEventType = "cdr"
+	CallEventTypeRecord   CallEventType = "record"
+
+	defaultCallEventDedupTTL  = 24 * time.Hour
+	defaultCallEventDedupSize = 100000
+	defaultCallEventMaxAge    = time.Hour
+	maxCallEventBodySize      = 1024 * 1024
+)
+
+var (
+	CallEventSignatureErr = errors.New("call event signature invalid")
+	CallEventExpiredErr   = errors.New("call event expired")
+	CallEventReplayedErr  = errors.New("call event replayed")
+)
+
+// CallEvent 呼叫中心推送事件的公共字段
+type CallEvent struct {
+	RequestId       string        `json:"requestId"`
+	Type            CallEventType `json:"type"`
+	Cno             string        `json:"cno"`
+	CustomerNumber  string        `json:"customerNumber"`
+	MainUniqueId    string        `json:"mainUniqueId"`
+	UniqueId        string        `json:"uniqueId"`
+	RequestUniqueId string        `json:"requestUniqueId"` // 外呼时传入的 CalloutReq.RequestUniqueId
+	Timestamp       int64         `json:"timestamp"`       // 事件发生时间，毫秒
+}
+
+// CallRingingEvent 振铃
+type CallRingingEvent struct {
+	CallEvent
+	RingSide int32 `json:"ringSide"` // 振铃方，1：客户侧，2：座席侧
+}
+
+// CallAnsweredEvent 接听
+type CallAnsweredEvent struct {
+	CallEvent
+	AnswerSide int32 `json:"answerSide"` // 接听方，1：客户侧，2：座席侧
+}
+
+// CallHangupEvent 挂机
+type CallHangupEvent struct {
+	CallEvent
+	Status         int32 `json:"status"`         // 接听状态 1: 客户未接听 2: 座席未接听 3: 双方接听
+	HangupSide     int32 `json:"hangupSide"`     // 挂机方，1：客户侧，2：座席侧，3：系统
+	BridgeDuration int64 `json:"bridgeDuration"` // 通话时长，秒
+}
+
+// CallCdrEvent 通话记录生成
+type CallCdrEvent struct {
+	CallEvent
+	Status         int32 `json:"status"` // 接听状态 1: 客户未接听 2: 座席未接听 3: 双方接听
+	StartTime      int64 `json:"startTime"`
+	AnswerTime     int64 `json:"answerTime"`
+	EndTime        int64 `json:"endTime"`
+	BridgeDuration int64 `json:"bridgeDuration"` // 通话时长，秒
+	TotalDuration  int64 `json:"totalDuration"`  // 总时长，秒
+}
+
+// CallRecordEvent 录音文件生成
+type CallRecordEvent struct {
+	CallEvent
+	RecordType string `json:"recordType"` // "record": 通话录音，"voicemail": 留言
+	RecordFile string `json:"recordFile"`
+}
+
+type CallEventReceiverConfig struct {
+	SignHost   string        `json:"signHost"`   // 参与签名的 host，经过代理转发时需设置为推送配置中的 host，默认取请求的 Host
+	SkipVerify bool          `json:"skipVerify"` // 跳过签名校验，仅用于调试
+	DedupTTL   time.Duration `json:"dedupTTL"`   // requestId 去重的保留时长，默认24小时
+	DedupSize  int           `json:"dedupSize"`  // requestId 去重的最大条数，默认100000
+	MaxAge     time.Duration `json:"maxAge"`     // 推送签名的最长有效期，推送携带的 Expires 更长时以此为准，默认1小时
+	NonceStore NonceStore    `json:"-"`          // 记录已使用的 Signature，默认为容量 DedupSize 的 MemoryNonceStore，多实例部署时需共享
+}
+
+// CallEventReceiver 接收呼叫中心推送的通话事件，校验签名后按事件类型分发，并按 requestId 去重
+type CallEventReceiver struct {
+	client   *Client
+	config   *CallEventReceiverConfig
+	mu       sync.Mutex
+	handlers map[CallEventType]func(*dgctx.DgContext, []byte) error
+	seen     map[string]time.Time
+	handling map[string]bool
+}
+
+func NewCallEventReceiver(client *Client, config *CallEventReceiverConfig) *CallEventReceiver {
+	if config == nil {
+		config = &CallEventReceiverConfig{}
+	}
+	if config.DedupTTL <= 0 {
+		config.DedupTTL = defaultCallEventDedupTTL
+	}
+	if config.DedupSize <= 0 {
+		config.DedupSize = defaultCallEventDedupSize
+	}
+	if config.MaxAge <= 0 {
+		config.MaxAge = defaultCallEventMaxAge
+	}
+	if config.NonceStore == nil {
+		config.NonceStore = NewMemoryNonceStore(config.DedupSize)
+	}
+
+	return &CallEventReceiver{
+		client:   client,
+		config:   config,
+		handlers: map[CallEventType]func(*dgctx.DgContext, []byte) error{},
+		seen:     map[string]time.Time{},
+		handling: map[string]bool{},
+	}
+}
+
+func (r *CallEventReceiver) OnRinging(handler CallEventHandler[CallRingingEvent]) {
+	registerCallEventHandler(r, CallEventTypeRinging, handler)
+}
+
+func (r *CallEventReceiver) OnAnswered(handler CallEventHandler[CallAnsweredEvent]) {
+	registerCallEventHandler(r, CallEventTypeAnswered, handler)
+}
+
+func (r *CallEventReceiver) OnHangup(handler CallEventHandler[CallHangupEvent]) {
+	registerCallEventHandler(r, CallEventTypeHangup, handler)
+}
+
+func (r *CallEventReceiver) OnCdr(handler CallEventHandler[CallCdrEvent]) {
+	registerCallEventHandler(r, CallEventTypeCdr, handler)
+}
+
+func (r *CallEventReceiver) OnRecord(handler CallEventHandler[CallRecordEvent]) {
+	registerCallEventHandler(r, CallEventTypeRecord, handler)
+}
+
+func registerCallEventHandler[T any](r *CallEventReceiver, eventType CallEventType, handler CallEventHandler[T]) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers[eventType] = func(ctx *dgctx.DgContext, data []byte) error {
+		event := new(T)
+		if err := json.Unmarshal(data, event); err != nil {
+			return err
+		}
+		return handler(ctx, event)
+	}
+}
+
+func (r *CallEventReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx := &dgctx.DgContext{TraceId: req.Header.Get("x-traceid")}
+
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !r.config.SkipVerify {
+		if err := r.verify(req); err != nil {
+			dglogger.Errorf(ctx, "CallEventReceiver verify err: %v", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(req.Body, maxCallEventBodySize))
+	if err != nil {
+		dglogger.Errorf(ctx, "CallEventReceiver read body err: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var event CallEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		dglogger.Errorf(ctx, "CallEventReceiver unmarshal event[%s] err: %v", string(data), err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if ctx.TraceId == "" {
+		ctx.TraceId = event.RequestId
+	}
+
+	if !r.config.SkipVerify {
+		if err := r.checkReplay(req.URL.Query().Get("Signature"), event.RequestId); err != nil {
+			dglogger.Errorf(ctx, "CallEventReceiver requestId: %s, type: %s, err: %v", event.RequestId, event.Type, err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if !r.acquire(event.RequestId) {
+		dglogger.Infof(ctx, "CallEventReceiver drop duplicate event, requestId: %s, type: %s", event.RequestId, event.Type)
+		writeCallEventAck(w)
+		return
+	}
+
+	err = r.dispatch(ctx, event.Type, data)
+	r.release(event.RequestId, err == nil)
+	if err != nil {
+		dglogger.Errorf(ctx, "CallEventReceiver handle event[requestId: %s, type: %s] err: %v", event.RequestId, event.Type, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeCallEventAck(w)
+}
+
+func (r *CallEventReceiver) dispatch(ctx *dgctx.DgContext, eventType CallEventType, data []byte) error {
+	r.mu.Lock()
+	handler, ok := r.handlers[eventType]
+	r.mu.Unlock()
+
+	if !ok {
+		dglogger.Debugf(ctx, "CallEventReceiver no handler for event type: %s", eventType)
+		return nil
+	}
+
+	return handler(ctx, data)
+}
+
+// verify 推送请求的签名方式与调用 /cc 接口一致：query 中携带 AccessKeyId、Timestamp、Expires、Signature，
+// 签名只覆盖 query，Signature 是否被重放在读取 body 后由 checkReplay 校验
+func (r *CallEventReceiver) verify(req *http.Request) error {
+	err := r.client.VerifyCCSignature(req, r.config.SignHost, &SignatureVerifyOptions{MaxAge: r.config.MaxAge})
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, SignatureExpiredErr):
+		return CallEventExpiredErr
+	default:
+		return CallEventSignatureErr
+	}
+}
+
+// checkReplay 同一个 Signature 只能用于一个 requestId：相同 requestId 的重复推送交给 requestId 去重，
+// 处理失败后推送方可以原样重试；同一个 Signature 携带其他 requestId 时视为重放
+func (r *CallEventReceiver) checkReplay(signature string, requestId string) error {
+	expireAt := r.client.now().Add(r.config.MaxAge + defaultMaxClockSkew)
+	first, err := r.config.NonceStore.Use(signature, expireAt)
+	if err != nil {
+		return err
+	}
+	firstForRequest, err := r.config.NonceStore.Use(signature+"\n"+requestId, expireAt)
+	if err != nil {
+		return err
+	}
+	if !first && firstForRequest {
+		return CallEventReplayedErr
+	}
+
+	return nil
+}
+
+// acquire 返回 false 表示该 requestId 已处理成功或正在处理中
+func (r *CallEventReceiver) acquire(requestId string) bool {
+	if requestId == "" {
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.handling[requestId] {
+		return false
+	}
+	if seenAt, ok := r.seen[requestId]; ok && time.Since(seenAt) < r.config.DedupTTL {
+		return false
+	}
+
+	r.handling[requestId] = true
+	return true
+}
+
+// release 处理失败时不记录 requestId，以便推送方重试
+func (r *CallEventReceiver) release(requestId string, handled bool) {
+	if requestId == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.handling, requestId)
+	if !handled {
+		return
+	}
+
+	if len(r.seen) >= r.config.DedupSize {
+		r.evictSeen()
+	}
+	r.seen[requestId] = time.Now()
+}
+
+// evictSeen 需在持有锁时调用，先清理过期的 requestId，仍然超限时清理最早的一半
+func (r *CallEventReceiver) evictSeen() {
+	now := time.Now()
+	var oldest time.Time
+	for requestId, seenAt := range r.seen {
+		if now.Sub(seenAt) >= r.config.DedupTTL {
+			delete(r.seen, requestId)
+		} else if oldest.IsZero() || seenAt.Before(oldest) {
+			oldest = seenAt
+		}
+	}
+
+	if len(r.seen) < r.config.DedupSize {
+		return
+	}
+
+	middle := oldest.Add(now.Sub(oldest) / 2)
+	for requestId, seenAt := range r.seen {
+		if !seenAt.After(middle) {
+			delete(r.seen, requestId)
+		}
+	}
+}
+
+func writeCallEventAck(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte("{\"code\":\"" + apiSuccessCode + "\"}"))
+}
@@ -0,0 +1,58 @@
+package iflytek_test
+
+import (
+	dgctx "github.com/darwinOrg/go-common/context"
+	"github.com/darwinOrg/go-common/model"
+	"github.com/darwinOrg/go-common/utils"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCallEventReceiver(t *testing.T) {
+	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{AccessKeyId: "ak", AccessKeySecret: "sk"})
+	receiver := dgkdxf.NewCallEventReceiver(client, &dgkdxf.CallEventReceiverConfig{SignHost: "push.example.com"})
+
+	var hangups []*dgkdxf.CallHangupEvent
+	receiver.OnHangup(func(_ *dgctx.DgContext, event *dgkdxf.CallHangupEvent) error {
+		hangups = append(hangups, event)
+		return nil
+	})
+
+	params := []*model.KeyValuePair[string, any]{
+		{Key: "AccessKeyId", Value: "ak"},
+		{Key: "Expires", Value: 600},
+		{Key: "Timestamp", Value: time.Now().Format("2006-01-02T15:04:05Z")},
+	}
+	signature, _ := client.SignParamsWithUrlPrefix(http.MethodPost+"push.example.com/events?", params)
+	params = append(params, &model.KeyValuePair[string, any]{Key: "Signature", Value: signature})
+	uri := "/events?" + utils.FormUrlEncodedParams(params)
+	body := `{"requestId":"r1","type":"hangup","cno":"1001","mainUniqueId":"m1","status":3,"bridgeDuration":42}`
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		receiver.ServeHTTP(w, httptest.NewRequest(http.MethodPost, uri, strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status: %d", w.Code)
+		}
+	}
+	if len(hangups) != 1 || hangups[0].MainUniqueId != "m1" || hangups[0].BridgeDuration != 42 {
+		t.Fatalf("hangups: %s", utils.MustConvertBeanToJsonString(hangups))
+	}
+
+	w := httptest.NewRecorder()
+	receiver.ServeHTTP(w, httptest.NewRequest(http.MethodPost, strings.Replace(uri, "Expires=600", "Expires=601", 1), strings.NewReader(body)))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("tampered status: %d", w.Code)
+	}
+
+	// 截获的推送地址不能携带其他事件重放
+	w = httptest.NewRecorder()
+	receiver.ServeHTTP(w, httptest.NewRequest(http.MethodPost, uri, strings.NewReader(strings.Replace(body, `"r1"`, `"r2"`, 1))))
+	if w.Code != http.StatusUnauthorized || len(hangups) != 1 {
+		t.Fatalf("replayed status: %d", w.Code)
+	}
+}
@@ -2,8 +2,6 @@ package iflytek
 
 import (
 	"bufio"
-	"errors"
-	"fmt"
 	dgctx "github.com/darwinOrg/go-common/context"
 	"github.com/darwinOrg/go-common/model"
 	"github.com/darwinOrg/go-common/utils"
@@ -17,25 +15,38 @@ import (
 )
 
 // DetailByCno 查看坐席详情
-func (c *Client) DetailByCno(ctx *dgctx.DgContext, conReq *CnoReq) (*CnoDetailResp, error) {
-	uri := c.buildDetailByCnoUri(conReq.Cno)
-	dglogger.Infof(ctx, "DetailByCno buildDetailByCnoUri: %s", uri)
+func (c *Client) DetailByCno(ctx *dgctx.DgContext, conReq *CnoReq) (_ *CnoDetailResp, err error) {
+	call, err := c.startCall(ctx, CcApi("/cc/describe_client?"))
+	if err != nil {
+		dglogger.Errorf(ctx, "DetailByCno rate limit err: %v", err)
+		return nil, err
+	}
+	defer func() { err = call.end(err) }()
+
+	uri, err := c.buildDetailByCnoUri(conReq.Cno)
+	if err != nil {
+		dglogger.Errorf(ctx, "DetailByCno credentials err: %v", err)
+		return nil, err
+	}
+	dglogger.Infof(ctx, "DetailByCno buildDetailByCnoUri: %s", c.redactUrl(uri))
 
 	req, err := http.NewRequest(http.MethodGet, uri, nil)
 	if err != nil {
 		dglogger.Errorf(ctx, "DetailByCno http.NewRequest err: %v", err)
 		return nil, err
 	}
+	call.setHeaders(req.Header)
 
 	response, err := dghttp.Client2.DoRequestRaw(ctx, req)
 	if err != nil {
 		dglogger.Errorf(ctx, "DetailByCno dghttp.Client2.DoRequestRaw err: %v", err)
 		return nil, err
 	}
+	call.statusCode = response.StatusCode
 
 	if response.StatusCode != http.StatusOK {
 		dglogger.Errorf(ctx, "DetailByCno dghttp.Client2.DoRequestRaw statusCode: %d", response.StatusCode)
-		return nil, errors.New(fmt.Sprintf("call detail cno statusCode: %d", response.StatusCode))
+		return nil, call.statusErr(response)
 	}
 
 	bytes, err := read(response)
@@ -44,7 +55,7 @@ func (c *Client) DetailByCno(ctx *dgctx.DgContext, conReq *CnoReq) (*CnoDetailRe
 		return nil, err
 	}
 
-	dglogger.Infof(ctx, "res: %s", string(bytes))
+	dglogger.Infof(ctx, "res: %s", c.redactJson(bytes))
 	calloutResp, err := utils.ConvertJsonBytesToBean[CnoDetailResp](bytes)
 	if err != nil {
 		dglogger.Errorf(ctx, "DetailByCno ConvertJsonBytesToBean err: %v", err)
@@ -55,15 +66,27 @@ func (c *Client) DetailByCno(ctx *dgctx.DgContext, conReq *CnoReq) (*CnoDetailRe
 }
 
 // Callout 外呼
-func (c *Client) Callout(ctx *dgctx.DgContext, calloutReq *CalloutReq) (*CalloutResp, error) {
-	uri := c.buildPostUri("/cc/callout?")
-	dglogger.Infof(ctx, "Callout buildPostUri: %s", uri)
+func (c *Client) Callout(ctx *dgctx.DgContext, calloutReq *CalloutReq) (_ *CalloutResp, err error) {
+	call, err := c.startCall(ctx, CcApi("/cc/callout?"))
+	if err != nil {
+		dglogger.Errorf(ctx, "Callout rate limit err: %v", err)
+		return nil, err
+	}
+	defer func() { err = call.end(err) }()
+
+	uri, err := c.buildPostUri("/cc/callout?")
+	if err != nil {
+		dglogger.Errorf(ctx, "Callout credentials err: %v", err)
+		return nil, err
+	}
+	dglogger.Infof(ctx, "Callout buildPostUri: %s", c.redactUrl(uri))
 
 	req, err := http.NewRequest(http.MethodPost, uri, strings.NewReader(utils.MustConvertBeanToJsonString(calloutReq)))
 	if err != nil {
 		dglogger.Errorf(ctx, "Callout http.NewRequest err: %v", err)
 		return nil, err
 	}
+	call.setHeaders(req.Header)
 	req.Header.Set("Content-Type", "application/json")
 
 	response, err := dghttp.Client2.DoRequestRaw(ctx, req)
@@ -71,10 +94,11 @@ func (c *Client) Callout(ctx *dgctx.DgContext, calloutReq *CalloutReq) (*Callout
 		dglogger.Errorf(ctx, "Callout dghttp.Client2.DoRequestRaw err: %v", err)
 		return nil, err
 	}
+	call.statusCode = response.StatusCode
 
 	if response.StatusCode != http.StatusOK {
 		dglogger.Errorf(ctx, "Callout dghttp.Client2.DoRequestRaw statusCode: %d", response.StatusCode)
-		return nil, errors.New(fmt.Sprintf("call callout statusCode: %d", response.StatusCode))
+		return nil, call.statusErr(response)
 	}
 
 	bytes, err := read(response)
@@ -83,7 +107,7 @@ func (c *Client) Callout(ctx *dgctx.DgContext, calloutReq *CalloutReq) (*Callout
 		return nil, err
 	}
 
-	dglogger.Infof(ctx, "res: %s", string(bytes))
+	dglogger.Infof(ctx, "res: %s", c.redactJson(bytes))
 	calloutResp, err := utils.ConvertJsonBytesToBean[CalloutResp](bytes)
 	if err != nil {
 		dglogger.Errorf(ctx, "Callout ConvertJsonBytesToBean err: %v", err)
@@ -94,15 +118,27 @@ func (c *Client) Callout(ctx *dgctx.DgContext, calloutReq *CalloutReq) (*Callout
 }
 
 // Cancel 外呼取消
-func (c *Client) Cancel(ctx *dgctx.DgContext, conReq *CnoReq) (*RequestIdResp, error) {
-	uri := c.buildPostUri("/cc/callout_cancel?")
-	dglogger.Infof(ctx, "Cancel buildPostUri: %s", uri)
+func (c *Client) Cancel(ctx *dgctx.DgContext, conReq *CnoReq) (_ *RequestIdResp, err error) {
+	call, err := c.startCall(ctx, CcApi("/cc/callout_cancel?"))
+	if err != nil {
+		dglogger.Errorf(ctx, "Cancel rate limit err: %v", err)
+		return nil, err
+	}
+	defer func() { err = call.end(err) }()
+
+	uri, err := c.buildPostUri("/cc/callout_cancel?")
+	if err != nil {
+		dglogger.Errorf(ctx, "Cancel credentials err: %v", err)
+		return nil, err
+	}
+	dglogger.Infof(ctx, "Cancel buildPostUri: %s", c.redactUrl(uri))
 
 	req, err := http.NewRequest(http.MethodPost, uri, strings.NewReader(utils.MustConvertBeanToJsonString(conReq)))
 	if err != nil {
 		dglogger.Errorf(ctx, "Cancel http.NewRequest err: %v", err)
 		return nil, err
 	}
+	call.setHeaders(req.Header)
 	req.Header.Set("Content-Type", "application/json")
 
 	response, err := dghttp.Client2.DoRequestRaw(ctx, req)
@@ -110,10 +146,11 @@ func (c *Client) Cancel(ctx *dgctx.DgContext, conReq *CnoReq) (*RequestIdResp, e
 		dglogger.Errorf(ctx, "Cancel dghttp.Client2.DoRequestRaw err: %v", err)
 		return nil, err
 	}
+	call.statusCode = response.StatusCode
 
 	if response.StatusCode != http.StatusOK {
 		dglogger.Errorf(ctx, "Cancel dghttp.Client2.DoRequestRaw statusCode: %d", response.StatusCode)
-		return nil, errors.New(fmt.Sprintf("call unlink statusCode: %d", response.StatusCode))
+		return nil, call.statusErr(response)
 	}
 
 	bytes, err := read(response)
@@ -122,7 +159,7 @@ func (c *Client) Cancel(ctx *dgctx.DgContext, conReq *CnoReq) (*RequestIdResp, e
 		return nil, err
 	}
 
-	dglogger.Infof(ctx, "res: %s", string(bytes))
+	dglogger.Infof(ctx, "res: %s", c.redactJson(bytes))
 	cancelResp, err := utils.ConvertJsonBytesToBean[RequestIdResp](bytes)
 	if err != nil {
 		dglogger.Errorf(ctx, "Cancel ConvertJsonBytesToBean err: %v", err)
@@ -133,15 +170,27 @@ func (c *Client) Cancel(ctx *dgctx.DgContext, conReq *CnoReq) (*RequestIdResp, e
 }
 
 // Unlink 挂机
-func (c *Client) Unlink(ctx *dgctx.DgContext, conReq *CnoReq) (*RequestIdResp, error) {
-	uri := c.buildPostUri("/cc/unlink?")
-	dglogger.Infof(ctx, "Unlink buildPostUri: %s", uri)
+func (c *Client) Unlink(ctx *dgctx.DgContext, conReq *CnoReq) (_ *RequestIdResp, err error) {
+	call, err := c.startCall(ctx, CcApi("/cc/unlink?"))
+	if err != nil {
+		dglogger.Errorf(ctx, "Unlink rate limit err: %v", err)
+		return nil, err
+	}
+	defer func() { err = call.end(err) }()
+
+	uri, err := c.buildPostUri("/cc/unlink?")
+	if err != nil {
+		dglogger.Errorf(ctx, "Unlink credentials err: %v", err)
+		return nil, err
+	}
+	dglogger.Infof(ctx, "Unlink buildPostUri: %s", c.redactUrl(uri))
 
 	req, err := http.NewRequest(http.MethodPost, uri, strings.NewReader(utils.MustConvertBeanToJsonString(conReq)))
 	if err != nil {
 		dglogger.Errorf(ctx, "Unlink http.NewRequest err: %v", err)
 		return nil, err
 	}
+	call.setHeaders(req.Header)
 	req.Header.Set("Content-Type", "application/json")
 
 	response, err := dghttp.Client2.DoRequestRaw(ctx, req)
@@ -149,10 +198,11 @@ func (c *Client) Unlink(ctx *dgctx.DgContext, conReq *CnoReq) (*RequestIdResp, e
 		dglogger.Errorf(ctx, "Unlink dghttp.Client2.DoRequestRaw err: %v", err)
 		return nil, err
 	}
+	call.statusCode = response.StatusCode
 
 	if response.StatusCode != http.StatusOK {
 		dglogger.Errorf(ctx, "Unlink dghttp.Client2.DoRequestRaw statusCode: %d", response.StatusCode)
-		return nil, errors.New(fmt.Sprintf("call unlink statusCode: %d", response.StatusCode))
+		return nil, call.statusErr(response)
 	}
 
 	bytes, err := read(response)
@@ -161,7 +211,7 @@ func (c *Client) Unlink(ctx *dgctx.DgContext, conReq *CnoReq) (*RequestIdResp, e
 		return nil, err
 	}
 
-	dglogger.Infof(ctx, "res: %s", string(bytes))
+	dglogger.Infof(ctx, "res: %s", c.redactJson(bytes))
 	unlinkResp, err := utils.ConvertJsonBytesToBean[RequestIdResp](bytes)
 	if err != nil {
 		dglogger.Errorf(ctx, "Unlink ConvertJsonBytesToBean err: %v", err)
@@ -172,15 +222,27 @@ func (c *Client) Unlink(ctx *dgctx.DgContext, conReq *CnoReq) (*RequestIdResp, e
 }
 
 // Online 上线
-func (c *Client) Online(ctx *dgctx.DgContext, onlineReq *OnlineReq) (*RequestIdResp, error) {
-	uri := c.buildPostUri("/cc/online?")
-	dglogger.Infof(ctx, "Online BuildOnlineUri: %s", uri)
+func (c *Client) Online(ctx *dgctx.DgContext, onlineReq *OnlineReq) (_ *RequestIdResp, err error) {
+	call, err := c.startCall(ctx, CcApi("/cc/online?"))
+	if err != nil {
+		dglogger.Errorf(ctx, "Online rate limit err: %v", err)
+		return nil, err
+	}
+	defer func() { err = call.end(err) }()
+
+	uri, err := c.buildPostUri("/cc/online?")
+	if err != nil {
+		dglogger.Errorf(ctx, "Online credentials err: %v", err)
+		return nil, err
+	}
+	dglogger.Infof(ctx, "Online BuildOnlineUri: %s", c.redactUrl(uri))
 
 	req, err := http.NewRequest(http.MethodPost, uri, strings.NewReader(utils.MustConvertBeanToJsonString(onlineReq)))
 	if err != nil {
 		dglogger.Errorf(ctx, "Online http.NewRequest err: %v", err)
 		return nil, err
 	}
+	call.setHeaders(req.Header)
 	req.Header.Set("Content-Type", "application/json")
 
 	response, err := dghttp.Client2.DoRequestRaw(ctx, req)
@@ -188,19 +250,20 @@ func (c *Client) Online(ctx *dgctx.DgContext, onlineReq *OnlineReq) (*RequestIdR
 		dglogger.Errorf(ctx, "Online dghttp.Client2.DoRequestRaw err: %v", err)
 		return nil, err
 	}
+	call.statusCode = response.StatusCode
 
 	if response.StatusCode != http.StatusOK {
 		dglogger.Errorf(ctx, "Online dghttp.Client2.DoRequestRaw statusCode: %d", response.StatusCode)
-		return nil, errors.New(fmt.Sprintf("call online statusCode: %d", response.StatusCode))
+		return nil, call.statusErr(response)
 	}
 
 	bytes, err := read(response)
 	if err != nil {
 		dglogger.Errorf(ctx, "Online read response err: %v", err)
-		return nil, errors.New(fmt.Sprintf("call online statusCode: %d", response.StatusCode))
+		return nil, err
 	}
 
-	dglogger.Infof(ctx, "res: %s", string(bytes))
+	dglogger.Infof(ctx, "res: %s", c.redactJson(bytes))
 	onlineResp, err := utils.ConvertJsonBytesToBean[RequestIdResp](bytes)
 	if err != nil {
 		dglogger.Errorf(ctx, "Online ConvertJsonBytesToBean err: %v", err)
@@ -211,15 +274,27 @@ func (c *Client) Online(ctx *dgctx.DgContext, onlineReq *OnlineReq) (*RequestIdR
 }
 
 // Offline 下线
-func (c *Client) Offline(ctx *dgctx.DgContext, offlineReq *OfflineReq) (*OfflineResp, error) {
-	uri := c.buildPostUri("/cc/offline?")
-	dglogger.Infof(ctx, "Offline buildPostUri: %s", uri)
+func (c *Client) Offline(ctx *dgctx.DgContext, offlineReq *OfflineReq) (_ *OfflineResp, err error) {
+	call, err := c.startCall(ctx, CcApi("/cc/offline?"))
+	if err != nil {
+		dglogger.Errorf(ctx, "Offline rate limit err: %v", err)
+		return nil, err
+	}
+	defer func() { err = call.end(err) }()
+
+	uri, err := c.buildPostUri("/cc/offline?")
+	if err != nil {
+		dglogger.Errorf(ctx, "Offline credentials err: %v", err)
+		return nil, err
+	}
+	dglogger.Infof(ctx, "Offline buildPostUri: %s", c.redactUrl(uri))
 
 	req, err := http.NewRequest(http.MethodPost, uri, strings.NewReader(utils.MustConvertBeanToJsonString(offlineReq)))
 	if err != nil {
 		dglogger.Errorf(ctx, "Offline http.NewRequest err: %v", err)
 		return nil, err
 	}
+	call.setHeaders(req.Header)
 	req.Header.Set("Content-Type", "application/json")
 
 	response, err := dghttp.Client2.DoRequestRaw(ctx, req)
@@ -227,19 +302,20 @@ func (c *Client) Offline(ctx *dgctx.DgContext, offlineReq *OfflineReq) (*Offline
 		dglogger.Errorf(ctx, "Offline dghttp.Client2.DoRequestRaw err: %v", err)
 		return nil, err
 	}
+	call.statusCode = response.StatusCode
 
 	if response.StatusCode != http.StatusOK {
 		dglogger.Errorf(ctx, "Offline dghttp.Client2.DoRequestRaw statusCode: %d", response.StatusCode)
-		return nil, errors.New(fmt.Sprintf("call offline statusCode: %d", response.StatusCode))
+		return nil, call.statusErr(response)
 	}
 
 	bytes, err := read(response)
 	if err != nil {
 		dglogger.Errorf(ctx, "Offline read response err: %v", err)
-		return nil, errors.New(fmt.Sprintf("call offline statusCode: %d", response.StatusCode))
+		return nil, err
 	}
 
-	dglogger.Infof(ctx, "res: %s", string(bytes))
+	dglogger.Infof(ctx, "res: %s", c.redactJson(bytes))
 	offlineResp, err := utils.ConvertJsonBytesToBean[OfflineResp](bytes)
 	if err != nil {
 		dglogger.Errorf(ctx, "Offline ConvertJsonBytesToBean err: %v", err)
@@ -250,56 +326,135 @@ func (c *Client) Offline(ctx *dgctx.DgContext, offlineReq *OfflineReq) (*Offline
 }
 
 // ListCdrObs 查询外呼通话记录列表
-func (c *Client) ListCdrObs(ctx *dgctx.DgContext, listCdrObsReq *ListCdrObsReq) {
-	uri := c.buildListCdrObsUri(listCdrObsReq)
-	dglogger.Infof(ctx, "ListCdrObs buildListCdrObsUri: %s", uri)
+func (c *Client) ListCdrObs(ctx *dgctx.DgContext, listCdrObsReq *ListCdrObsReq) (_ *ListCdrObsResp, err error) {
+	call, err := c.startCall(ctx, CcApi("/cc/list_cdr_obs?"))
+	if err != nil {
+		dglogger.Errorf(ctx, "ListCdrObs rate limit err: %v", err)
+		return nil, err
+	}
+	defer func() { err = call.end(err) }()
+
+	uri, err := c.buildListCdrObsUri(listCdrObsReq)
+	if err != nil {
+		dglogger.Errorf(ctx, "ListCdrObs credentials err: %v", err)
+		return nil, err
+	}
+	dglogger.Infof(ctx, "ListCdrObs buildListCdrObsUri: %s", c.redactUrl(uri))
 
 	req, err := http.NewRequest(http.MethodGet, uri, nil)
 	if err != nil {
 		dglogger.Errorf(ctx, "ListCdrObs http.NewRequest err: %v", err)
-		return
+		return nil, err
 	}
+	call.setHeaders(req.Header)
 
 	response, err := dghttp.Client2.DoRequestRaw(ctx, req)
 	if err != nil {
 		dglogger.Errorf(ctx, "ListCdrObs dghttp.Client2.DoRequestRaw err: %v", err)
-		return
+		return nil, err
 	}
+	call.statusCode = response.StatusCode
 
 	if response.StatusCode != http.StatusOK {
 		dglogger.Errorf(ctx, "ListCdrObs dghttp.Client2.DoRequestRaw statusCode: %d", response.StatusCode)
-		return
+		return nil, call.statusErr(response)
 	}
 
 	bytes, err := read(response)
 	if err != nil {
 		dglogger.Errorf(ctx, "ListCdrObs read response err: %v", err)
-		return
+		return nil, err
 	}
 
-	dglogger.Infof(ctx, "res: %s", string(bytes))
+	dglogger.Infof(ctx, "res: %s", c.redactJson(bytes))
+	listCdrObsResp, err := utils.ConvertJsonBytesToBean[ListCdrObsResp](bytes)
+	if err != nil {
+		dglogger.Errorf(ctx, "ListCdrObs ConvertJsonBytesToBean err: %v", err)
+		return nil, err
+	}
+
+	return listCdrObsResp, nil
 }
 
 // DownloadRecordFile 下载通话详情录音文件
 func (c *Client) DownloadRecordFile(ctx *dgctx.DgContext, downloadRecordFileReq *DownloadRecordFileReq) (*DownloadRecordFileResp, error) {
-	uri := c.buildDownloadRecordFileUri(downloadRecordFileReq)
-	dglogger.Infof(ctx, "DownloadRecordFile buildDownloadRecordFileUri: %s", uri)
+	response, fileName, err := c.requestRecordFile(ctx, downloadRecordFileReq)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	filepath := "/tmp/" + fileName
+	file, err := os.Create(filepath)
+	if err != nil {
+		dglogger.Errorf(ctx, "DownloadRecordFile os.Create err: %v", err)
+		return nil, err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriterSize(file, defaultBufferSize)
+	defer writer.Flush()
+
+	_, err = io.Copy(writer, bufio.NewReaderSize(response.Body, defaultBufferSize))
+	if err != nil {
+		dglogger.Errorf(ctx, "DownloadRecordFile io.Copy err: %v", err)
+		return nil, err
+	}
+
+	return &DownloadRecordFileResp{Filepath: filepath, Filename: fileName}, nil
+}
+
+// DownloadRecordFileTo 下载通话详情录音文件并写入 writer，返回文件名称
+func (c *Client) DownloadRecordFileTo(ctx *dgctx.DgContext, downloadRecordFileReq *DownloadRecordFileReq, writer io.Writer) (string, error) {
+	response, fileName, err := c.requestRecordFile(ctx, downloadRecordFileReq)
+	if err != nil {
+		return "", err
+	}
+
+	defer response.Body.Close()
+
+	_, err = io.Copy(writer, bufio.NewReaderSize(response.Body, defaultBufferSize))
+	if err != nil {
+		dglogger.Errorf(ctx, "DownloadRecordFileTo io.Copy err: %v", err)
+		return "", err
+	}
+
+	return fileName, nil
+}
+
+func (c *Client) requestRecordFile(ctx *dgctx.DgContext, downloadRecordFileReq *DownloadRecordFileReq) (_ *http.Response, _ string, err error) {
+	call, err := c.startCall(ctx, CcApi("/cc/download_record_file?"))
+	if err != nil {
+		dglogger.Errorf(ctx, "DownloadRecordFile rate limit err: %v", err)
+		return nil, "", err
+	}
+	defer func() { err = call.end(err) }()
+
+	uri, err := c.buildDownloadRecordFileUri(downloadRecordFileReq)
+	if err != nil {
+		dglogger.Errorf(ctx, "DownloadRecordFile credentials err: %v", err)
+		return nil, "", err
+	}
+	dglogger.Infof(ctx, "DownloadRecordFile buildDownloadRecordFileUri: %s", c.redactUrl(uri))
 
 	req, err := http.NewRequest(http.MethodGet, uri, nil)
 	if err != nil {
 		dglogger.Errorf(ctx, "DownloadRecordFile http.NewRequest err: %v", err)
-		return nil, err
+		return nil, "", err
 	}
+	call.setHeaders(req.Header)
 
 	response, err := dghttp.Client11.DoRequestRaw(ctx, req)
 	if err != nil {
 		dglogger.Errorf(ctx, "DownloadRecordFile dghttp.Client11.DoRequestRaw err: %v", err)
-		return nil, err
+		return nil, "", err
 	}
+	call.statusCode = response.StatusCode
 
 	if response.StatusCode != http.StatusOK {
 		dglogger.Errorf(ctx, "DownloadRecordFile dghttp.Client11.DoRequestRaw statusCode: %d", response.StatusCode)
-		return nil, errors.New(fmt.Sprintf("call download_record_file statusCode: %d", response.StatusCode))
+		return nil, "", call.statusErr(response)
 	}
 
 	disposition := response.Header.Get("Content-Disposition")
@@ -307,139 +462,134 @@ func (c *Client) DownloadRecordFile(ctx *dgctx.DgContext, downloadRecordFileReq
 	if disposition != "" {
 		_, params, err := mime.ParseMediaType(disposition)
 		if err != nil {
+			response.Body.Close()
 			dglogger.Errorf(ctx, "DownloadRecordFile ParseMediaType err: %v", err)
 
-			return nil, err
+			return nil, "", err
 		}
 		var ok bool
 		fileName, ok = params["filename"]
 		if !ok {
+			response.Body.Close()
 			dglogger.Errorf(ctx, "DownloadRecordFile filename not exist")
-			return nil, errors.New("DownloadRecordFile filename not exist")
+			return nil, "", call.err(EmptyResponseErr, "filename not exist in Content-Disposition")
 		}
 	}
 
-	defer response.Body.Close()
+	// 录音边读边写，关闭 Body 时才归还并发名额
+	response.Body = &releaseOnCloseBody{ReadCloser: response.Body, release: call.detachRelease()}
 
-	filepath := "/tmp/" + fileName
-	file, err := os.Create(filepath)
+	return response, fileName, nil
+}
+
+// BindClientTel 绑定座席电话
+func (c *Client) BindClientTel(ctx *dgctx.DgContext, bindReq *BindClientTelReq) (err error) {
+	call, err := c.startCall(ctx, CcApi("/cc/bind_client_tel?"))
 	if err != nil {
-		dglogger.Errorf(ctx, "DownloadRecordFile os.Create err: %v", err)
-		return nil, err
+		dglogger.Errorf(ctx, "BindClientTel rate limit err: %v", err)
+		return err
 	}
-	defer file.Close()
+	defer func() { err = call.end(err) }()
 
-	writer := bufio.NewWriterSize(file, defaultBufferSize)
-	defer writer.Flush()
-
-	_, err = io.Copy(writer, bufio.NewReaderSize(response.Body, defaultBufferSize))
+	uri, err := c.buildPostUri("/cc/bind_client_tel?")
 	if err != nil {
-		dglogger.Errorf(ctx, "DownloadRecordFile io.Copy err: %v", err)
-		return nil, err
+		dglogger.Errorf(ctx, "BindClientTel credentials err: %v", err)
+		return err
 	}
-
-	return &DownloadRecordFileResp{Filepath: filepath, Filename: fileName}, nil
-}
-
-// BindClientTel 绑定座席电话
-func (c *Client) BindClientTel(ctx *dgctx.DgContext, bindReq *BindClientTelReq) error {
-	uri := c.buildPostUri("/cc/bind_client_tel?")
-	dglogger.Infof(ctx, "BindClientTel buildPostUri: %s", uri)
+	dglogger.Infof(ctx, "BindClientTel buildPostUri: %s", c.redactUrl(uri))
 	dghttp.SetHttpClient(ctx, dghttp.Client2)
 	defer dghttp.SetHttpClient(ctx, nil)
-	resp, err := dghttp.DoPostJsonToStruct[KdxfResponse](ctx, uri, bindReq, nil)
+	resp, err := dghttp.DoPostJsonToStruct[KdxfResponse](ctx, uri, bindReq, call.headers())
 	if err != nil {
-		dglogger.Errorf(ctx, "BindClientTel[%+v] do post err: %v", bindReq, err)
+		dglogger.Errorf(ctx, "BindClientTel[%s] do post err: %v", c.redactBean(bindReq), err)
 		return err
 	}
 	if resp.Error.Message != "" {
-		return errors.New(resp.Error.Message)
+		call.code, call.requestId = resp.Error.Code, resp.RequestID
+		return call.err(ApiNoSuccessErr, resp.Error.Message)
 	}
 	return nil
 }
 
 // UnbindClientTel 解绑座席电话
-func (c *Client) UnbindClientTel(ctx *dgctx.DgContext, unbindReq *UnbindClientTelReq) error {
-	uri := c.buildPostUri("/cc/unbind_client_tel?")
-	dglogger.Infof(ctx, "UnbindClientTel buildPostUri: %s", uri)
+func (c *Client) UnbindClientTel(ctx *dgctx.DgContext, unbindReq *UnbindClientTelReq) (err error) {
+	call, err := c.startCall(ctx, CcApi("/cc/unbind_client_tel?"))
+	if err != nil {
+		dglogger.Errorf(ctx, "UnbindClientTel rate limit err: %v", err)
+		return err
+	}
+	defer func() { err = call.end(err) }()
+
+	uri, err := c.buildPostUri("/cc/unbind_client_tel?")
+	if err != nil {
+		dglogger.Errorf(ctx, "UnbindClientTel credentials err: %v", err)
+		return err
+	}
+	dglogger.Infof(ctx, "UnbindClientTel buildPostUri: %s", c.redactUrl(uri))
 	dghttp.SetHttpClient(ctx, dghttp.Client2)
 	defer dghttp.SetHttpClient(ctx, nil)
-	resp, err := dghttp.DoPostJsonToStruct[KdxfResponse](ctx, uri, unbindReq, nil)
+	resp, err := dghttp.DoPostJsonToStruct[KdxfResponse](ctx, uri, unbindReq, call.headers())
 	if err != nil {
 		dglogger.Errorf(ctx, "UnbindClientTel[%+v] do post err: %v", unbindReq, err)
 		return err
 	}
 	if resp.Error.Message != "" {
-		return errors.New(resp.Error.Message)
+		call.code, call.requestId = resp.Error.Code, resp.RequestID
+		return call.err(ApiNoSuccessErr, resp.Error.Message)
 	}
 	return nil
 }
 
-func (c *Client) buildPostUri(callUrl string) string {
-	params := c.buildCommonParams()
-	after := cutPrefix(c.Config.Host, "https://")
-	urlPrefix := fmt.Sprintf("%s%s%s", http.MethodPost, after, callUrl)
-	signature := c.GenerateSignatureWithUrlPrefix(urlPrefix, params)
-
-	params = append(params, &model.KeyValuePair[string, any]{Key: "Signature", Value: signature})
-	parameters := utils.FormUrlEncodedParams(params)
-
-	return c.Config.Host + callUrl + parameters
+func (c *Client) buildPostUri(callUrl string) (string, error) {
+	return c.buildSignedCcUri(http.MethodPost, callUrl, nil)
 }
 
-func (c *Client) buildDetailByCnoUri(cno string) string {
-	params := c.buildCommonParams()
-	params = append(params, &model.KeyValuePair[string, any]{Key: "cno", Value: cno})
-	sortParams(params)
+func (c *Client) buildDetailByCnoUri(cno string) (string, error) {
+	params := []*model.KeyValuePair[string, any]{{Key: "cno", Value: cno}}
 
 	callUrl := "/cc/describe_client?"
-	after := cutPrefix(c.Config.Host, "https://")
-	urlPrefix := fmt.Sprintf("%s%s%s", http.MethodGet, after, callUrl)
-	signature := c.GenerateSignatureWithUrlPrefix(urlPrefix, params)
-
-	params = append(params, &model.KeyValuePair[string, any]{Key: "Signature", Value: signature})
-	parameters := utils.FormUrlEncodedParams(params)
-
-	return c.Config.Host + callUrl + parameters
+	return c.buildSignedCcUri(http.MethodGet, callUrl, params)
 }
 
-func (c *Client) buildListCdrObsUri(listCdrObsReq *ListCdrObsReq) string {
-	params := c.buildCommonParams()
+func (c *Client) buildListCdrObsUri(listCdrObsReq *ListCdrObsReq) (string, error) {
+	var params []*model.KeyValuePair[string, any]
 	params = append(params, &model.KeyValuePair[string, any]{Key: "hiddenType", Value: listCdrObsReq.HiddenType})
 	params = append(params, &model.KeyValuePair[string, any]{Key: "customerNumber", Value: listCdrObsReq.CustomerNumber})
 	params = append(params, &model.KeyValuePair[string, any]{Key: "cno", Value: listCdrObsReq.Cno})
 	params = append(params, &model.KeyValuePair[string, any]{Key: "status", Value: listCdrObsReq.Status})
-	sortParams(params)
 
 	callUrl := "/cc/list_cdr_obs?"
-	after := cutPrefix(c.Config.Host, "https://")
-	urlPrefix := fmt.Sprintf("%s%s%s", http.MethodGet, after, callUrl)
-	signature := c.GenerateSignatureWithUrlPrefix(urlPrefix, params)
-
-	params = append(params, &model.KeyValuePair[string, any]{Key: "Signature", Value: signature})
-	parameters := utils.FormUrlEncodedParams(params)
-
-	return c.Config.Host + callUrl + parameters
+	return c.buildSignedCcUri(http.MethodGet, callUrl, params)
 }
 
-func (c *Client) buildDownloadRecordFileUri(downloadRecordFileReq *DownloadRecordFileReq) string {
-	params := c.buildCommonParams()
+func (c *Client) buildDownloadRecordFileUri(downloadRecordFileReq *DownloadRecordFileReq) (string, error) {
+	var params []*model.KeyValuePair[string, any]
 	params = append(params, &model.KeyValuePair[string, any]{Key: "mainUniqueId", Value: downloadRecordFileReq.MainUniqueId})
 	if downloadRecordFileReq.RecordSide > 0 {
 		params = append(params, &model.KeyValuePair[string, any]{Key: "recordSide", Value: downloadRecordFileReq.RecordSide})
 	}
 	params = append(params, &model.KeyValuePair[string, any]{Key: "recordType", Value: downloadRecordFileReq.RecordType})
-	sortParams(params)
 
 	callUrl := "/cc/download_record_file?"
-	after := cutPrefix(c.Config.Host, "https://")
-	urlPrefix := fmt.Sprintf("%s%s%s", http.MethodGet, after, callUrl)
-	signature := c.GenerateSignatureWithUrlPrefix(urlPrefix, params)
+	return c.buildSignedCcUri(http.MethodGet, callUrl, params)
+}
+
+// buildSignedCcUri 在业务参数上加入公共参数后签名，签名串为 METHOD + host + path? + 排序后的参数
+func (c *Client) buildSignedCcUri(method string, callUrl string, params []*model.KeyValuePair[string, any]) (string, error) {
+	creds, err := c.credentials()
+	if err != nil {
+		return "", err
+	}
+	params = append(c.buildCommonParams(creds), params...)
+	sortParams(params)
+
+	host := c.Config.ServiceHost(ServiceCc)
+	signature := creds.sign(ccStringToSign(method, host, callUrl, params))
 
 	params = append(params, &model.KeyValuePair[string, any]{Key: "Signature", Value: signature})
 	parameters := utils.FormUrlEncodedParams(params)
 
-	return c.Config.Host + callUrl + parameters
+	return host + callUrl + parameters, nil
 }
 
 func read(resp *http.Response) ([]byte, error) {
@@ -452,10 +602,3 @@ func read(resp *http.Response) ([]byte, error) {
 	defer resp.Body.Close()
 	return io.ReadAll(resp.Body)
 }
-
-func cutPrefix(s, prefix string) string {
-	if !strings.HasPrefix(s, prefix) {
-		return s
-	}
-	return s[len(prefix):]
-}
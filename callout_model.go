@@ -66,6 +66,14 @@ type ListCdrObsReq struct {
 	Status         int32  `json:"status"`         // 接听状态 0: 全部 1: 客户未接听 2: 座席未接听 3: 双方接听
 }
 
+type ListCdrObsResp struct {
+	RequestId  string           `json:"requestId"`
+	PageNumber int              `json:"pageNumber"`
+	PageSize   int              `json:"pageSize"`
+	TotalCount int              `json:"totalCount"`
+	CdrObs     []map[string]any `json:"cdrObs"` // 通话记录，字段随 hiddenType 和账号配置变化，按原样返回
+}
+
 type DownloadRecordFileReq struct {
 	MainUniqueId string `binding:"required" json:"mainUniqueId"` // 通话记录唯一标识
 	RecordSide   int32  `json:"recordSide"`                      // 不传递获取mp3格式录音，传递时获取wav格式录音。1：双轨录音客户侧，2：双轨录音座席侧，3：两侧合成录音
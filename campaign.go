@@ -0,0 +1,475 @@
+package iflytek
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dglogger "github.com/darwinOrg/go-logger"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type CampaignNumberStatus string
+
+const (
+	CampaignNumberStatusPending     CampaignNumberStatus = "pending"     // 等待外呼
+	CampaignNumberStatusDialing     CampaignNumberStatus = "dialing"     // 已发起外呼，等待挂机事件
+	CampaignNumberStatusUnanswered  CampaignNumberStatus = "unanswered"  // 未接通，冷却后重试
+	CampaignNumberStatusAnswered    CampaignNumberStatus = "answered"    // 双方接听
+	CampaignNumberStatusFailed      CampaignNumberStatus = "failed"      // 重试次数用尽
+	CampaignNumberStatusUnconfirmed CampaignNumberStatus = "unconfirmed" // 超时未收到挂机事件，为避免重复拨打不再重试
+
+	hangupStatusBothAnswered = 3
+
+	defaultCampaignMaxCallsPerAgent = 1
+	defaultCampaignMaxAttempts      = 3
+	defaultCampaignRetryCooldown    = 30 * time.Minute
+	defaultCampaignDialInterval     = time.Second
+	defaultCampaignCallTimeout      = 10 * time.Minute
+)
+
+var CampaignStoppedErr = errors.New("campaign stopped")
+
+type CampaignConfig struct {
+	CampaignId       string        `json:"campaignId" binding:"required"` // 活动标识，用于持久化进度和生成 RequestUniqueId
+	Cnos             []string      `json:"cnos" binding:"required"`       // 参与外呼的坐席，需已在 AgentManager 中设置期望在线
+	CustomerNumbers  []string      `json:"customerNumbers"`               // 客户号码，重启时已持久化的号码不会重复加入
+	MaxConcurrency   int           `json:"maxConcurrency"`                // 全局最大并发通话数，默认为坐席数乘以 MaxCallsPerAgent
+	MaxCallsPerAgent int           `json:"maxCallsPerAgent"`              // 单个坐席最大并发通话数，默认1
+	MaxAttempts      int           `json:"maxAttempts"`                   // 单个号码最大拨打次数，默认3
+	RetryCooldown    time.Duration `json:"retryCooldown"`                 // 未接通号码的重试冷却时间，默认30分钟
+	DialInterval     time.Duration `json:"dialInterval"`                  // 两次外呼之间的最小间隔，默认1秒
+	CallTimeout      time.Duration `json:"callTimeout"`                   // 外呼后等待挂机事件的最长时间，默认10分钟
+}
+
+type CampaignNumber struct {
+	CustomerNumber  string               `json:"customerNumber"`
+	Status          CampaignNumberStatus `json:"status"`
+	Attempts        int                  `json:"attempts"`
+	RequestUniqueId string               `json:"requestUniqueId"` // 最近一次外呼的幂等标识
+	Cno             string               `json:"cno"`
+	MainUniqueId    string               `json:"mainUniqueId"`
+	DialedAt        time.Time            `json:"dialedAt"`
+	NextDialAt      time.Time            `json:"nextDialAt"`
+	LastError       string               `json:"lastError"`
+}
+
+type CampaignProgress struct {
+	CampaignId string            `json:"campaignId"`
+	Numbers    []*CampaignNumber `json:"numbers"`
+	UpdatedAt  time.Time         `json:"updatedAt"`
+}
+
+// CampaignStore 持久化外呼活动进度，Load 在没有记录时返回 nil, nil
+type CampaignStore interface {
+	Load(campaignId string) (*CampaignProgress, error)
+	Save(progress *CampaignProgress) error
+}
+
+// FileCampaignStore 将进度以 json 文件保存在 Dir 目录下
+type FileCampaignStore struct {
+	Dir string
+}
+
+func (s *FileCampaignStore) Load(campaignId string) (*CampaignProgress, error) {
+	data, err := os.ReadFile(s.path(campaignId))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &CampaignProgress{}
+	if err := json.Unmarshal(data, progress); err != nil {
+		return nil, err
+	}
+
+	return progress, nil
+}
+
+// Save 先写入同目录下的临时文件再替换，并发保存时各自使用不同的临时文件
+func (s *FileCampaignStore) Save(progress *CampaignProgress) error {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(s.Dir, progress.CampaignId+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), s.path(progress.CampaignId))
+}
+
+func (s *FileCampaignStore) path(campaignId string) string {
+	return filepath.Join(s.Dir, campaignId+".json")
+}
+
+// CampaignDialer 将客户号码分配给空闲坐席外呼，需将 HandleHangup 注册到 CallEventReceiver.OnHangup
+type CampaignDialer struct {
+	agents    *AgentManager
+	store     CampaignStore
+	config    *CampaignConfig
+	mu        sync.Mutex
+	saveMu    sync.Mutex // 保证快照按生成顺序保存，较早的快照不会覆盖较新的
+	progress  *CampaignProgress
+	inflight  map[string]int
+	byRequest map[string]*CampaignNumber
+	stop      chan struct{}
+}
+
+func NewCampaignDialer(agents *AgentManager, store CampaignStore, config *CampaignConfig) (*CampaignDialer, error) {
+	if config.CampaignId == "" || len(config.Cnos) == 0 {
+		return nil, errors.New("campaignId and cnos are required")
+	}
+	if config.MaxCallsPerAgent <= 0 {
+		config.MaxCallsPerAgent = defaultCampaignMaxCallsPerAgent
+	}
+	if config.MaxConcurrency <= 0 {
+		config.MaxConcurrency = len(config.Cnos) * config.MaxCallsPerAgent
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultCampaignMaxAttempts
+	}
+	if config.RetryCooldown <= 0 {
+		config.RetryCooldown = defaultCampaignRetryCooldown
+	}
+	if config.DialInterval <= 0 {
+		config.DialInterval = defaultCampaignDialInterval
+	}
+	if config.CallTimeout <= 0 {
+		config.CallTimeout = defaultCampaignCallTimeout
+	}
+
+	progress, err := store.Load(config.CampaignId)
+	if err != nil {
+		return nil, err
+	}
+	if progress == nil {
+		progress = &CampaignProgress{CampaignId: config.CampaignId}
+	}
+
+	d := &CampaignDialer{
+		agents:    agents,
+		store:     store,
+		config:    config,
+		progress:  progress,
+		inflight:  map[string]int{},
+		byRequest: map[string]*CampaignNumber{},
+		stop:      make(chan struct{}),
+	}
+
+	known := map[string]bool{}
+	for _, number := range progress.Numbers {
+		known[number.CustomerNumber] = true
+		if number.Status == CampaignNumberStatusDialing {
+			d.inflight[number.Cno]++
+			d.byRequest[number.RequestUniqueId] = number
+		}
+	}
+	for _, customerNumber := range config.CustomerNumbers {
+		if known[customerNumber] {
+			continue
+		}
+		known[customerNumber] = true
+		progress.Numbers = append(progress.Numbers, &CampaignNumber{CustomerNumber: customerNumber, Status: CampaignNumberStatusPending})
+	}
+
+	if err := d.save(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Run 按 DialInterval 节奏持续外呼，所有号码进入终态后返回 nil，调用 Stop 后返回 CampaignStoppedErr
+func (d *CampaignDialer) Run(ctx *dgctx.DgContext) error {
+	ticker := time.NewTicker(d.config.DialInterval)
+	defer ticker.Stop()
+
+	for {
+		finished, err := d.Step(ctx)
+		if err != nil {
+			dglogger.Errorf(ctx, "CampaignDialer[%s] step err: %v", d.config.CampaignId, err)
+		}
+		if finished {
+			return nil
+		}
+
+		select {
+		case <-d.stop:
+			return CampaignStoppedErr
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *CampaignDialer) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	select {
+	case <-d.stop:
+	default:
+		close(d.stop)
+	}
+}
+
+// Step 处理超时的通话并最多发起一次外呼，返回所有号码是否已进入终态
+func (d *CampaignDialer) Step(ctx *dgctx.DgContext) (bool, error) {
+	d.mu.Lock()
+	now := time.Now()
+	changed := d.expireDialing(now)
+	if d.finished() {
+		d.mu.Unlock()
+		return true, d.saveIf(changed)
+	}
+
+	index, number, cno := d.next(now)
+	if number == nil {
+		d.mu.Unlock()
+		return false, d.saveIf(changed)
+	}
+
+	number.Attempts++
+	number.Status = CampaignNumberStatusDialing
+	number.Cno = cno
+	number.MainUniqueId = ""
+	number.DialedAt = now
+	// 使用号码在活动中的序号而不是号码本身，RequestUniqueId 会出现在日志和讯飞的回调中
+	number.RequestUniqueId = fmt.Sprintf("%s-%d-%d", d.config.CampaignId, index, number.Attempts)
+	d.inflight[cno]++
+	d.byRequest[number.RequestUniqueId] = number
+	calloutReq := &CalloutReq{Cno: cno, CustomerNumber: number.CustomerNumber, RequestUniqueId: number.RequestUniqueId}
+	d.mu.Unlock()
+
+	// 外呼前先持久化 RequestUniqueId，重启后等待该次外呼的挂机事件而不是重新拨打
+	if err := d.save(); err != nil {
+		d.mu.Lock()
+		d.revert(number)
+		d.mu.Unlock()
+		return false, err
+	}
+
+	_, err := d.agents.Callout(ctx, calloutReq)
+	if err == nil {
+		dglogger.Infof(ctx, "CampaignDialer[%s] callout cno: %s, requestUniqueId: %s", d.config.CampaignId, cno, calloutReq.RequestUniqueId)
+		return false, nil
+	}
+
+	d.mu.Lock()
+	switch {
+	case calloutNotPlaced(err):
+		d.revert(number)
+	case calloutRejected(err):
+		d.finishAttempt(number, false, false, err.Error(), time.Now())
+	default:
+		// 超时、连接中断或 5xx 时外呼可能已经发出，保持 dialing 和原 RequestUniqueId，
+		// 等待挂机事件或由 expireDialing 标记为 unconfirmed，避免重复拨打
+		dglogger.Warnf(ctx, "CampaignDialer[%s] callout requestUniqueId: %s result unknown: %v", d.config.CampaignId, calloutReq.RequestUniqueId, err)
+		number.LastError = err.Error()
+	}
+	d.mu.Unlock()
+
+	return false, errors.Join(err, d.save())
+}
+
+// HandleHangup 根据挂机事件更新号码状态，可直接注册到 CallEventReceiver.OnHangup
+func (d *CampaignDialer) HandleHangup(ctx *dgctx.DgContext, event *CallHangupEvent) error {
+	d.mu.Lock()
+	number, ok := d.byRequest[event.RequestUniqueId]
+	if !ok || number.Status != CampaignNumberStatusDialing {
+		d.mu.Unlock()
+		return nil
+	}
+
+	number.MainUniqueId = event.MainUniqueId
+	d.finishAttempt(number, true, event.Status == hangupStatusBothAnswered, "", time.Now())
+	d.mu.Unlock()
+
+	dglogger.Infof(ctx, "CampaignDialer[%s] hangup requestUniqueId: %s, status: %d", d.config.CampaignId, event.RequestUniqueId, event.Status)
+	return d.save()
+}
+
+// Progress 当前进度的快照
+func (d *CampaignDialer) Progress() *CampaignProgress {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	snapshot := &CampaignProgress{CampaignId: d.progress.CampaignId, UpdatedAt: d.progress.UpdatedAt}
+	for _, number := range d.progress.Numbers {
+		copied := *number
+		snapshot.Numbers = append(snapshot.Numbers, &copied)
+	}
+
+	return snapshot
+}
+
+// next 需在持有锁时调用
+func (d *CampaignDialer) next(now time.Time) (int, *CampaignNumber, string) {
+	total := 0
+	for _, calls := range d.inflight {
+		total += calls
+	}
+	if total >= d.config.MaxConcurrency {
+		return 0, nil, ""
+	}
+
+	index := -1
+	for i, n := range d.progress.Numbers {
+		if n.Status == CampaignNumberStatusPending || n.Status == CampaignNumberStatusUnanswered && !now.Before(n.NextDialAt) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return 0, nil, ""
+	}
+	number := d.progress.Numbers[index]
+
+	// 只使用空闲坐席；MaxCallsPerAgent 大于1时，坐席的通话都来自本活动时可以继续分配
+	for _, cno := range d.config.Cnos {
+		if d.inflight[cno] >= d.config.MaxCallsPerAgent {
+			continue
+		}
+		state := d.agents.State(cno)
+		if state == AgentStateOnline || state == AgentStateBusy && d.config.MaxCallsPerAgent > 1 && d.agents.calls(cno) <= d.inflight[cno] {
+			return index, number, cno
+		}
+	}
+
+	return 0, nil, ""
+}
+
+// calloutNotPlaced 坐席不可用或请求没有被讯飞处理，撤销本次外呼
+func calloutNotPlaced(err error) bool {
+	if errors.Is(err, AgentOfflineErr) || errors.Is(err, AgentUnboundErr) || errors.Is(err, AgentInactiveErr) || errors.Is(err, AgentNotManagedErr) {
+		return true
+	}
+
+	var e *Error
+	return notSent(err) || errors.As(err, &e) && e.StatusCode == http.StatusTooManyRequests
+}
+
+// calloutRejected 讯飞明确拒绝了外呼请求，计入拨打次数
+func calloutRejected(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode >= http.StatusBadRequest && e.StatusCode < http.StatusInternalServerError
+}
+
+// finishAttempt 需在持有锁时调用，agentCalling 表示坐席在 AgentManager 中仍被本次通话占用
+func (d *CampaignDialer) finishAttempt(number *CampaignNumber, agentCalling bool, answered bool, lastError string, now time.Time) {
+	d.release(number, agentCalling)
+	number.LastError = lastError
+
+	switch {
+	case answered:
+		number.Status = CampaignNumberStatusAnswered
+	case number.Attempts >= d.config.MaxAttempts:
+		number.Status = CampaignNumberStatusFailed
+	default:
+		number.Status = CampaignNumberStatusUnanswered
+		number.NextDialAt = now.Add(d.config.RetryCooldown)
+	}
+}
+
+// revert 需在持有锁时调用，坐席不可用时撤销本次外呼，不计入拨打次数
+func (d *CampaignDialer) revert(number *CampaignNumber) {
+	d.release(number, false)
+	number.Attempts--
+	number.Cno = ""
+	number.RequestUniqueId = ""
+	if number.Attempts == 0 {
+		number.Status = CampaignNumberStatusPending
+	} else {
+		number.Status = CampaignNumberStatusUnanswered
+	}
+}
+
+// release 需在持有锁时调用
+func (d *CampaignDialer) release(number *CampaignNumber, agentCalling bool) {
+	delete(d.byRequest, number.RequestUniqueId)
+	if d.inflight[number.Cno] > 0 {
+		d.inflight[number.Cno]--
+	}
+	if agentCalling {
+		d.agents.CallEnded(number.Cno)
+	}
+}
+
+// expireDialing 需在持有锁时调用
+func (d *CampaignDialer) expireDialing(now time.Time) bool {
+	changed := false
+	for _, number := range d.progress.Numbers {
+		if number.Status == CampaignNumberStatusDialing && now.Sub(number.DialedAt) > d.config.CallTimeout {
+			d.release(number, true)
+			number.Status = CampaignNumberStatusUnconfirmed
+			number.LastError = "hangup event not received"
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// finished 需在持有锁时调用
+func (d *CampaignDialer) finished() bool {
+	for _, number := range d.progress.Numbers {
+		switch number.Status {
+		case CampaignNumberStatusPending, CampaignNumberStatusDialing, CampaignNumberStatusUnanswered:
+			return false
+		}
+	}
+
+	return true
+}
+
+func (d *CampaignDialer) saveIf(changed bool) error {
+	if !changed {
+		return nil
+	}
+
+	return d.save()
+}
+
+func (d *CampaignDialer) save() error {
+	d.saveMu.Lock()
+	defer d.saveMu.Unlock()
+
+	d.mu.Lock()
+	d.progress.UpdatedAt = time.Now()
+	data, err := json.Marshal(d.progress)
+	d.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	progress := &CampaignProgress{}
+	if err := json.Unmarshal(data, progress); err != nil {
+		return err
+	}
+
+	return d.store.Save(progress)
+}
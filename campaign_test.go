@@ -0,0 +1,134 @@
+package iflytek_test
+
+import (
+	dgctx "github.com/darwinOrg/go-common/context"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCampaignDialer(t *testing.T) {
+	var callouts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cc/describe_client":
+			_, _ = w.Write([]byte(`{"client":{"cno":"1001","bindTel":"13800000000","active":1,"status":1}}`))
+		case "/cc/callout":
+			callouts = append(callouts, r.URL.Path)
+			_, _ = w.Write([]byte(`{"requestId":"1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := &dgctx.DgContext{TraceId: "123"}
+	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{Host: server.URL})
+	agents := dgkdxf.NewAgentManager(client, nil)
+	agents.SetOnline(&dgkdxf.OnlineReq{Cno: "1001", BindType: 1, BindTel: "13800000000"})
+	if _, err := agents.ReconcileAgent(ctx, "1001"); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &dgkdxf.FileCampaignStore{Dir: t.TempDir()}
+	config := &dgkdxf.CampaignConfig{
+		CampaignId:      "c1",
+		Cnos:            []string{"1001"},
+		CustomerNumbers: []string{"13900000001", "13900000002"},
+		MaxAttempts:     2,
+		RetryCooldown:   time.Hour,
+	}
+	dialer, err := dgkdxf.NewCampaignDialer(agents, store, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := dialer.Step(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(callouts) != 1 {
+		t.Fatalf("callouts with one idle agent: %d", len(callouts))
+	}
+
+	// 重启后沿用持久化的进度，不会重新拨打正在通话的号码
+	restarted, err := dgkdxf.NewCampaignDialer(agents, store, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := restarted.Progress().Numbers[0]
+	if first.Status != dgkdxf.CampaignNumberStatusDialing || first.RequestUniqueId != "c1-0-1" {
+		t.Fatalf("restored number: %+v", first)
+	}
+
+	err = restarted.HandleHangup(ctx, &dgkdxf.CallHangupEvent{CallEvent: dgkdxf.CallEvent{RequestUniqueId: first.RequestUniqueId}, Status: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := restarted.Progress().Numbers[0].Status; status != dgkdxf.CampaignNumberStatusUnanswered {
+		t.Fatalf("status after unanswered hangup: %s", status)
+	}
+
+	if _, err := restarted.Step(ctx); err != nil {
+		t.Fatal(err)
+	}
+	second := restarted.Progress().Numbers[1]
+	if len(callouts) != 2 || second.Status != dgkdxf.CampaignNumberStatusDialing {
+		t.Fatalf("callouts: %d, second: %+v", len(callouts), second)
+	}
+}
+
+func TestCampaignDialerAmbiguousCallout(t *testing.T) {
+	callouts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cc/describe_client":
+			_, _ = w.Write([]byte(`{"client":{"cno":"1001","bindTel":"13800000000","active":1,"status":1}}`))
+		case "/cc/callout":
+			callouts++
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := &dgctx.DgContext{TraceId: "123"}
+	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{Host: server.URL})
+	agents := dgkdxf.NewAgentManager(client, nil)
+	agents.SetOnline(&dgkdxf.OnlineReq{Cno: "1001", BindType: 1, BindTel: "13800000000"})
+	if _, err := agents.ReconcileAgent(ctx, "1001"); err != nil {
+		t.Fatal(err)
+	}
+
+	dialer, err := dgkdxf.NewCampaignDialer(agents, &dgkdxf.FileCampaignStore{Dir: t.TempDir()}, &dgkdxf.CampaignConfig{
+		CampaignId:      "c1",
+		Cnos:            []string{"1001"},
+		CustomerNumbers: []string{"13900000001", "13900000002"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 5xx 时外呼可能已经发出，保持 dialing 等待挂机事件，不重新拨打
+	for i := 0; i < 3; i++ {
+		_, _ = dialer.Step(ctx)
+	}
+	first := dialer.Progress().Numbers[0]
+	if callouts != 1 || first.Status != dgkdxf.CampaignNumberStatusDialing || first.RequestUniqueId != "c1-0-1" || first.LastError == "" {
+		t.Fatalf("callouts: %d, first: %+v", callouts, first)
+	}
+	if state := agents.State("1001"); state != dgkdxf.AgentStateBusy {
+		t.Fatalf("agent state: %s", state)
+	}
+
+	if err := dialer.HandleHangup(ctx, &dgkdxf.CallHangupEvent{CallEvent: dgkdxf.CallEvent{RequestUniqueId: first.RequestUniqueId}, Status: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if status := dialer.Progress().Numbers[0].Status; status != dgkdxf.CampaignNumberStatusAnswered || agents.State("1001") != dgkdxf.AgentStateOnline {
+		t.Fatalf("status after hangup: %s, agent: %s", status, agents.State("1001"))
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var asrGroup = &group{
+	service: dgkdxf.ServiceAsr,
+	commands: map[string]*command{
+		"upload": {usage: "[-name name] [-callback url] <file>", run: asrUpload},
+		"status": {usage: "<orderId>", run: asrStatus},
+		"wait":   {usage: "[-poll 10s] [-timeout 2h] <orderId>", run: asrWait},
+		"export": {usage: "[-format srt|vtt|txt] [-o file] [-wait] <orderId>", run: asrExport},
+	},
+}
+
+// asrUpload 时长和文件大小通过 ProbeAudio 获取，不需要手动指定
+func asrUpload(c *cli, args []string) (any, error) {
+	fs := c.flags()
+	name := fs.String("name", "", "上传使用的文件名，默认为文件路径的文件名")
+	callbackUrl := fs.String("callback", "", "转写完成的回调地址")
+	args, err := c.args(fs, args, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	ret, err := client.AsrUploadFile(c.ctx, args[0], &dgkdxf.AsrUploadOptions{FileName: *name, CallbackUrl: *callbackUrl})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret.Content, nil
+}
+
+type asrStatusOutput struct {
+	OrderId          string `json:"orderId"`
+	Status           int    `json:"status"`
+	Finished         bool   `json:"finished"`
+	OriginalDuration int    `json:"originalDuration"`
+	RealDuration     int    `json:"realDuration"`
+	ExpireTime       int    `json:"expireTime"`
+}
+
+func asrStatus(c *cli, args []string) (any, error) {
+	args, err := c.args(c.flags(), args, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	ret, err := client.GetAsrResult(c.ctx, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	orderInfo := ret.Content.OrderInfo
+	return &asrStatusOutput{
+		OrderId:          args[0],
+		Status:           orderInfo.Status,
+		Finished:         ret.Finished(),
+		OriginalDuration: orderInfo.OriginalDuration,
+		RealDuration:     orderInfo.RealDuration,
+		ExpireTime:       orderInfo.ExpireTime,
+	}, nil
+}
+
+type asrResultOutput struct {
+	OrderId   string              `json:"orderId"`
+	Subtitles []*dgkdxf.Subtitles `json:"subtitles"`
+}
+
+func asrWait(c *cli, args []string) (any, error) {
+	fs := c.flags()
+	pollInterval := fs.Duration("poll", 10*time.Second, "查询间隔")
+	timeout := fs.Duration("timeout", 2*time.Hour, "最长等待时间")
+	args, err := c.args(fs, args, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	result, err := client.WaitAsrResult(c.ctx, args[0], *pollInterval, *timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &asrResultOutput{OrderId: args[0], Subtitles: result.Convert2Subtitles()}, nil
+}
+
+type asrExportOutput struct {
+	OrderId   string                 `json:"orderId"`
+	Format    dgkdxf.SubtitlesFormat `json:"format"`
+	File      string                 `json:"file"`
+	Subtitles int                    `json:"subtitles"`
+}
+
+// asrExport 未指定 -o 时直接把字幕写到标准输出
+func asrExport(c *cli, args []string) (any, error) {
+	fs := c.flags()
+	format := fs.String("format", string(dgkdxf.SubtitlesFormatSrt), "字幕格式：srt、vtt、txt")
+	output := fs.String("o", "", "输出文件，默认写到标准输出")
+	wait := fs.Bool("wait", false, "订单未完成时等待完成")
+	timeout := fs.Duration("timeout", 2*time.Hour, "-wait 的最长等待时间")
+	args, err := c.args(fs, args, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+	switch dgkdxf.SubtitlesFormat(*format) {
+	case dgkdxf.SubtitlesFormatSrt, dgkdxf.SubtitlesFormatVtt, dgkdxf.SubtitlesFormatTxt:
+	default:
+		return nil, fmt.Errorf("%w: %s", dgkdxf.UnsupportedSubtitlesFormatErr, *format)
+	}
+	orderId := args[0]
+
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	var result *dgkdxf.OrderResult
+	if *wait {
+		result, err = client.WaitAsrResult(c.ctx, orderId, 0, *timeout)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		ret, err := client.GetAsrResult(c.ctx, orderId)
+		if err != nil {
+			return nil, err
+		}
+		if !ret.Finished() {
+			return nil, fmt.Errorf("order %s is not finished, status: %d", orderId, ret.Content.OrderInfo.Status)
+		}
+		result = ret.Content.OrderResult
+	}
+	if result == nil {
+		result = &dgkdxf.OrderResult{}
+	}
+	subtitles := result.Convert2Subtitles()
+
+	if *output == "" {
+		return nil, dgkdxf.WriteSubtitles(c.stdout, subtitles, dgkdxf.SubtitlesFormat(*format))
+	}
+
+	if err := writeSubtitlesFile(*output, subtitles, dgkdxf.SubtitlesFormat(*format)); err != nil {
+		return nil, err
+	}
+
+	return &asrExportOutput{OrderId: orderId, Format: dgkdxf.SubtitlesFormat(*format), File: *output, Subtitles: len(subtitles)}, nil
+}
+
+// writeSubtitlesFile 先写入同目录的临时文件再重命名，失败时不会留下不完整的输出文件
+func writeSubtitlesFile(path string, subtitles []*dgkdxf.Subtitles, format dgkdxf.SubtitlesFormat) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	// CreateTemp 创建的文件只有属主可读，与 os.Create 保持一致
+	if err := file.Chmod(0644); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := dgkdxf.WriteSubtitles(file, subtitles, format); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
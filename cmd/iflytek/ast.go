@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"github.com/gorilla/websocket"
+	"os"
+	"strconv"
+	"time"
+)
+
+const astChunkMillis = 40
+
+var astGroup = &group{
+	service: dgkdxf.ServiceAst,
+	commands: map[string]*command{
+		"stream": {usage: "[-lang cn] [-samplerate 16000] [-rate 16000] [-channels 1] [-channel 0] [-feature-ids ids] [-partial] [-fast] <file>", run: astStream},
+	},
+}
+
+type astEvent struct {
+	Event     string        `json:"event"` // started、partial、result、error、end
+	SegId     int64         `json:"segId,omitempty"`
+	Begin     string        `json:"begin,omitempty"` // 毫秒
+	End       string        `json:"end,omitempty"`
+	Text      string        `json:"text,omitempty"`
+	Speaker   string        `json:"speaker,omitempty"`
+	Meta      any           `json:"meta,omitempty"` // started 消息的原始内容
+	Error     *dgkdxf.Error `json:"error,omitempty"`
+	Results   int           `json:"results,omitempty"`
+	SentBytes int           `json:"sentBytes,omitempty"`
+}
+
+// astStream 按实时速度发送 wav 或 pcm_s16le 文件，每收到一条转写结果输出一行 json
+func astStream(c *cli, args []string) (any, error) {
+	fs := c.flags()
+	lang := fs.String("lang", "cn", "语种")
+	samplerate := fs.Int("samplerate", 16000, "发送给讯飞的采样率")
+	rate := fs.Int("rate", 16000, "pcm 文件的采样率，wav 文件取文件头")
+	channels := fs.Int("channels", 1, "pcm 文件的声道数，wav 文件取文件头")
+	channel := fs.Int("channel", int(dgkdxf.AstChannelDownmix), "多声道时使用的声道，0 为混音，1 左声道，2 右声道")
+	featureIds := fs.String("feature-ids", "", "用于区分说话人的声纹，多个以逗号分隔")
+	partial := fs.Bool("partial", false, "同时输出中间结果")
+	fast := fs.Bool("fast", false, "不按实时速度发送，可能触发讯飞的上传速度限制")
+	waitTimeout := fs.Duration("wait", 30*time.Second, "发送完成后等待结果的最长时间")
+	args, err := c.args(fs, args, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return nil, err
+	}
+	source := &dgkdxf.AstAudioSource{SampleRate: *rate, Channels: *channels, Channel: dgkdxf.AstChannel(*channel)}
+	pcm := data
+	if dgkdxf.IsWav(data) {
+		var header *dgkdxf.WavHeader
+		header, pcm, err = dgkdxf.ParseWav(data)
+		if err != nil {
+			return nil, err
+		}
+		if source, err = dgkdxf.NewAstAudioSourceFromWav(header, dgkdxf.AstChannel(*channel)); err != nil {
+			return nil, err
+		}
+	}
+
+	// 采样率或声道数为 0、负数时分片大小不为正，发送循环无法推进
+	chunkSize := source.SampleRate * source.Channels * 2 * astChunkMillis / 1000
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("invalid audio format: %d Hz, %d channels", source.SampleRate, source.Channels)
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	config := &dgkdxf.AstParamConfig{
+		Lang:        *lang,
+		Codec:       dgkdxf.AstAudioEncodePcm,
+		AudioEncode: dgkdxf.AstAudioEncodePcm,
+		Samplerate:  strconv.Itoa(*samplerate),
+		FeatureIds:  *featureIds,
+		AudioSource: source,
+	}
+	if *featureIds != "" {
+		config.RoleType = dgkdxf.RoleTypeOpen
+	}
+	stream, err := client.AstOpen(c.ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	results := make(chan int, 1)
+	go func() { results <- c.readAstResults(stream.Conn, *partial) }()
+
+	start := time.Now()
+	for offset := 0; offset < len(pcm); offset += chunkSize {
+		if _, err := stream.Write(pcm[offset:min(offset+chunkSize, len(pcm))]); err != nil {
+			return nil, err
+		}
+		if !*fast {
+			// 按已发送的音频时长对齐，避免 sleep 误差累积
+			sent := time.Duration(offset/chunkSize+1) * astChunkMillis * time.Millisecond
+			time.Sleep(time.Until(start.Add(sent)))
+		}
+	}
+	if err := stream.End(c.ctx); err != nil {
+		return nil, err
+	}
+
+	_ = stream.Conn.SetReadDeadline(time.Now().Add(*waitTimeout))
+	return &astEvent{Event: "end", Results: <-results, SentBytes: len(pcm)}, nil
+}
+
+// readAstResults 读到结束消息、连接关闭或超时为止，返回最终结果数
+func (c *cli) readAstResults(cn *websocket.Conn, partial bool) int {
+	count := 0
+	for {
+		mt, data, err := dgkdxf.AstReceiveMessage(cn)
+		if err != nil || dgkdxf.IsAstEndMessage(c.ctx, mt, data) {
+			var closeErr *websocket.CloseError
+			if err != nil && !errors.As(err, &closeErr) {
+				c.emitAst(&astEvent{Event: "error", Error: &dgkdxf.Error{Service: dgkdxf.ServiceAst, Op: "read", Message: err.Error()}})
+			}
+			return count
+		}
+		if mt != websocket.TextMessage {
+			continue
+		}
+
+		if astErr := dgkdxf.AstMessageError(data); astErr != nil {
+			c.emitAst(&astEvent{Event: "error", Error: astErr})
+			continue
+		}
+
+		var meta map[string]any
+		if json.Unmarshal(data, &meta) == nil && meta["action"] == "started" {
+			c.emitAst(&astEvent{Event: "started", Meta: meta})
+			continue
+		}
+
+		var result dgkdxf.AstResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			continue
+		}
+		event := &astEvent{Event: "partial", SegId: result.SegID, Begin: result.Cn.St.Bg, End: result.Cn.St.Ed}
+		if result.HasFinalWords() {
+			event.Event = "result"
+			count++
+		} else if !partial {
+			continue
+		}
+		for _, rt := range result.Cn.St.Rt {
+			for _, ws := range rt.Ws {
+				for _, cw := range ws.Cw {
+					event.Text += cw.W
+					if cw.Rl != "" && cw.Rl != "0" {
+						event.Speaker = cw.Rl
+					}
+				}
+			}
+		}
+		c.emitAst(event)
+	}
+}
+
+func (c *cli) emitAst(event *astEvent) {
+	if err := c.emit(event); err != nil {
+		fmt.Fprintln(c.stderr, err)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"os"
+	"path/filepath"
+)
+
+var ccGroup = &group{
+	service: dgkdxf.ServiceCc,
+	commands: map[string]*command{
+		"callout":  {usage: "-cno cno [-request-id id] <customerNumber>", run: ccCallout},
+		"hangup":   {usage: "<cno>", run: ccHangup},
+		"online":   {usage: "[-bind-type 1] <cno> <bindTel>", run: ccOnline},
+		"offline":  {usage: "[-unbind] <cno>", run: ccOffline},
+		"cdr":      {usage: "[-cno cno] [-customer number] [-status 0] [-hidden 0]", run: ccCdr},
+		"download": {usage: "[-side 0] [-type record] [-o file] <mainUniqueId>", run: ccDownload},
+	},
+}
+
+func ccCallout(c *cli, args []string) (any, error) {
+	fs := c.flags()
+	cno := fs.String("cno", "", "座席号")
+	requestId := fs.String("request-id", "", "请求唯一标识，用于关联通话事件")
+	args, err := c.args(fs, args, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+	if *cno == "" {
+		fs.Usage()
+		return nil, usageErr
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Callout(c.ctx, &dgkdxf.CalloutReq{Cno: *cno, CustomerNumber: args[0], RequestUniqueId: *requestId})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func ccHangup(c *cli, args []string) (any, error) {
+	args, err := c.args(c.flags(), args, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Unlink(c.ctx, &dgkdxf.CnoReq{Cno: args[0]})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func ccOnline(c *cli, args []string) (any, error) {
+	fs := c.flags()
+	bindType := fs.Int("bind-type", 1, "电话类型，1: 电话，2: IP话机")
+	args, err := c.args(fs, args, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Online(c.ctx, &dgkdxf.OnlineReq{Cno: args[0], BindTel: args[1], BindType: int32(*bindType)})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func ccOffline(c *cli, args []string) (any, error) {
+	fs := c.flags()
+	unbind := fs.Bool("unbind", false, "下线同时解绑电话")
+	args, err := c.args(fs, args, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &dgkdxf.OfflineReq{Cno: args[0]}
+	if *unbind {
+		req.UnbindTel = 1
+	}
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Offline(c.ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func ccCdr(c *cli, args []string) (any, error) {
+	fs := c.flags()
+	req := &dgkdxf.ListCdrObsReq{}
+	fs.StringVar(&req.Cno, "cno", "", "座席号")
+	fs.StringVar(&req.CustomerNumber, "customer", "", "客户号码")
+	status := fs.Int("status", 0, "接听状态，0: 全部，1: 客户未接听，2: 座席未接听，3: 双方接听")
+	hidden := fs.Int("hidden", 0, "号码隐藏方式，0: 不隐藏，1: 中间四位，2: 最后八位，3: 全部号码，4: 最后四位")
+	if _, err := c.args(fs, args, 0, 0); err != nil {
+		return nil, err
+	}
+	req.Status, req.HiddenType = int32(*status), int32(*hidden)
+
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.ListCdrObs(c.ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type ccDownloadOutput struct {
+	MainUniqueId string `json:"mainUniqueId"`
+	File         string `json:"file"`
+	Bytes        int64  `json:"bytes"`
+}
+
+// ccDownload 未指定 -o 时保存到当前目录，文件名取响应中的文件名
+func ccDownload(c *cli, args []string) (any, error) {
+	fs := c.flags()
+	side := fs.Int("side", 0, "不指定时下载 mp3，指定时下载 wav，1: 客户侧，2: 座席侧，3: 两侧合成")
+	recordType := fs.String("type", "", "record: 通话录音，voicemail: 留言，默认 record")
+	output := fs.String("o", "", "输出文件")
+	args, err := c.args(fs, args, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+	mainUniqueId := args[0]
+
+	dir := "."
+	if *output != "" {
+		dir = filepath.Dir(*output)
+	}
+	file, err := os.CreateTemp(dir, ".iflytek-download-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	fileName, err := client.DownloadRecordFileTo(c.ctx, &dgkdxf.DownloadRecordFileReq{MainUniqueId: mainUniqueId, RecordSide: int32(*side), RecordType: *recordType}, file)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	target := *output
+	if target == "" {
+		target = filepath.Base(fileName)
+		if fileName == "" {
+			target = mainUniqueId
+		}
+	}
+	if err := os.Rename(file.Name(), target); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ccDownloadOutput{MainUniqueId: mainUniqueId, File: target, Bytes: info.Size()}, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/base64"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"os"
+	"time"
+)
+
+var featureGroup = &group{
+	service: dgkdxf.ServiceFeature,
+	commands: map[string]*command{
+		"register": {usage: "[-uid uid] [-type raw|speex|opus-ogg|opus-wb] [-min-speech 3s] <file>", run: featureRegister},
+		"update":   {usage: "[-type raw|speex|opus-ogg|opus-wb] [-min-speech 3s] <featureId> <file>", run: featureUpdate},
+		"delete":   {usage: "<featureId>...", run: featureDelete},
+	},
+}
+
+type featureOutput struct {
+	FeatureId string `json:"featureId"`
+}
+
+func featureAudioFlags(c *cli, args []string, n int) (*dgkdxf.RegisterFeatureOptions, []string, error) {
+	fs := c.flags()
+	opts := &dgkdxf.RegisterFeatureOptions{}
+	uid := ""
+	if n == 1 {
+		fs.StringVar(&uid, "uid", "", "注册时关联的用户标识")
+	}
+	audioType := fs.String("type", "", "音频类型，默认按内容识别，speex 和 opus-wb 需显式指定")
+	minSpeech := fs.Duration("min-speech", 3*time.Second, "有效语音的最短时长")
+	args, err := c.args(fs, args, n, n)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts.Uid, opts.AudioType, opts.MinSpeechDuration = uid, dgkdxf.AudioType(*audioType), *minSpeech
+
+	return opts, args, nil
+}
+
+func featureRegister(c *cli, args []string) (any, error) {
+	opts, args, err := featureAudioFlags(c, args, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	featureId, err := client.RegisterFeatureFromFile(c.ctx, args[0], opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &featureOutput{FeatureId: featureId}, nil
+}
+
+func featureUpdate(c *cli, args []string) (any, error) {
+	opts, args, err := featureAudioFlags(c, args, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(args[1])
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	audio, err := dgkdxf.ReadFeatureAudio(file, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	err = client.UpdateFeature(c.ctx, &dgkdxf.UpdateFeatureRequest{
+		FeatureId: args[0],
+		AudioData: base64.StdEncoding.EncodeToString(audio.Data),
+		AudioType: audio.AudioType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &featureOutput{FeatureId: args[0]}, nil
+}
+
+// featureDelete 部分失败时同时输出删除结果和错误
+func featureDelete(c *cli, args []string) (any, error) {
+	args, err := c.args(c.flags(), args, 1, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.DeleteFeatures(c.ctx, args)
+}
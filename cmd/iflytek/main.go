@@ -0,0 +1,213 @@
+// Command iflytek 讯飞录音转写、实时转写、声纹和呼叫中心的命令行工具。
+//
+// 配置依次合并 -config 指定的 json/yaml 文件和 IFLYTEK_ 前缀的环境变量，如 IFLYTEK_ACCESS_KEY_ID。
+// 结果以 json 输出到标准输出，失败时向标准错误输出 {"error": ..., "detail": ...} 并以状态 1 退出，参数错误时以状态 2 退出。
+//
+//	iflytek [-config file]... [-env-prefix IFLYTEK_] [-pretty] <asr|ast|feature|cc> <command> [flags] [args]
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"github.com/google/uuid"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	exitError = 1
+	exitUsage = 2
+)
+
+type group struct {
+	service  dgkdxf.Service
+	commands map[string]*command
+}
+
+type command struct {
+	usage string // 子命令的参数，如 "[-callback url] <file>"
+	run   func(c *cli, args []string) (any, error)
+}
+
+var groups = map[string]*group{
+	"asr":     asrGroup,
+	"ast":     astGroup,
+	"feature": featureGroup,
+	"cc":      ccGroup,
+}
+
+// usageErr 参数错误，已向标准错误输出用法
+var usageErr = errors.New("usage")
+
+type cli struct {
+	ctx     *dgctx.DgContext
+	name    string // 如 "asr upload"
+	usage   string
+	stdout  io.Writer
+	stderr  io.Writer
+	pretty  bool
+	service dgkdxf.Service
+	opts    *dgkdxf.LoadConfigOptions
+	cl      *dgkdxf.Client
+}
+
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	var configFiles stringsFlag
+	fs := flag.NewFlagSet("iflytek", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Var(&configFiles, "config", "json 或 yaml 配置文件，可重复指定，后面的覆盖前面的")
+	envPrefix := fs.String("env-prefix", dgkdxf.DefaultConfigEnvPrefix, "环境变量前缀")
+	pretty := fs.Bool("pretty", false, "缩进输出 json")
+	fs.Usage = func() { printUsage(fs) }
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	rest := fs.Args()
+	if len(rest) < 2 {
+		fs.Usage()
+		return exitUsage
+	}
+	g, ok := groups[rest[0]]
+	if !ok {
+		fs.Usage()
+		return exitUsage
+	}
+	cmd, ok := g.commands[rest[1]]
+	if !ok {
+		fs.Usage()
+		return exitUsage
+	}
+
+	c := &cli{
+		ctx:     &dgctx.DgContext{TraceId: uuid.NewString()},
+		name:    rest[0] + " " + rest[1],
+		usage:   cmd.usage,
+		stdout:  stdout,
+		stderr:  stderr,
+		pretty:  *pretty,
+		service: g.service,
+		opts:    &dgkdxf.LoadConfigOptions{EnvPrefix: *envPrefix, Files: configFiles, Services: []dgkdxf.Service{g.service}},
+	}
+	result, err := cmd.run(c, rest[2:])
+	if result != nil {
+		if emitErr := c.emit(result); emitErr != nil && err == nil {
+			err = emitErr
+		}
+	}
+	if err != nil {
+		if errors.Is(err, usageErr) {
+			return exitUsage
+		}
+		c.printError(err)
+		return exitError
+	}
+
+	return 0
+}
+
+func printUsage(fs *flag.FlagSet) {
+	out := fs.Output()
+	fmt.Fprintln(out, "usage: iflytek [-config file]... [-env-prefix IFLYTEK_] [-pretty] <group> <command> [flags] [args]")
+	fs.PrintDefaults()
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		commands := make([]string, 0, len(groups[name].commands))
+		for commandName := range groups[name].commands {
+			commands = append(commands, commandName)
+		}
+		sort.Strings(commands)
+		fmt.Fprintln(out)
+		for _, commandName := range commands {
+			fmt.Fprintf(out, "  iflytek %s %s %s\n", name, commandName, groups[name].commands[commandName].usage)
+		}
+	}
+}
+
+// flags 子命令的参数，解析后用 args 检查位置参数个数
+func (c *cli) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet("iflytek "+c.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: iflytek %s %s\n", c.name, c.usage)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// args 解析参数，位置参数少于 min 或多于 max 时返回 usageErr，max 小于0时不限制
+func (c *cli) args(fs *flag.FlagSet, args []string, min int, max int) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, usageErr
+	}
+	if fs.NArg() < min || max >= 0 && fs.NArg() > max {
+		fs.Usage()
+		return nil, usageErr
+	}
+
+	return fs.Args(), nil
+}
+
+// client 解析完子命令参数后再加载配置，使 -h 不依赖配置
+func (c *cli) client() (*dgkdxf.Client, error) {
+	if c.cl == nil {
+		cfg, err := dgkdxf.LoadClientConfig(c.opts)
+		if err != nil {
+			return nil, err
+		}
+		c.cl = dgkdxf.NewClient(cfg)
+	}
+
+	return c.cl, nil
+}
+
+// emit 向标准输出写一行 json
+func (c *cli) emit(v any) error {
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetEscapeHTML(false)
+	if c.pretty {
+		encoder.SetIndent("", "  ")
+	}
+
+	return encoder.Encode(v)
+}
+
+type errorOutput struct {
+	Error  string        `json:"error"`
+	Detail *dgkdxf.Error `json:"detail,omitempty"`
+}
+
+func (c *cli) printError(err error) {
+	output := &errorOutput{Error: err.Error()}
+	errors.As(err, &output.Detail)
+
+	data, _ := json.Marshal(output)
+	fmt.Fprintln(c.stderr, string(data))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/getResult":
+			orderResult := `{"lattice":[{"json_1best":"{\"st\":{\"bg\":\"500\",\"ed\":\"2000\",\"rt\":[{\"ws\":[{\"wb\":0,\"we\":100,\"cw\":[{\"w\":\"你好\"}]},{\"wb\":100,\"we\":150,\"cw\":[{\"w\":\"。\"}]}]}]}}"}]}`
+			data, _ := json.Marshal(orderResult)
+			_, _ = w.Write([]byte(`{"code":"000000","content":{"orderInfo":{"orderId":"o1","status":4,"realDuration":1500},"orderResult":` + string(data) + `}}`))
+		case "/cc/unlink":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"requestId":"r1","error":{"code":"InvalidParameter","message":"cno"}}`))
+		}
+	}))
+	defer server.Close()
+	t.Setenv("IFLYTEK_HOST", server.URL)
+	t.Setenv("IFLYTEK_ACCESS_KEY_ID", "ak")
+	t.Setenv("IFLYTEK_ACCESS_KEY_SECRET", "sk")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"asr", "status", "o1"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("status exit %d: %s", code, stderr.String())
+	}
+	var status asrStatusOutput
+	if err := json.Unmarshal(stdout.Bytes(), &status); err != nil || !status.Finished || status.RealDuration != 1500 {
+		t.Fatalf("status: %s, %v", stdout.String(), err)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"asr", "export", "-format", "vtt", "o1"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("export exit %d: %s", code, stderr.String())
+	}
+	if stdout.String() != "WEBVTT\n\n00:00:00.500 --> 00:00:02.000\n你好\n\n" {
+		t.Fatalf("export: %q", stdout.String())
+	}
+
+	// 格式错误时不会创建输出文件
+	output := filepath.Join(t.TempDir(), "o1.srt")
+	if code := run([]string{"asr", "export", "-format", "ass", "-o", output, "o1"}, &stdout, &stderr); code != exitError {
+		t.Fatalf("export invalid format exit %d", code)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Fatalf("output created for invalid format: %v", err)
+	}
+	if code := run([]string{"asr", "export", "-o", output, "o1"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("export file exit %d: %s", code, stderr.String())
+	}
+	if data, err := os.ReadFile(output); err != nil || !strings.HasPrefix(string(data), "1\n00:00:00,500 --> 00:00:02,000\n") {
+		t.Fatalf("export file: %q, %v", data, err)
+	}
+
+	pcm := filepath.Join(t.TempDir(), "a.pcm")
+	_ = os.WriteFile(pcm, make([]byte, 3200), 0644)
+	if code := run([]string{"ast", "stream", "-rate", "0", pcm}, &stdout, &stderr); code != exitError || !strings.Contains(stderr.String(), "invalid audio format") {
+		t.Fatalf("ast zero rate exit %d: %s", code, stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := run([]string{"cc", "hangup", "1001"}, &stdout, &stderr); code != exitError {
+		t.Fatalf("hangup exit %d", code)
+	}
+	var hangup struct {
+		Error  string `json:"error"`
+		Detail struct {
+			Service   string `json:"service"`
+			Code      string `json:"code"`
+			RequestId string `json:"requestId"`
+		} `json:"detail"`
+	}
+	if err := json.Unmarshal(stderr.Bytes(), &hangup); err != nil || hangup.Detail.Service != "cc" || hangup.Detail.Code != "InvalidParameter" || hangup.Detail.RequestId != "r1" {
+		t.Fatalf("hangup: %s, %v", stderr.String(), err)
+	}
+
+	stderr.Reset()
+	if code := run([]string{"asr", "status"}, &stdout, &stderr); code != exitUsage || !strings.Contains(stderr.String(), "usage: iflytek asr status") {
+		t.Fatalf("usage: %s", stderr.String())
+	}
+}
@@ -2,9 +2,13 @@ package iflytek
 
 import (
 	"errors"
+	"fmt"
 	dgcoll "github.com/darwinOrg/go-common/collection"
 	"github.com/darwinOrg/go-common/model"
-	"github.com/darwinOrg/go-common/utils"
+	"github.com/google/uuid"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -23,11 +27,6 @@ const (
 	defaultBufferSize = 1024 * 16
 )
 
-var (
-	ApiNoSuccessErr         = errors.New("api resp no success")
-	ApiGetResultFailTypeErr = errors.New("api get result fail")
-)
-
 type KdxfResponse struct {
 	Error struct {
 		Code    string `json:"code"`
@@ -36,42 +35,171 @@ type KdxfResponse struct {
 	RequestID string `json:"requestId"`
 }
 
+type Service string
+
+const (
+	ServiceAsr     Service = "asr"
+	ServiceAst     Service = "ast"
+	ServiceFeature Service = "feature"
+	ServiceCc      Service = "cc"
+
+	DefaultAsrHost     = "https://api.iflyrec.com"
+	DefaultAstHost     = "wss://api.iflyrec.com"
+	DefaultFeatureHost = "https://office-api-personal-dx.iflyaisol.com"
+	DefaultCcHost      = "https://api.iflyrec.com"
+)
+
+var InvalidHostErr = errors.New("invalid host")
+
 type ClientConfig struct {
-	AppId           string `json:"appId"`
-	Host            string `json:"host"`
-	AccessKeyId     string `json:"accessKeyId"`
-	AccessKeySecret string `json:"accessKeySecret"`
+	AppId           string     `json:"appId" yaml:"appId"`
+	Host            string     `json:"host" yaml:"host"`               // 未单独配置的服务统一使用该地址，ast 会将 http(s) 转换为 ws(s)
+	AsrHost         string     `json:"asrHost" yaml:"asrHost"`         // 录音文件转写，默认 DefaultAsrHost
+	AstHost         string     `json:"astHost" yaml:"astHost"`         // 实时转写，默认 DefaultAstHost
+	FeatureHost     string     `json:"featureHost" yaml:"featureHost"` // 声纹，默认 DefaultFeatureHost
+	CcHost          string     `json:"ccHost" yaml:"ccHost"`           // 呼叫中心，默认 DefaultCcHost
+	AccessKeyId     string     `json:"accessKeyId" yaml:"accessKeyId"`
+	AccessKeySecret string     `json:"accessKeySecret" yaml:"accessKeySecret"`
+	LogHiddenType   HiddenType `json:"logHiddenType" yaml:"logHiddenType"` // 日志中号码的隐藏方式，取值同 ListCdrObsReq.HiddenType，默认中间四位，LogHiddenTypeNone 为不隐藏；签名参数始终会被去除
+
+	Clock    func() time.Time `json:"-" yaml:"-"` // 签名使用的当前时间，默认 time.Now
+	RandomId func() string    `json:"-" yaml:"-"` // 签名使用的随机串，默认 uuid.NewString
+
+	// CredentialsProvider 配置后每次签名从中获取密钥并按 TTL 缓存，忽略 AccessKeyId 和 AccessKeySecret
+	CredentialsProvider CredentialsProvider `json:"-" yaml:"-"`
+
+	RateLimits  map[Api]RateLimit `json:"rateLimits" yaml:"rateLimits"` // 按接口限流，key 为 Api，也可以配置 asr、feature、cc 对整个服务生效
+	RateLimiter *RateLimiter      `json:"-" yaml:"-"`                   // 同一账号的多个 Client 共用时设置，优先于 RateLimits
+
+	Instrumentation Instrumentation `json:"-" yaml:"-"` // 调用观测钩子，默认不做任何处理
+}
+
+// ServiceHost 服务实际使用的地址，优先级为服务单独配置、Host、默认值
+func (cfg *ClientConfig) ServiceHost(service Service) string {
+	var host, defaultHost string
+	switch service {
+	case ServiceAsr:
+		host, defaultHost = cfg.AsrHost, DefaultAsrHost
+	case ServiceAst:
+		host, defaultHost = cfg.AstHost, DefaultAstHost
+	case ServiceFeature:
+		host, defaultHost = cfg.FeatureHost, DefaultFeatureHost
+	case ServiceCc:
+		host, defaultHost = cfg.CcHost, DefaultCcHost
+	}
+	if host == "" {
+		host = cfg.Host
+		if service == ServiceAst {
+			host = toWebsocketScheme(host)
+		}
+	}
+	if host == "" {
+		host = defaultHost
+	}
+
+	return strings.TrimSuffix(host, "/")
+}
+
+// Validate 校验各服务地址的 scheme，ast 只允许 ws 和 wss，其他服务只允许 http 和 https
+func (cfg *ClientConfig) Validate() error {
+	for _, service := range []Service{ServiceAsr, ServiceAst, ServiceFeature, ServiceCc} {
+		host := cfg.ServiceHost(service)
+		u, err := url.Parse(host)
+		if err != nil {
+			return fmt.Errorf("%w: %s host %q: %v", InvalidHostErr, service, host, err)
+		}
+		if u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+			return fmt.Errorf("%w: %s host %q", InvalidHostErr, service, host)
+		}
+
+		schemes := []string{"http", "https"}
+		if service == ServiceAst {
+			schemes = []string{"ws", "wss"}
+		}
+		if !dgcoll.Contains(schemes, u.Scheme) {
+			return fmt.Errorf("%w: %s host %q scheme must be one of %v", InvalidHostErr, service, host, schemes)
+		}
+	}
+
+	return nil
+}
+
+func toWebsocketScheme(host string) string {
+	switch {
+	case strings.HasPrefix(host, "https://"):
+		return "wss://" + strings.TrimPrefix(host, "https://")
+	case strings.HasPrefix(host, "http://"):
+		return "ws://" + strings.TrimPrefix(host, "http://")
+	}
+
+	return host
+}
+
+// signHost 签名使用的地址，去除 scheme，保留 host、端口和路径前缀
+func signHost(host string) string {
+	u, err := url.Parse(host)
+	if err != nil || u.Host == "" {
+		if _, after, found := strings.Cut(host, "://"); found {
+			return after
+		}
+		return host
+	}
+
+	return u.Host + strings.TrimSuffix(u.EscapedPath(), "/")
 }
 
 type Client struct {
 	Config *ClientConfig
+
+	credsOnce   sync.Once
+	creds       *CachingCredentialsProvider
+	limiterOnce sync.Once
+	limiter     *RateLimiter
+	usage       usageCounter
 }
 
 func NewClient(config *ClientConfig) *Client {
 	return &Client{Config: config}
 }
 
+// GenerateSignature 使用当前密钥签名，获取密钥失败时返回空串
+//
+// Deprecated: 使用 SignParams，获取密钥失败时返回错误
 func (c *Client) GenerateSignature(params []*model.KeyValuePair[string, any]) string {
-	baseString := utils.FormUrlEncodedParams(params)
-
-	return utils.Sha1Base64Encode(c.Config.AccessKeySecret, baseString)
+	signature, _ := c.SignParams(params)
+	return signature
 }
 
+// Deprecated: 使用 SignParamsWithUrlPrefix，获取密钥失败时返回错误
 func (c *Client) GenerateSignatureWithUrlPrefix(urlPrefix string, params []*model.KeyValuePair[string, any]) string {
-	baseString := utils.FormUrlEncodedParams(params)
+	signature, _ := c.SignParamsWithUrlPrefix(urlPrefix, params)
+	return signature
+}
+
+// SignParams 使用当前密钥签名。密钥轮换后在 CachingCredentialsOptions.ActivationDelay 内仍使用旧密钥，
+// CachingCredentialsOptions.Overlap 只影响入站签名校验时旧密钥的有效期
+func (c *Client) SignParams(params []*model.KeyValuePair[string, any]) (string, error) {
+	return c.SignParamsWithUrlPrefix("", params)
+}
 
-	return utils.Sha1Base64Encode(c.Config.AccessKeySecret, urlPrefix+baseString)
+func (c *Client) SignParamsWithUrlPrefix(urlPrefix string, params []*model.KeyValuePair[string, any]) (string, error) {
+	creds, err := c.credentials()
+	if err != nil {
+		return "", err
+	}
+
+	return creds.sign(urlPrefix + asrStringToSign(params)), nil
 }
 
-func (c *Client) buildCommonParams() []*model.KeyValuePair[string, any] {
+func (c *Client) buildCommonParams(creds *Credentials) []*model.KeyValuePair[string, any] {
 	params := []*model.KeyValuePair[string, any]{
 		{
 			Key:   "Timestamp",
-			Value: getTimestampString(),
+			Value: c.getTimestampString(),
 		},
 		{
 			Key:   "AccessKeyId",
-			Value: c.Config.AccessKeyId,
+			Value: creds.AccessKeyId,
 		},
 		{
 			Key:   "Expires",
@@ -83,12 +211,28 @@ func (c *Client) buildCommonParams() []*model.KeyValuePair[string, any] {
 	return params
 }
 
-func getDateTimeString() string {
-	return time.Now().Format(dateTimeFormat)
+func (c *Client) now() time.Time {
+	if c.Config.Clock != nil {
+		return c.Config.Clock()
+	}
+
+	return time.Now()
+}
+
+func (c *Client) randomId() string {
+	if c.Config.RandomId != nil {
+		return c.Config.RandomId()
+	}
+
+	return uuid.NewString()
+}
+
+func (c *Client) getDateTimeString() string {
+	return c.now().Format(dateTimeFormat)
 }
 
-func getTimestampString() string {
-	return time.Now().Format(timestampFormat)
+func (c *Client) getTimestampString() string {
+	return c.now().Format(timestampFormat)
 }
 
 func sortParams(params []*model.KeyValuePair[string, any]) {
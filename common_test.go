@@ -0,0 +1,62 @@
+package iflytek_test
+
+import (
+	"errors"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServiceHost(t *testing.T) {
+	config := &dgkdxf.ClientConfig{}
+	if host := config.ServiceHost(dgkdxf.ServiceFeature); host != dgkdxf.DefaultFeatureHost {
+		t.Fatalf("default feature host: %s", host)
+	}
+	if host := config.ServiceHost(dgkdxf.ServiceAst); host != dgkdxf.DefaultAstHost {
+		t.Fatalf("default ast host: %s", host)
+	}
+
+	config = &dgkdxf.ClientConfig{Host: "http://127.0.0.1:8080/", AsrHost: "https://asr.example.com"}
+	if host := config.ServiceHost(dgkdxf.ServiceAsr); host != "https://asr.example.com" {
+		t.Fatalf("asr host: %s", host)
+	}
+	if host := config.ServiceHost(dgkdxf.ServiceAst); host != "ws://127.0.0.1:8080" {
+		t.Fatalf("ast host: %s", host)
+	}
+	if host := config.ServiceHost(dgkdxf.ServiceCc); host != "http://127.0.0.1:8080" {
+		t.Fatalf("cc host: %s", host)
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, invalid := range []*dgkdxf.ClientConfig{
+		{AstHost: "https://api.iflyrec.com"},
+		{FeatureHost: "wss://api.iflyrec.com"},
+		{CcHost: "api.iflyrec.com"},
+	} {
+		if err := invalid.Validate(); !errors.Is(err, dgkdxf.InvalidHostErr) {
+			t.Fatalf("%+v err: %v", invalid, err)
+		}
+	}
+}
+
+func TestCcSignatureWithHttpHost(t *testing.T) {
+	var client *dgkdxf.Client
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 签名的 host 不含 scheme，包含端口
+		if client.VerifyCCSignature(r, "", nil) != nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"requestId":"r1","client":{"cno":"1001"}}`))
+	}))
+	defer server.Close()
+
+	client = dgkdxf.NewClient(&dgkdxf.ClientConfig{AccessKeyId: "ak", AccessKeySecret: "sk", CcHost: server.URL})
+	if _, err := client.DetailByCno(&dgctx.DgContext{TraceId: "t1"}, &dgkdxf.CnoReq{Cno: "1001"}); err != nil {
+		t.Fatal(err)
+	}
+}
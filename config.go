@@ -0,0 +1,216 @@
+package iflytek
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultConfigEnvPrefix = "IFLYTEK_"
+
+	redactedSecret = "******"
+)
+
+var (
+	MissingConfigErr           = errors.New("missing config")
+	UnsupportedConfigFormatErr = errors.New("unsupported config format")
+)
+
+type LoadConfigOptions struct {
+	EnvPrefix  string        // 环境变量前缀，默认 DefaultConfigEnvPrefix，如 IFLYTEK_APP_ID、IFLYTEK_ACCESS_KEY_SECRET
+	DisableEnv bool          // 不读取环境变量
+	Files      []string      // yaml 或 json 配置文件，按扩展名识别，后面的覆盖前面的，不存在的文件报错
+	Overrides  *ClientConfig // 非零值字段覆盖文件和环境变量中的配置
+	Services   []Service     // 需要校验必填项的服务，为空时只校验服务地址
+}
+
+// configEnvFields 环境变量名（不含前缀）与配置字段的对应关系
+func configEnvFields(cfg *ClientConfig) map[string]*string {
+	return map[string]*string{
+		"APP_ID":            &cfg.AppId,
+		"HOST":              &cfg.Host,
+		"ASR_HOST":          &cfg.AsrHost,
+		"AST_HOST":          &cfg.AstHost,
+		"FEATURE_HOST":      &cfg.FeatureHost,
+		"CC_HOST":           &cfg.CcHost,
+		"ACCESS_KEY_ID":     &cfg.AccessKeyId,
+		"ACCESS_KEY_SECRET": &cfg.AccessKeySecret,
+	}
+}
+
+// LoadClientConfig 依次合并配置文件、环境变量和 Overrides，后者优先，合并后校验 Services 的必填项
+func LoadClientConfig(opts *LoadConfigOptions) (*ClientConfig, error) {
+	if opts == nil {
+		opts = &LoadConfigOptions{}
+	}
+
+	cfg := &ClientConfig{}
+	for _, file := range opts.Files {
+		if err := mergeConfigFile(cfg, file); err != nil {
+			return nil, err
+		}
+	}
+	if !opts.DisableEnv {
+		prefix := opts.EnvPrefix
+		if prefix == "" {
+			prefix = DefaultConfigEnvPrefix
+		}
+		if err := mergeConfigEnv(cfg, prefix); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Overrides != nil {
+		mergeConfigOverrides(cfg, opts.Overrides)
+	}
+
+	if err := cfg.ValidateServices(opts.Services...); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func mergeConfigFile(cfg *ClientConfig, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	// 只覆盖文件中出现的字段
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		err = json.Unmarshal(data, cfg)
+	case ".yaml", ".yml":
+		// 直接解析到结构体，appId: 12345678 这类数字写法也能读取为字符串
+		err = yaml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("%w: %s", UnsupportedConfigFormatErr, file)
+	}
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", file, err)
+	}
+
+	return nil
+}
+
+func mergeConfigEnv(cfg *ClientConfig, prefix string) error {
+	for name, field := range configEnvFields(cfg) {
+		if value, ok := os.LookupEnv(prefix + name); ok && value != "" {
+			*field = value
+		}
+	}
+
+	if value := os.Getenv(prefix + "LOG_HIDDEN_TYPE"); value != "" {
+		hiddenType, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid env %sLOG_HIDDEN_TYPE %q: %v", prefix, value, err)
+		}
+		cfg.LogHiddenType = HiddenType(hiddenType)
+	}
+
+	return nil
+}
+
+func mergeConfigOverrides(cfg *ClientConfig, overrides *ClientConfig) {
+	fields := configEnvFields(cfg)
+	for name, value := range configEnvFields(overrides) {
+		if *value != "" {
+			*fields[name] = *value
+		}
+	}
+
+	if overrides.LogHiddenType != HiddenTypeNone {
+		cfg.LogHiddenType = overrides.LogHiddenType
+	}
+	if overrides.Clock != nil {
+		cfg.Clock = overrides.Clock
+	}
+	if overrides.RandomId != nil {
+		cfg.RandomId = overrides.RandomId
+	}
+	if overrides.CredentialsProvider != nil {
+		cfg.CredentialsProvider = overrides.CredentialsProvider
+	}
+	if overrides.RateLimits != nil {
+		cfg.RateLimits = overrides.RateLimits
+	}
+	if overrides.RateLimiter != nil {
+		cfg.RateLimiter = overrides.RateLimiter
+	}
+	if overrides.Instrumentation != nil {
+		cfg.Instrumentation = overrides.Instrumentation
+	}
+}
+
+// ValidateServices 校验服务地址及 services 的必填项：所有服务都需要密钥或 CredentialsProvider，ast 还需要 AppId
+func (cfg *ClientConfig) ValidateServices(services ...Service) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, service := range services {
+		switch service {
+		case ServiceAsr, ServiceAst, ServiceFeature, ServiceCc:
+		default:
+			return fmt.Errorf("unknown service %q", service)
+		}
+		if service == ServiceAst && cfg.AppId == "" {
+			missing = append(missing, "ast: appId")
+		}
+		if cfg.CredentialsProvider == nil {
+			if cfg.AccessKeyId == "" {
+				missing = append(missing, string(service)+": accessKeyId")
+			}
+			if cfg.AccessKeySecret == "" {
+				missing = append(missing, string(service)+": accessKeySecret")
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", MissingConfigErr, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// Redacted 去除 AccessKeySecret、隐藏部分 AccessKeyId 后的副本，用于打印和日志
+func (cfg ClientConfig) Redacted() ClientConfig {
+	if cfg.AccessKeySecret != "" {
+		cfg.AccessKeySecret = redactedSecret
+	}
+	if len(cfg.AccessKeyId) > 4 {
+		cfg.AccessKeyId = cfg.AccessKeyId[:4] + redactedSecret
+	} else if cfg.AccessKeyId != "" {
+		cfg.AccessKeyId = redactedSecret
+	}
+
+	return cfg
+}
+
+// MarshalJSON 序列化时始终去除密钥，避免配置被整体打印到日志中；保存配置请使用 MarshalWithSecrets
+func (cfg ClientConfig) MarshalJSON() ([]byte, error) {
+	type plain ClientConfig
+	return json.Marshal(plain(cfg.Redacted()))
+}
+
+// MarshalWithSecrets 保留密钥的 json，可用 LoadClientConfig 重新读取，注意不要打印到日志中
+func (cfg ClientConfig) MarshalWithSecrets() ([]byte, error) {
+	type plain ClientConfig
+	return json.Marshal(plain(cfg))
+}
+
+func (cfg ClientConfig) String() string {
+	data, _ := cfg.MarshalJSON()
+	return string(data)
+}
+
+func (cfg ClientConfig) GoString() string {
+	return "iflytek.ClientConfig" + cfg.String()
+}
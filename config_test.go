@@ -0,0 +1,87 @@
+package iflytek_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newEnvClient 集成测试从 IFLYTEK_APP_ID、IFLYTEK_ACCESS_KEY_ID、IFLYTEK_ACCESS_KEY_SECRET 读取配置，缺少时跳过
+func newEnvClient(t *testing.T, host string, service dgkdxf.Service) *dgkdxf.Client {
+	t.Helper()
+	config, err := dgkdxf.LoadClientConfig(&dgkdxf.LoadConfigOptions{
+		Overrides: &dgkdxf.ClientConfig{Host: host},
+		Services:  []dgkdxf.Service{service},
+	})
+	if errors.Is(err, dgkdxf.MissingConfigErr) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dgkdxf.NewClient(config)
+}
+
+func TestLoadClientConfig(t *testing.T) {
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "iflytek.yaml")
+	jsonFile := filepath.Join(dir, "iflytek.json")
+	_ = os.WriteFile(yamlFile, []byte("appId: yaml-app\naccessKeyId: yaml-ak\naccessKeySecret: yaml-sk\nccHost: https://cc.example.com\nlogHiddenType: 1\n"), 0600)
+	_ = os.WriteFile(jsonFile, []byte(`{"accessKeyId": "json-ak"}`), 0600)
+	t.Setenv("TEST_IFLYTEK_ACCESS_KEY_SECRET", "env-sk")
+
+	config, err := dgkdxf.LoadClientConfig(&dgkdxf.LoadConfigOptions{
+		EnvPrefix: "TEST_IFLYTEK_",
+		Files:     []string{yamlFile, jsonFile},
+		Overrides: &dgkdxf.ClientConfig{AsrHost: "https://asr.example.com"},
+		Services:  []dgkdxf.Service{dgkdxf.ServiceAsr, dgkdxf.ServiceAst, dgkdxf.ServiceCc},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.AppId != "yaml-app" || config.AccessKeyId != "json-ak" || config.AccessKeySecret != "env-sk" ||
+		config.CcHost != "https://cc.example.com" || config.AsrHost != "https://asr.example.com" || config.LogHiddenType != dgkdxf.HiddenTypeMiddleFour {
+		t.Fatalf("merged config: %+v", *config)
+	}
+
+	marshaled, _ := json.Marshal(config)
+	for _, printed := range []string{config.String(), fmt.Sprintf("%v", config), fmt.Sprintf("%+v", *config), fmt.Sprintf("%#v", *config), string(marshaled)} {
+		if strings.Contains(printed, "env-sk") || strings.Contains(printed, "json-ak") {
+			t.Fatalf("secret leaked: %s", printed)
+		}
+	}
+
+	// MarshalWithSecrets 保留密钥，可以把配置原样保存
+	data, _ := config.MarshalWithSecrets()
+	var saved dgkdxf.ClientConfig
+	if err := json.Unmarshal(data, &saved); err != nil || saved.AccessKeySecret != "env-sk" || saved.AccessKeyId != "json-ak" {
+		t.Fatalf("marshaled config: %s, %v", data, err)
+	}
+
+	// yaml 中未加引号的数字也能读取为字符串
+	numericFile := filepath.Join(dir, "numeric.yml")
+	_ = os.WriteFile(numericFile, []byte("appId: 12345678\naccessKeyId: 0123\naccessKeySecret: sk\nrateLimits:\n  cc.callout:\n    qps: 2\n"), 0600)
+	config, err = dgkdxf.LoadClientConfig(&dgkdxf.LoadConfigOptions{DisableEnv: true, Files: []string{numericFile}})
+	if err != nil || config.AppId != "12345678" || config.AccessKeyId != "0123" || config.RateLimits["cc.callout"].QPS != 2 {
+		t.Fatalf("numeric yaml: %+v, %v", config, err)
+	}
+
+	_, err = dgkdxf.LoadClientConfig(&dgkdxf.LoadConfigOptions{
+		DisableEnv: true,
+		Overrides:  &dgkdxf.ClientConfig{AccessKeyId: "ak", AccessKeySecret: "sk"},
+		Services:   []dgkdxf.Service{dgkdxf.ServiceCc, dgkdxf.ServiceAst},
+	})
+	if !errors.Is(err, dgkdxf.MissingConfigErr) || !strings.Contains(err.Error(), "ast: appId") {
+		t.Fatalf("ast without appId: %v", err)
+	}
+
+	if _, err := dgkdxf.LoadClientConfig(&dgkdxf.LoadConfigOptions{Files: []string{filepath.Join(dir, "iflytek.toml")}}); err == nil {
+		t.Fatal("expect error for unsupported config file")
+	}
+}
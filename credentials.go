@@ -0,0 +1,278 @@
+package iflytek
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/darwinOrg/go-common/utils"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	EnvAccessKeyId     = "IFLYTEK_ACCESS_KEY_ID"
+	EnvAccessKeySecret = "IFLYTEK_ACCESS_KEY_SECRET"
+
+	defaultCredentialsTTL     = time.Minute
+	defaultCredentialsOverlap = 10 * time.Minute
+)
+
+var CredentialsNotFoundErr = errors.New("credentials not found")
+
+type Credentials struct {
+	AccessKeyId     string `json:"accessKeyId"`
+	AccessKeySecret string `json:"accessKeySecret"`
+}
+
+func (c *Credentials) valid() bool {
+	return c != nil && c.AccessKeyId != "" && c.AccessKeySecret != ""
+}
+
+// CredentialsProvider 提供签名使用的密钥，每次签名都会调用，实现方需保证并发安全
+type CredentialsProvider interface {
+	Credentials() (*Credentials, error)
+}
+
+type StaticCredentialsProvider struct {
+	AccessKeyId     string
+	AccessKeySecret string
+}
+
+func (p *StaticCredentialsProvider) Credentials() (*Credentials, error) {
+	return &Credentials{AccessKeyId: p.AccessKeyId, AccessKeySecret: p.AccessKeySecret}, nil
+}
+
+// EnvCredentialsProvider 从环境变量读取密钥，变量名为空时使用 EnvAccessKeyId 和 EnvAccessKeySecret
+type EnvCredentialsProvider struct {
+	AccessKeyIdEnv     string
+	AccessKeySecretEnv string
+}
+
+func (p *EnvCredentialsProvider) Credentials() (*Credentials, error) {
+	idEnv, secretEnv := p.AccessKeyIdEnv, p.AccessKeySecretEnv
+	if idEnv == "" {
+		idEnv = EnvAccessKeyId
+	}
+	if secretEnv == "" {
+		secretEnv = EnvAccessKeySecret
+	}
+
+	creds := &Credentials{AccessKeyId: os.Getenv(idEnv), AccessKeySecret: os.Getenv(secretEnv)}
+	if !creds.valid() {
+		return nil, fmt.Errorf("%w: env %s or %s is empty", CredentialsNotFoundErr, idEnv, secretEnv)
+	}
+
+	return creds, nil
+}
+
+// FileCredentialsProvider 从 json 文件读取 {"accessKeyId": "...", "accessKeySecret": "..."}，
+// 文件的修改时间或大小变化时重新读取，适用于 vault agent 等定期写入文件的场景
+type FileCredentialsProvider struct {
+	Path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	creds   *Credentials
+}
+
+func (p *FileCredentialsProvider) Credentials() (*Credentials, error) {
+	info, err := os.Stat(p.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.creds != nil && info.ModTime().Equal(p.modTime) && info.Size() == p.size {
+		return p.creds, nil
+	}
+
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	creds := &Credentials{}
+	if err := json.Unmarshal(data, creds); err != nil {
+		return nil, fmt.Errorf("parse credentials file %s: %w", p.Path, err)
+	}
+	if !creds.valid() {
+		return nil, fmt.Errorf("%w: %s", CredentialsNotFoundErr, p.Path)
+	}
+
+	p.creds, p.modTime, p.size = creds, info.ModTime(), info.Size()
+
+	return creds, nil
+}
+
+type CachingCredentialsOptions struct {
+	TTL     time.Duration // 缓存时长，默认1分钟
+	Overlap time.Duration // 密钥轮换后旧密钥仍可用于校验入站签名的时长，默认10分钟
+	// ActivationDelay 密钥轮换后出站请求继续使用旧密钥签名的时长，用于等待讯飞侧启用新密钥，默认0立即切换
+	ActivationDelay time.Duration
+	Clock           func() time.Time
+}
+
+// CachingCredentialsProvider 按 TTL 缓存 provider 的结果；刷新失败时继续使用上一次的密钥，
+// 密钥变化后旧密钥在 Overlap 时长内仍会出现在 Candidates 中，供入站签名校验使用；
+// 出站请求在 ActivationDelay 内仍使用旧密钥签名，之后切换到新密钥
+type CachingCredentialsProvider struct {
+	provider CredentialsProvider
+	opts     CachingCredentialsOptions
+
+	mu           sync.Mutex
+	current      *Credentials
+	fetchedAt    time.Time
+	previous     *Credentials
+	rotatedAt    time.Time
+	lastFetchErr error
+}
+
+func NewCachingCredentialsProvider(provider CredentialsProvider, opts *CachingCredentialsOptions) *CachingCredentialsProvider {
+	p := &CachingCredentialsProvider{provider: provider}
+	if opts != nil {
+		p.opts = *opts
+	}
+	if p.opts.TTL <= 0 {
+		p.opts.TTL = defaultCredentialsTTL
+	}
+	if p.opts.Overlap <= 0 {
+		p.opts.Overlap = defaultCredentialsOverlap
+	}
+	if p.opts.Clock == nil {
+		p.opts.Clock = time.Now
+	}
+
+	return p
+}
+
+// Credentials 出站签名使用的密钥，轮换后 ActivationDelay 内返回旧密钥
+func (p *CachingCredentialsProvider) Credentials() (*Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	latest, err := p.refresh()
+	if err != nil {
+		return nil, err
+	}
+	if p.previous != nil && p.opts.Clock().Sub(p.rotatedAt) < p.opts.ActivationDelay {
+		return p.previous, nil
+	}
+
+	return latest, nil
+}
+
+// Candidates 最新密钥及仍在重叠期内的旧密钥
+func (p *CachingCredentialsProvider) Candidates() ([]*Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	latest, err := p.refresh()
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []*Credentials{latest}
+	if p.previous != nil && p.opts.Clock().Sub(p.rotatedAt) < max(p.opts.Overlap, p.opts.ActivationDelay) {
+		candidates = append(candidates, p.previous)
+	}
+
+	return candidates, nil
+}
+
+// refresh 需要持有 p.mu，返回最新获取到的密钥
+func (p *CachingCredentialsProvider) refresh() (*Credentials, error) {
+	now := p.opts.Clock()
+	if p.current != nil && now.Sub(p.fetchedAt) < p.opts.TTL {
+		return p.current, nil
+	}
+
+	creds, err := p.provider.Credentials()
+	if err == nil && !creds.valid() {
+		err = CredentialsNotFoundErr
+	}
+	if err != nil {
+		p.lastFetchErr = err
+		if p.current != nil {
+			return p.current, nil
+		}
+		return nil, err
+	}
+
+	p.lastFetchErr = nil
+	if p.current != nil && *p.current != *creds {
+		p.previous, p.rotatedAt = p.current, now
+	}
+	p.current, p.fetchedAt = creds, now
+
+	return creds, nil
+}
+
+// LastError 最近一次刷新失败的错误，刷新成功后清空
+func (p *CachingCredentialsProvider) LastError() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.lastFetchErr
+}
+
+func (c *Credentials) sign(stringToSign string) string {
+	return utils.Sha1Base64Encode(c.AccessKeySecret, stringToSign)
+}
+
+// credentialsProvider 未配置 CredentialsProvider 时使用 ClientConfig 中的静态密钥
+func (c *Client) credentialsProvider() *CachingCredentialsProvider {
+	c.credsOnce.Do(func() {
+		switch provider := c.Config.CredentialsProvider.(type) {
+		case nil:
+		case *CachingCredentialsProvider:
+			c.creds = provider
+		default:
+			c.creds = NewCachingCredentialsProvider(provider, &CachingCredentialsOptions{Clock: c.now})
+		}
+	})
+
+	return c.creds
+}
+
+// credentials 每次签名获取一次密钥，保证同一个签名中的 AccessKeyId 和 AccessKeySecret 一致
+func (c *Client) credentials() (*Credentials, error) {
+	provider := c.credentialsProvider()
+	if provider == nil {
+		return &Credentials{AccessKeyId: c.Config.AccessKeyId, AccessKeySecret: c.Config.AccessKeySecret}, nil
+	}
+
+	return provider.Credentials()
+}
+
+// verifyCredentials 校验签名时可用的密钥，包括轮换重叠期内的旧密钥
+func (c *Client) verifyCredentials(accessKeyId string) ([]*Credentials, error) {
+	var candidates []*Credentials
+	if provider := c.credentialsProvider(); provider != nil {
+		all, err := provider.Candidates()
+		if err != nil {
+			return nil, err
+		}
+		candidates = all
+	} else {
+		creds, err := c.credentials()
+		if err != nil {
+			return nil, err
+		}
+		candidates = []*Credentials{creds}
+	}
+
+	var matched []*Credentials
+	for _, creds := range candidates {
+		if creds.AccessKeyId == accessKeyId {
+			matched = append(matched, creds)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("%w: accessKeyId mismatch", SignatureInvalidErr)
+	}
+
+	return matched, nil
+}
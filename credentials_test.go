@@ -0,0 +1,128 @@
+package iflytek_test
+
+import (
+	"errors"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvCredentialsProvider(t *testing.T) {
+	t.Setenv(dgkdxf.EnvAccessKeyId, "")
+	t.Setenv(dgkdxf.EnvAccessKeySecret, "")
+	provider := &dgkdxf.EnvCredentialsProvider{}
+	if _, err := provider.Credentials(); !errors.Is(err, dgkdxf.CredentialsNotFoundErr) {
+		t.Fatalf("empty env: %v", err)
+	}
+
+	t.Setenv(dgkdxf.EnvAccessKeyId, "ak")
+	t.Setenv(dgkdxf.EnvAccessKeySecret, "sk")
+	creds, err := provider.Credentials()
+	if err != nil || creds.AccessKeyId != "ak" || creds.AccessKeySecret != "sk" {
+		t.Fatalf("env: %+v, %v", creds, err)
+	}
+}
+
+func TestCredentialsRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	writeCredentials(t, path, `{"accessKeyId":"ak1","accessKeySecret":"sk1"}`, time.Now())
+
+	now := time.Now()
+	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{
+		AppId:               "app",
+		Clock:               func() time.Time { return now },
+		CredentialsProvider: &dgkdxf.FileCredentialsProvider{Path: path},
+	})
+	ctx := &dgctx.DgContext{TraceId: "t1"}
+	authString := func() string {
+		astUri, err := client.AstUri(ctx, &dgkdxf.AstParamConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		uri, err := url.Parse(astUri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return uri.Query().Get("authString")
+	}
+	opts := &dgkdxf.SignatureVerifyOptions{MaxClockSkew: time.Hour}
+
+	oldAuth := authString()
+	writeCredentials(t, path, `{"accessKeyId":"ak2","accessKeySecret":"sk2-rotated"}`, time.Now().Add(time.Second))
+
+	// TTL 内仍使用缓存的旧密钥
+	now = now.Add(30 * time.Second)
+	if auth := authString(); !strings.HasPrefix(auth, "v1.0,app,ak1") {
+		t.Fatalf("cached: %s", auth)
+	}
+
+	now = now.Add(time.Minute)
+	newAuth := authString()
+	if !strings.HasPrefix(newAuth, "v1.0,app,ak2") {
+		t.Fatalf("rotated: %s", newAuth)
+	}
+	if err := client.VerifyAstAuthString(newAuth, opts); err != nil {
+		t.Fatalf("new key: %v", err)
+	}
+	if err := client.VerifyAstAuthString(oldAuth, opts); err != nil {
+		t.Fatalf("old key in overlap: %v", err)
+	}
+
+	// 刷新失败时继续使用上一次的密钥
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Minute)
+	if auth := authString(); !strings.HasPrefix(auth, "v1.0,app,ak2") {
+		t.Fatalf("stale: %s", auth)
+	}
+
+	now = now.Add(10 * time.Minute)
+	if err := client.VerifyAstAuthString(oldAuth, opts); !errors.Is(err, dgkdxf.SignatureInvalidErr) {
+		t.Fatalf("old key after overlap: %v", err)
+	}
+	if err := client.VerifyAstAuthString(authString(), opts); err != nil {
+		t.Fatalf("current key: %v", err)
+	}
+}
+
+func TestCredentialsActivationDelay(t *testing.T) {
+	keys := &dgkdxf.StaticCredentialsProvider{AccessKeyId: "ak1", AccessKeySecret: "sk1"}
+	now := time.Now()
+	provider := dgkdxf.NewCachingCredentialsProvider(keys, &dgkdxf.CachingCredentialsOptions{
+		ActivationDelay: 5 * time.Minute,
+		Clock:           func() time.Time { return now },
+	})
+	if creds, _ := provider.Credentials(); creds.AccessKeyId != "ak1" {
+		t.Fatalf("initial: %+v", creds)
+	}
+
+	keys.AccessKeyId, keys.AccessKeySecret = "ak2", "sk2"
+	now = now.Add(2 * time.Minute)
+	if creds, _ := provider.Credentials(); creds.AccessKeyId != "ak1" {
+		t.Fatalf("before activation: %+v", creds)
+	}
+	if candidates, _ := provider.Candidates(); len(candidates) != 2 || candidates[0].AccessKeyId != "ak2" {
+		t.Fatalf("candidates: %+v", candidates)
+	}
+
+	now = now.Add(5 * time.Minute)
+	if creds, _ := provider.Credentials(); creds.AccessKeyId != "ak2" {
+		t.Fatalf("after activation: %+v", creds)
+	}
+}
+
+func writeCredentials(t *testing.T, path string, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,172 @@
+package iflytek
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// 错误类别，*Error 通过 Unwrap 返回其中之一或底层错误，可以用 errors.Is 判断
+var (
+	ApiNoSuccessErr         = errors.New("api resp no success") // 讯飞返回了非成功的业务码
+	ApiGetResultFailTypeErr = errors.New("api get result fail") // 转写订单失败，Code 为 failType
+	HttpStatusErr           = errors.New("unexpected http status")
+	EmptyResponseErr        = errors.New("empty response")
+	OperationFailedErr      = errors.New("operation failed") // 请求成功但业务处理失败，如声纹注册、更新、删除失败
+	NilConnErr              = errors.New("websocket conn is nil")
+)
+
+const maxErrorBodySize = 4 * 1024
+
+// Error 调用讯飞接口失败时返回的错误，所有接口方法的错误都可以用 errors.As 取得
+type Error struct {
+	Service    Service `json:"service"`
+	Op         string  `json:"op"`         // 接口名，如 upload、getResult、register、callout
+	StatusCode int     `json:"statusCode"` // http 状态码，未收到响应时为 0
+	Code       string  `json:"code"`       // 讯飞的业务码，转写订单失败时为 failType
+	Message    string  `json:"message"`
+	RequestId  string  `json:"requestId"` // 呼叫中心的 requestId，声纹和 ast 的 sid
+	Retryable  bool    `json:"retryable"` // 请求未被处理，或幂等接口超时、网络错误和 5xx 时可以重试
+	Err        error   `json:"-"`
+}
+
+func (e *Error) Error() string {
+	var sb strings.Builder
+	sb.WriteString("iflytek ")
+	sb.WriteString(string(e.Service))
+	if e.Op != "" {
+		sb.WriteString(".")
+		sb.WriteString(e.Op)
+	}
+	sb.WriteString(": ")
+	if e.Err != nil {
+		sb.WriteString(e.Err.Error())
+	} else {
+		sb.WriteString("error")
+	}
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&sb, ", statusCode: %d", e.StatusCode)
+	}
+	if e.Code != "" {
+		fmt.Fprintf(&sb, ", code: %s", e.Code)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&sb, ", message: %s", e.Message)
+	}
+	if e.RequestId != "" {
+		fmt.Fprintf(&sb, ", requestId: %s", e.RequestId)
+	}
+
+	return sb.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable err 为可重试的 *Error
+func IsRetryable(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Retryable
+}
+
+// nonIdempotentOps 重复请求会产生重复副作用的接口：转写订单、外呼、声纹和已发送的实时音频
+var nonIdempotentOps = map[string]bool{
+	"asr.upload":       true,
+	"cc.callout":       true,
+	"feature.register": true,
+	"ast.write":        true,
+}
+
+// retryable 确定请求未被处理时（限流、建立连接失败、429、讯飞的限速码）都可以重试；
+// 超时、连接中断和 5xx 时请求可能已被处理，只有幂等接口可以重试
+func (e *Error) retryable() bool {
+	if notSent(e.Err) || e.StatusCode == http.StatusTooManyRequests || e.Code == ExceedUploadSpeedLimitCode {
+		return true
+	}
+	if nonIdempotentOps[string(e.Service)+"."+e.Op] {
+		return false
+	}
+
+	return e.ambiguous()
+}
+
+// ambiguous 请求已发出但没有明确结果（超时、连接中断或 5xx），讯飞可能已经处理
+func (e *Error) ambiguous() bool {
+	if notSent(e.Err) {
+		return false
+	}
+
+	var netErr net.Error
+	return e.StatusCode >= http.StatusInternalServerError || errors.Is(e.Err, io.ErrUnexpectedEOF) || errors.As(e.Err, &netErr)
+}
+
+// notSent 请求没有发出：被限流拒绝或没有建立连接
+func notSent(err error) bool {
+	var opErr *net.OpError
+	return errors.Is(err, RateLimitedErr) || errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// newError 按接口名拆分 service 和 op，如 asr.upload、cc.callout
+func newError(api Api, op string, cause error) *Error {
+	service, apiOp, _ := strings.Cut(string(api), ".")
+	if op == "" {
+		op = apiOp
+	}
+	e := &Error{Service: Service(service), Op: op, Err: cause}
+	e.Retryable = e.retryable()
+
+	return e
+}
+
+// AstMessageError ast 返回的错误消息，如 {"action":"error","code":"100001","desc":"...","sid":"..."}，不是错误消息时返回 nil
+func AstMessageError(data []byte) *Error {
+	var msg struct {
+		Action string `json:"action"`
+		Code   any    `json:"code"`
+		Desc   string `json:"desc"`
+		Sid    string `json:"sid"`
+	}
+	if json.Unmarshal(data, &msg) != nil {
+		return nil
+	}
+
+	code := ""
+	if msg.Code != nil {
+		code = fmt.Sprint(msg.Code)
+	}
+	if msg.Action != "error" && (code == "" || code == "0" || code == apiSuccessCode) {
+		return nil
+	}
+
+	return &Error{
+		Service:   ServiceAst,
+		Op:        "stream",
+		Code:      code,
+		Message:   msg.Desc,
+		RequestId: msg.Sid,
+		Retryable: code == ExceedUploadSpeedLimitCode,
+		Err:       ApiNoSuccessErr,
+	}
+}
+
+// readErrorBody 读取非 200 响应的错误信息，呼叫中心返回 KdxfResponse 格式
+func readErrorBody(response *http.Response) (code string, message string, requestId string) {
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxErrorBodySize))
+	if err != nil || len(body) == 0 {
+		return "", "", ""
+	}
+
+	var resp KdxfResponse
+	if json.Unmarshal(body, &resp) == nil && (resp.Error.Code != "" || resp.Error.Message != "" || resp.RequestID != "") {
+		return resp.Error.Code, resp.Error.Message, resp.RequestID
+	}
+
+	return "", strings.TrimSpace(string(body)), ""
+}
@@ -0,0 +1,89 @@
+package iflytek_test
+
+import (
+	"errors"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cc/online":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"requestId":"r1","error":{"code":"ServiceUnavailable","message":"busy"}}`))
+		case "/cc/callout":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/cc/offline":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"requestId":"r2","error":{"code":"InvalidParameter","message":"cno"}}`))
+		case "/v2/getResult":
+			if r.URL.Query().Get("orderId") == "failed" {
+				_, _ = w.Write([]byte(`{"code":"000000","descInfo":"audio error","content":{"orderInfo":{"orderId":"failed","failType":3}}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"code":"26600","descInfo":"order not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	limiter := dgkdxf.NewRateLimiter(map[dgkdxf.Api]dgkdxf.RateLimit{dgkdxf.ApiCc: {MaxConcurrent: 1, MaxWaitMillis: 1}})
+	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{AccessKeyId: "ak", AccessKeySecret: "sk", Host: server.URL, RateLimiter: limiter})
+	ctx := &dgctx.DgContext{TraceId: "t1"}
+
+	_, err := client.Online(ctx, &dgkdxf.OnlineReq{})
+	var e *dgkdxf.Error
+	if !errors.As(err, &e) || !errors.Is(err, dgkdxf.HttpStatusErr) {
+		t.Fatalf("online: %v", err)
+	}
+	if e.Service != dgkdxf.ServiceCc || e.Op != "online" || e.StatusCode != http.StatusServiceUnavailable ||
+		e.Code != "ServiceUnavailable" || e.Message != "busy" || e.RequestId != "r1" || !e.Retryable {
+		t.Fatalf("online: %+v", e)
+	}
+
+	_, err = client.Offline(ctx, &dgkdxf.OfflineReq{})
+	if !errors.Is(err, dgkdxf.HttpStatusErr) || dgkdxf.IsRetryable(err) {
+		t.Fatalf("offline: %v", err)
+	}
+
+	_, err = client.GetAsrResult(ctx, "o1")
+	if !errors.As(err, &e) || !errors.Is(err, dgkdxf.ApiNoSuccessErr) || e.Op != "getResult" || e.Code != "26600" || e.Message != "order not found" {
+		t.Fatalf("getResult: %v", err)
+	}
+
+	_, err = client.GetAsrResult(ctx, "failed")
+	if !errors.As(err, &e) || !errors.Is(err, dgkdxf.ApiGetResultFailTypeErr) || e.Code != "3" {
+		t.Fatalf("failType: %v", err)
+	}
+
+	// 外呼可能已经发出，5xx 不能重试
+	_, err = client.Callout(ctx, &dgkdxf.CalloutReq{})
+	if !errors.As(err, &e) || e.StatusCode != http.StatusBadGateway || e.Retryable {
+		t.Fatalf("callout: %+v", err)
+	}
+
+	// 限流拒绝同样返回 *Error
+	release, err := limiter.Acquire(ctx, dgkdxf.CcApi("/cc/callout?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	_, err = client.Callout(ctx, &dgkdxf.CalloutReq{})
+	if !errors.As(err, &e) || !errors.Is(err, dgkdxf.RateLimitedErr) || e.Op != "callout" || !e.Retryable {
+		t.Fatalf("rate limited: %v", err)
+	}
+}
+
+func TestAstMessageError(t *testing.T) {
+	if err := dgkdxf.AstMessageError([]byte(`{"action":"started","code":"0","sid":"s1"}`)); err != nil {
+		t.Fatalf("started: %v", err)
+	}
+
+	err := dgkdxf.AstMessageError([]byte(`{"action":"error","code":"100001","desc":"exceed upload speed limit","sid":"s1"}`))
+	if err == nil || !errors.Is(err, dgkdxf.ApiNoSuccessErr) || err.Service != dgkdxf.ServiceAst || err.RequestId != "s1" || !err.Retryable {
+		t.Fatalf("error: %+v", err)
+	}
+}
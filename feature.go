@@ -6,11 +6,21 @@ import (
 	"github.com/darwinOrg/go-common/model"
 	"github.com/darwinOrg/go-common/utils"
 	dghttp "github.com/darwinOrg/go-httpclient"
-	"github.com/google/uuid"
+	dglogger "github.com/darwinOrg/go-logger"
+	"maps"
 	"strings"
 )
 
-const featureFailStatus = 2
+const (
+	featureFailStatus = 2
+
+	featurePathPrefix   = "/res/feature/v1/"
+	registerFeaturePath = featurePathPrefix + "register?"
+	updateFeaturePath   = featurePathPrefix + "update?"
+	deleteFeaturePath   = featurePathPrefix + "delete?"
+
+	maxDeleteFeatureBatchSize = 100
+)
 
 type FeatureResult[T any] struct {
 	Code string `json:"code"`
@@ -25,7 +35,7 @@ func (rt *FeatureResult[T]) isSuccess() bool {
 
 type RegisterFeatureRequest struct {
 	AudioData string    `json:"audio_data" binding:"required,minLength=1"`
-	AudioType AudioType `json:"audio_type" binding:"required,mustIn=raw#speex#opus-ogg"`
+	AudioType AudioType `json:"audio_type" binding:"required,mustIn=raw#speex#opus-ogg#opus-wb"`
 	Uid       string    `json:"uid"`
 }
 
@@ -37,7 +47,7 @@ type RegisterFeatureResponse struct {
 type UpdateFeatureRequest struct {
 	FeatureId string    `json:"feature_id" binding:"required"`
 	AudioData string    `json:"audio_data" binding:"required,minLength=1"`
-	AudioType AudioType `json:"audio_type" binding:"required,mustIn=raw#speex#opus-ogg"`
+	AudioType AudioType `json:"audio_type" binding:"required,mustIn=raw#speex#opus-ogg#opus-wb"`
 }
 
 type UpdateFeatureResponse struct {
@@ -52,18 +62,52 @@ type DeleteFeatureResponse struct {
 	DelFailIds string `json:"del_fail_ids"`
 }
 
-func (c *Client) RegisterFeature(ctx *dgctx.DgContext, req *RegisterFeatureRequest) (string, error) {
-	params, header := c.buildFeatureParamsAndHeader(ctx)
-	url := c.Config.Host + "/res/feature/v1/register?" + utils.FormUrlEncodedParams(params)
+type DeleteFeatureFailure struct {
+	FeatureId string `json:"featureId"`
+	Reason    string `json:"reason"`          // 讯飞返回的 desc，没有时为"删除失败"
+	Err       *Error `json:"error,omitempty"` // 所在批次的错误，包含业务码和 sid
+}
+
+type DeleteFeatureResult struct {
+	DeletedIds []string                `json:"deletedIds"`
+	Failed     []*DeleteFeatureFailure `json:"failed"`
+}
+
+func (r *DeleteFeatureResult) FailedIds() []string {
+	ids := make([]string, 0, len(r.Failed))
+	for _, failure := range r.Failed {
+		ids = append(ids, failure.FeatureId)
+	}
+
+	return ids
+}
+
+func (c *Client) RegisterFeature(ctx *dgctx.DgContext, req *RegisterFeatureRequest) (_ string, err error) {
+	call, err := c.startFeatureCall(ctx, registerFeaturePath)
+	if err != nil {
+		return "", err
+	}
+	defer func() { err = call.end(err) }()
+
+	params, header, err := c.buildFeatureParamsAndHeader(ctx)
+	if err != nil {
+		return "", err
+	}
+	url := c.Config.ServiceHost(ServiceFeature) + registerFeaturePath + utils.FormUrlEncodedParams(params)
+	maps.Copy(header, call.headers())
 	dghttp.SetHttpClient(ctx, dghttp.Client11)
 	defer dghttp.SetHttpClient(ctx, nil)
 	rt, err := dghttp.DoPostJsonToStruct[FeatureResult[string]](ctx, url, req, header)
 	if err != nil {
 		return "", err
 	}
+	call.code, call.requestId = rt.Code, rt.Sid
 
 	if !rt.isSuccess() {
-		return "", errors.New(rt.Desc)
+		return "", call.err(ApiNoSuccessErr, rt.Desc)
+	}
+	if rt.Data == nil {
+		return "", call.err(EmptyResponseErr, "")
 	}
 
 	resp, err := utils.ConvertJsonStringToBean[RegisterFeatureResponse](*rt.Data)
@@ -72,24 +116,38 @@ func (c *Client) RegisterFeature(ctx *dgctx.DgContext, req *RegisterFeatureReque
 	}
 
 	if resp.Status == featureFailStatus || resp.FeatureId == "" {
-		return "", errors.New("注册失败")
+		return "", call.err(OperationFailedErr, "注册失败")
 	}
 
 	return resp.FeatureId, nil
 }
 
-func (c *Client) UpdateFeature(ctx *dgctx.DgContext, req *UpdateFeatureRequest) error {
-	params, header := c.buildFeatureParamsAndHeader(ctx)
-	url := c.Config.Host + "/res/feature/v1/update?" + utils.FormUrlEncodedParams(params)
+func (c *Client) UpdateFeature(ctx *dgctx.DgContext, req *UpdateFeatureRequest) (err error) {
+	call, err := c.startFeatureCall(ctx, updateFeaturePath)
+	if err != nil {
+		return err
+	}
+	defer func() { err = call.end(err) }()
+
+	params, header, err := c.buildFeatureParamsAndHeader(ctx)
+	if err != nil {
+		return err
+	}
+	url := c.Config.ServiceHost(ServiceFeature) + updateFeaturePath + utils.FormUrlEncodedParams(params)
+	maps.Copy(header, call.headers())
 	dghttp.SetHttpClient(ctx, dghttp.Client11)
 	defer dghttp.SetHttpClient(ctx, nil)
 	rt, err := dghttp.DoPostJsonToStruct[FeatureResult[string]](ctx, url, req, header)
 	if err != nil {
 		return err
 	}
+	call.code, call.requestId = rt.Code, rt.Sid
 
 	if !rt.isSuccess() {
-		return errors.New(rt.Desc)
+		return call.err(ApiNoSuccessErr, rt.Desc)
+	}
+	if rt.Data == nil {
+		return call.err(EmptyResponseErr, "")
 	}
 
 	resp, err := utils.ConvertJsonStringToBean[UpdateFeatureResponse](*rt.Data)
@@ -98,59 +156,156 @@ func (c *Client) UpdateFeature(ctx *dgctx.DgContext, req *UpdateFeatureRequest)
 	}
 
 	if resp.Status == featureFailStatus {
-		return errors.New("更新失败")
+		return call.err(OperationFailedErr, "更新失败")
 	}
 
 	return nil
 }
 
+// DeleteFeature 返回删除失败的声纹，没有时返回空切片；某一批调用出错时该批全部计入
+//
+// Deprecated: 使用 DeleteFeatures 获取错误信息和失败原因
 func (c *Client) DeleteFeature(ctx *dgctx.DgContext, featureIds []string) []string {
-	req := map[string]any{"feature_ids": featureIds}
-	params, header := c.buildFeatureParamsAndHeader(ctx)
-	url := c.Config.Host + "/res/feature/v1/delete?" + utils.FormUrlEncodedParams(params)
+	result, err := c.DeleteFeatures(ctx, featureIds)
+	if err != nil {
+		dglogger.Errorf(ctx, "DeleteFeature err: %v", err)
+	}
+
+	return result.FailedIds()
+}
+
+// DeleteFeatures 删除声纹，超过单次请求上限时分批删除；某一批调用失败时该批全部计入 Failed，并返回合并后的 error
+func (c *Client) DeleteFeatures(ctx *dgctx.DgContext, featureIds []string) (*DeleteFeatureResult, error) {
+	result := &DeleteFeatureResult{}
+	var errs []error
+
+	uniqueIds := make([]string, 0, len(featureIds))
+	seen := map[string]bool{}
+	for _, featureId := range featureIds {
+		if featureId == "" || seen[featureId] {
+			continue
+		}
+		seen[featureId] = true
+		uniqueIds = append(uniqueIds, featureId)
+	}
+
+	for begin := 0; begin < len(uniqueIds); begin += maxDeleteFeatureBatchSize {
+		batch := uniqueIds[begin:min(begin+maxDeleteFeatureBatchSize, len(uniqueIds))]
+		resp, rt, err := postFeatureResult[DeleteFeatureResponse](c, ctx, deleteFeaturePath, &DeleteFeatureRequest{FeatureIds: batch})
+		if err != nil {
+			dglogger.Errorf(ctx, "DeleteFeatures batch[%d, %d) err: %v", begin, begin+len(batch), err)
+			errs = append(errs, err)
+			var e *Error
+			if !errors.As(err, &e) {
+				e = newError(ApiFeature, "delete", err)
+			}
+			for _, featureId := range batch {
+				result.Failed = append(result.Failed, &DeleteFeatureFailure{FeatureId: featureId, Reason: e.Message, Err: e})
+			}
+			continue
+		}
+
+		failedIds := splitFeatureIds(resp.DelFailIds)
+		// 讯飞只返回失败的 id，不返回单个 id 的原因，以本次响应的 desc 作为原因
+		reason := rt.Desc
+		if reason == "" {
+			reason = "删除失败"
+		}
+		for _, featureId := range batch {
+			if !failedIds[featureId] {
+				result.DeletedIds = append(result.DeletedIds, featureId)
+				continue
+			}
+			result.Failed = append(result.Failed, &DeleteFeatureFailure{
+				FeatureId: featureId,
+				Reason:    reason,
+				Err:       &Error{Service: ServiceFeature, Op: "delete", Code: rt.Code, Message: reason, RequestId: rt.Sid, Err: OperationFailedErr},
+			})
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+func splitFeatureIds(ids string) map[string]bool {
+	idMap := map[string]bool{}
+	if ids == "" {
+		return idMap
+	}
+
+	for _, id := range strings.Split(ids, ";") {
+		idMap[strings.TrimSpace(id)] = true
+	}
+
+	return idMap
+}
+
+// postFeatureResult 同时返回讯飞的原始响应，用于读取业务码和 sid
+func postFeatureResult[T any](c *Client, ctx *dgctx.DgContext, path string, req any) (_ *T, _ *FeatureResult[string], err error) {
+	call, err := c.startFeatureCall(ctx, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { err = call.end(err) }()
+
+	params, header, err := c.buildFeatureParamsAndHeader(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	url := c.Config.ServiceHost(ServiceFeature) + path + utils.FormUrlEncodedParams(params)
+	maps.Copy(header, call.headers())
 	dghttp.SetHttpClient(ctx, dghttp.Client11)
 	defer dghttp.SetHttpClient(ctx, nil)
 	rt, err := dghttp.DoPostJsonToStruct[FeatureResult[string]](ctx, url, req, header)
 	if err != nil {
-		return featureIds
+		return nil, nil, err
 	}
+	call.code, call.requestId = rt.Code, rt.Sid
 
 	if !rt.isSuccess() {
-		return featureIds
+		return nil, rt, call.err(ApiNoSuccessErr, rt.Desc)
 	}
 
-	resp, err := utils.ConvertJsonStringToBean[DeleteFeatureResponse](*rt.Data)
-	if err != nil {
-		return featureIds
+	if rt.Data == nil {
+		return nil, rt, call.err(EmptyResponseErr, "")
 	}
 
-	if resp.DelFailIds == "" {
-		return []string{}
-	}
+	data, err := utils.ConvertJsonStringToBean[T](*rt.Data)
+	return data, rt, err
+}
 
-	return strings.Split(resp.DelFailIds, ";")
+// startFeatureCall 声纹接口共用 ApiFeature 限流，错误中的 op 取 path 中的接口名，如 register
+func (c *Client) startFeatureCall(ctx *dgctx.DgContext, path string) (*apiCall, error) {
+	op := strings.TrimSuffix(strings.TrimPrefix(path, featurePathPrefix), "?")
+	return c.startCallOp(ctx, ApiFeature, op)
 }
 
-func (c *Client) buildFeatureParamsAndHeader(ctx *dgctx.DgContext) ([]*model.KeyValuePair[string, any], map[string]string) {
+// buildFeatureParamsAndHeader 需要在取得 ApiFeature 的限流名额之后调用，避免排队导致签名时间过期
+func (c *Client) buildFeatureParamsAndHeader(ctx *dgctx.DgContext) ([]*model.KeyValuePair[string, any], map[string]string, error) {
+	creds, err := c.credentials()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	params := []*model.KeyValuePair[string, any]{
 		{
 			Key:   "accessKeyId",
-			Value: c.Config.AccessKeyId,
+			Value: creds.AccessKeyId,
 		},
 		{
 			Key:   "dateTime",
-			Value: getDateTimeString(),
+			Value: c.getDateTimeString(),
 		},
 		{
 			Key:   "signatureRandom",
-			Value: uuid.NewString(),
+			Value: c.randomId(),
 		},
 	}
 
 	header := map[string]string{
-		"signature": c.GenerateSignature(params),
-		"x-traceid": ctx.TraceId,
+		"signature":   creds.sign(asrStringToSign(params)),
+		TraceIdHeader: ctx.TraceId,
 	}
 
-	return params, header
+	return params, header, nil
 }
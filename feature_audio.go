@@ -0,0 +1,195 @@
+package iflytek
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dglogger "github.com/darwinOrg/go-logger"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+const (
+	featureSampleRate    = 16000
+	featureChannels      = 1
+	featureBitsPerSample = 16
+
+	defaultFeatureMinSpeechDuration = 3 * time.Second
+	maxFeatureAudioSize             = 10 * 1024 * 1024
+
+	speechFrameMillis = 20
+	speechRmsLevel    = 500
+)
+
+var InvalidFeatureAudioErr = errors.New("invalid feature audio")
+
+type RegisterFeatureOptions struct {
+	Uid               string        // 注册时关联的用户标识
+	AudioType         AudioType     // 指定音频类型时不再识别格式，speex 和 opus-wb 无法识别，需显式指定
+	MinSpeechDuration time.Duration // 有效语音的最短时长，默认3秒
+}
+
+// FeatureAudio 校验后可直接上传的声纹音频
+type FeatureAudio struct {
+	AudioType      AudioType `json:"audioType"`
+	Data           []byte    `json:"-"`
+	DurationMillis int64     `json:"durationMillis"`
+	SpeechMillis   int64     `json:"speechMillis"` // 能量检测得到的有效语音时长，压缩格式无法检测时与 DurationMillis 相同
+}
+
+// RegisterFeatureFromFile 读取音频文件，校验并转换格式后注册声纹
+func (c *Client) RegisterFeatureFromFile(ctx *dgctx.DgContext, filePath string, opts *RegisterFeatureOptions) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	return c.RegisterFeatureFromReader(ctx, file, opts)
+}
+
+// RegisterFeatureFromReader 读取音频，校验并转换格式后注册声纹
+func (c *Client) RegisterFeatureFromReader(ctx *dgctx.DgContext, r io.Reader, opts *RegisterFeatureOptions) (string, error) {
+	if opts == nil {
+		opts = &RegisterFeatureOptions{}
+	}
+
+	audio, err := ReadFeatureAudio(r, opts)
+	if err != nil {
+		dglogger.Errorf(ctx, "RegisterFeatureFromReader validate audio err: %v", err)
+		return "", err
+	}
+
+	return c.RegisterFeature(ctx, &RegisterFeatureRequest{
+		AudioData: base64.StdEncoding.EncodeToString(audio.Data),
+		AudioType: audio.AudioType,
+		Uid:       opts.Uid,
+	})
+}
+
+// ReadFeatureAudio 识别 wav、ogg 和 raw 格式并在本地校验采样率、声道数和有效语音时长，wav 会转换为 raw
+func ReadFeatureAudio(r io.Reader, opts *RegisterFeatureOptions) (*FeatureAudio, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxFeatureAudioSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: empty audio", InvalidFeatureAudioErr)
+	}
+	if len(data) > maxFeatureAudioSize {
+		return nil, fmt.Errorf("%w: audio size exceeds %d bytes", InvalidFeatureAudioErr, maxFeatureAudioSize)
+	}
+
+	minSpeech := opts.MinSpeechDuration
+	if minSpeech <= 0 {
+		minSpeech = defaultFeatureMinSpeechDuration
+	}
+
+	var audio *FeatureAudio
+	switch {
+	case opts.AudioType == AudioTypeSpeex || opts.AudioType == AudioTypeOpusWb:
+		return &FeatureAudio{AudioType: opts.AudioType, Data: data}, nil
+	case opts.AudioType == AudioTypeOpusOgg || opts.AudioType == "" && isOgg(data):
+		audio, err = readOggFeatureAudio(data)
+	case opts.AudioType == "" && IsWav(data):
+		audio, err = readWavFeatureAudio(data)
+	case opts.AudioType == "" || opts.AudioType == AudioTypeRaw:
+		audio, err = readRawFeatureAudio(data)
+	default:
+		return nil, fmt.Errorf("%w: unsupported audio type %s", InvalidFeatureAudioErr, opts.AudioType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if audio.SpeechMillis < minSpeech.Milliseconds() {
+		return nil, fmt.Errorf("%w: speech duration %dms is shorter than %dms", InvalidFeatureAudioErr, audio.SpeechMillis, minSpeech.Milliseconds())
+	}
+
+	return audio, nil
+}
+
+func readWavFeatureAudio(data []byte) (*FeatureAudio, error) {
+	header, pcm, err := ParseWav(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", InvalidFeatureAudioErr, err)
+	}
+	if !header.IsPcm() {
+		return nil, fmt.Errorf("%w: wav audio format %d is not pcm", InvalidFeatureAudioErr, header.AudioFormat)
+	}
+	if header.SampleRate != featureSampleRate {
+		return nil, fmt.Errorf("%w: sample rate %dHz, expected %dHz", InvalidFeatureAudioErr, header.SampleRate, featureSampleRate)
+	}
+	if header.Channels != featureChannels {
+		return nil, fmt.Errorf("%w: %d channels, expected mono", InvalidFeatureAudioErr, header.Channels)
+	}
+	if header.BitsPerSample != featureBitsPerSample {
+		return nil, fmt.Errorf("%w: %d bits per sample, expected %d", InvalidFeatureAudioErr, header.BitsPerSample, featureBitsPerSample)
+	}
+
+	return readRawFeatureAudio(pcm)
+}
+
+// readRawFeatureAudio raw 按 16k 采样、16bit、单声道处理
+func readRawFeatureAudio(pcm []byte) (*FeatureAudio, error) {
+	if len(pcm)%2 != 0 {
+		return nil, fmt.Errorf("%w: raw audio length %d is not a multiple of 2", InvalidFeatureAudioErr, len(pcm))
+	}
+
+	bytesPerMillis := featureSampleRate * featureBitsPerSample / 8 / 1000
+	return &FeatureAudio{
+		AudioType:      AudioTypeRaw,
+		Data:           pcm,
+		DurationMillis: int64(len(pcm) / bytesPerMillis),
+		SpeechMillis:   speechMillis(pcm, featureSampleRate),
+	}, nil
+}
+
+func readOggFeatureAudio(data []byte) (*FeatureAudio, error) {
+	head, durationMillis, err := ProbeOggOpus(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", InvalidFeatureAudioErr, err)
+	}
+	if head.Channels != featureChannels {
+		return nil, fmt.Errorf("%w: %d channels, expected mono", InvalidFeatureAudioErr, head.Channels)
+	}
+	if head.InputSampleRate != 0 && head.InputSampleRate != featureSampleRate {
+		return nil, fmt.Errorf("%w: input sample rate %dHz, expected %dHz", InvalidFeatureAudioErr, head.InputSampleRate, featureSampleRate)
+	}
+
+	return &FeatureAudio{
+		AudioType:      AudioTypeOpusOgg,
+		Data:           data,
+		DurationMillis: durationMillis,
+		SpeechMillis:   durationMillis,
+	}, nil
+}
+
+// speechMillis 以 20ms 为一帧，统计均方根能量超过阈值的帧的总时长
+func speechMillis(pcm []byte, sampleRate int) int64 {
+	frameSamples := sampleRate * speechFrameMillis / 1000
+	samples := len(pcm) / 2
+	var speechFrames int64
+
+	for begin := 0; begin+frameSamples <= samples; begin += frameSamples {
+		var sum float64
+		for i := begin; i < begin+frameSamples; i++ {
+			sample := float64(int16(binary.LittleEndian.Uint16(pcm[i*2:])))
+			sum += sample * sample
+		}
+		if math.Sqrt(sum/float64(frameSamples)) >= speechRmsLevel {
+			speechFrames++
+		}
+	}
+
+	return speechFrames * speechFrameMillis
+}
+
+func isOgg(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(oggCapturePattern))
+}
@@ -0,0 +1,57 @@
+package iflytek_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadFeatureAudio(t *testing.T) {
+	audio, err := dgkdxf.ReadFeatureAudio(bytes.NewReader(testToneWav(16000, 1, 4000)), &dgkdxf.RegisterFeatureOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audio.AudioType != dgkdxf.AudioTypeRaw || len(audio.Data) != 16000*2*4 || audio.SpeechMillis != 4000 {
+		t.Fatalf("audio: %s, %d bytes, speech %dms", audio.AudioType, len(audio.Data), audio.SpeechMillis)
+	}
+
+	_, err = dgkdxf.ReadFeatureAudio(bytes.NewReader(testToneWav(8000, 1, 4000)), &dgkdxf.RegisterFeatureOptions{})
+	if !errors.Is(err, dgkdxf.InvalidFeatureAudioErr) || !strings.Contains(err.Error(), "sample rate 8000Hz") {
+		t.Fatalf("8k err: %v", err)
+	}
+
+	_, err = dgkdxf.ReadFeatureAudio(bytes.NewReader(testToneWav(16000, 2, 4000)), &dgkdxf.RegisterFeatureOptions{})
+	if !errors.Is(err, dgkdxf.InvalidFeatureAudioErr) || !strings.Contains(err.Error(), "2 channels") {
+		t.Fatalf("stereo err: %v", err)
+	}
+
+	_, err = dgkdxf.ReadFeatureAudio(bytes.NewReader(testWav(16000, 16000*5)), &dgkdxf.RegisterFeatureOptions{MinSpeechDuration: time.Second})
+	if !errors.Is(err, dgkdxf.InvalidFeatureAudioErr) || !strings.Contains(err.Error(), "speech duration 0ms") {
+		t.Fatalf("silence err: %v", err)
+	}
+}
+
+// testToneWav 生成 16bit 的 440Hz 正弦波 wav
+func testToneWav(sampleRate int, channels int, millis int) []byte {
+	samples := sampleRate * millis / 1000
+	pcm := make([]byte, samples*channels*2)
+	for i := 0; i < samples; i++ {
+		sample := int16(8000 * math.Sin(2*math.Pi*440*float64(i)/float64(sampleRate)))
+		for ch := 0; ch < channels; ch++ {
+			binary.LittleEndian.PutUint16(pcm[(i*channels+ch)*2:], uint16(sample))
+		}
+	}
+
+	header := testWav(sampleRate, 0)
+	binary.LittleEndian.PutUint16(header[22:], uint16(channels))
+	binary.LittleEndian.PutUint32(header[28:], uint32(sampleRate*channels*2))
+	binary.LittleEndian.PutUint16(header[32:], uint16(channels*2))
+	binary.LittleEndian.PutUint32(header[4:], uint32(36+len(pcm)))
+	binary.LittleEndian.PutUint32(header[40:], uint32(len(pcm)))
+	return append(header, pcm...)
+}
@@ -0,0 +1,323 @@
+package iflytek
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dglogger "github.com/darwinOrg/go-logger"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const featureIdsSeparator = ","
+
+type FeatureRecord struct {
+	Uid       string    `json:"uid"`
+	FeatureId string    `json:"featureId"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// FeatureStore 保存 uid 与 featureId 的对应关系，Get 在没有记录时返回 nil, nil
+type FeatureStore interface {
+	Get(uid string) (*FeatureRecord, error)
+	Put(record *FeatureRecord) error
+	Delete(uid string) error
+	List() ([]*FeatureRecord, error)
+}
+
+type MemoryFeatureStore struct {
+	mu      sync.RWMutex
+	records map[string]*FeatureRecord
+}
+
+func NewMemoryFeatureStore() *MemoryFeatureStore {
+	return &MemoryFeatureStore{records: map[string]*FeatureRecord{}}
+}
+
+func (s *MemoryFeatureStore) Get(uid string) (*FeatureRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.records[uid]
+	if !ok {
+		return nil, nil
+	}
+	copied := *record
+
+	return &copied, nil
+}
+
+func (s *MemoryFeatureStore) Put(record *FeatureRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *record
+	s.records[record.Uid] = &copied
+
+	return nil
+}
+
+func (s *MemoryFeatureStore) Delete(uid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, uid)
+
+	return nil
+}
+
+func (s *MemoryFeatureStore) List() ([]*FeatureRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make([]*FeatureRecord, 0, len(s.records))
+	for _, record := range s.records {
+		copied := *record
+		records = append(records, &copied)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Uid < records[j].Uid })
+
+	return records, nil
+}
+
+// FileFeatureStore 将全部记录以 json 保存在 Path 文件中，每次写入时整体替换
+type FileFeatureStore struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (s *FileFeatureStore) Get(uid string) (*FeatureRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return records[uid], nil
+}
+
+func (s *FileFeatureStore) Put(record *FeatureRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.load()
+	if err != nil {
+		return err
+	}
+	copied := *record
+	records[record.Uid] = &copied
+
+	return s.save(records)
+}
+
+func (s *FileFeatureStore) Delete(uid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := records[uid]; !ok {
+		return nil
+	}
+	delete(records, uid)
+
+	return s.save(records)
+}
+
+func (s *FileFeatureStore) List() ([]*FeatureRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*FeatureRecord, 0, len(records))
+	for _, record := range records {
+		list = append(list, record)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Uid < list[j].Uid })
+
+	return list, nil
+}
+
+func (s *FileFeatureStore) load() (map[string]*FeatureRecord, error) {
+	records := map[string]*FeatureRecord{}
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return records, nil
+	}
+
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (s *FileFeatureStore) save(records map[string]*FeatureRecord) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.Path, data)
+}
+
+// FeatureRegistry 维护本地 uid 与声纹 featureId 的对应关系，注册、更新和删除都经由它完成
+type FeatureRegistry struct {
+	client *Client
+	store  FeatureStore
+	mu     sync.Mutex
+}
+
+func NewFeatureRegistry(client *Client, store FeatureStore) *FeatureRegistry {
+	return &FeatureRegistry{client: client, store: store}
+}
+
+// Upsert uid 已有声纹时更新，否则注册新声纹；audioData 为 base64 编码的音频。
+// 服务端已不存在本地记录的声纹时更新会失败，确认后可先 Forget 再重新 Upsert
+func (r *FeatureRegistry) Upsert(ctx *dgctx.DgContext, uid string, audioData string, audioType AudioType) (*FeatureRecord, error) {
+	if uid == "" {
+		return nil, errors.New("uid is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, err := r.store.Get(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if record != nil {
+		// 更新失败时不重新注册，避免产生孤儿和重复声纹
+		if err := r.client.UpdateFeature(ctx, &UpdateFeatureRequest{FeatureId: record.FeatureId, AudioData: audioData, AudioType: audioType}); err != nil {
+			return nil, err
+		}
+		record.UpdatedAt = now
+		return record, r.store.Put(record)
+	}
+
+	featureId, err := r.client.RegisterFeature(ctx, &RegisterFeatureRequest{AudioData: audioData, AudioType: audioType, Uid: uid})
+	if err != nil {
+		return nil, err
+	}
+
+	record = &FeatureRecord{Uid: uid, FeatureId: featureId, CreatedAt: now, UpdatedAt: now}
+	if err := r.store.Put(record); err != nil {
+		// 本地保存失败时删除刚注册的声纹，避免产生孤儿
+		if _, delErr := r.client.DeleteFeatures(ctx, []string{featureId}); delErr != nil {
+			dglogger.Errorf(ctx, "FeatureRegistry delete featureId[%s] after store err: %v", featureId, delErr)
+		}
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// UpsertFromReader 读取并校验音频后执行 Upsert
+func (r *FeatureRegistry) UpsertFromReader(ctx *dgctx.DgContext, uid string, reader io.Reader, opts *RegisterFeatureOptions) (*FeatureRecord, error) {
+	if opts == nil {
+		opts = &RegisterFeatureOptions{}
+	}
+
+	audio, err := ReadFeatureAudio(reader, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Upsert(ctx, uid, base64.StdEncoding.EncodeToString(audio.Data), audio.AudioType)
+}
+
+// Delete 删除 uid 的声纹，服务端已不存在时同样移除本地记录
+func (r *FeatureRegistry) Delete(ctx *dgctx.DgContext, uid string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, err := r.store.Get(uid)
+	if err != nil || record == nil {
+		return err
+	}
+
+	result, err := r.client.DeleteFeatures(ctx, []string{record.FeatureId})
+	if err != nil {
+		return err
+	}
+	if len(result.Failed) > 0 {
+		return result.Failed[0].Err
+	}
+
+	return r.store.Delete(uid)
+}
+
+// Forget 只移除本地记录，不调用服务端，用于清理服务端已不存在的声纹
+func (r *FeatureRegistry) Forget(uid string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.store.Delete(uid)
+}
+
+// FeatureId uid 对应的 featureId，没有时返回空字符串
+func (r *FeatureRegistry) FeatureId(uid string) (string, error) {
+	record, err := r.store.Get(uid)
+	if err != nil || record == nil {
+		return "", err
+	}
+
+	return record.FeatureId, nil
+}
+
+// Uid featureId 对应的 uid，没有时返回空字符串
+func (r *FeatureRegistry) Uid(featureId string) (string, error) {
+	records, err := r.store.List()
+	if err != nil {
+		return "", err
+	}
+
+	for _, record := range records {
+		if record.FeatureId == featureId {
+			return record.Uid, nil
+		}
+	}
+
+	return "", nil
+}
+
+// FeatureIds 返回 uids 对应的 featureId，以逗号拼接，可直接用于 AstParamConfig.FeatureIds；没有声纹的 uid 会被忽略
+func (r *FeatureRegistry) FeatureIds(uids []string) (string, error) {
+	featureIds := make([]string, 0, len(uids))
+	seen := map[string]bool{}
+	for _, uid := range uids {
+		featureId, err := r.FeatureId(uid)
+		if err != nil {
+			return "", err
+		}
+		if featureId == "" || seen[featureId] {
+			continue
+		}
+		seen[featureId] = true
+		featureIds = append(featureIds, featureId)
+	}
+
+	return strings.Join(featureIds, featureIdsSeparator), nil
+}
@@ -0,0 +1,98 @@
+package iflytek_test
+
+import (
+	"encoding/json"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFeatureRegistry(t *testing.T) {
+	features := map[string]string{}
+	next := 0
+	throttled := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		if throttled {
+			_ = json.NewEncoder(w).Encode(&dgkdxf.FeatureResult[string]{Code: "100002", Desc: "too many requests"})
+			return
+		}
+
+		var data any
+		switch r.URL.Path {
+		case "/res/feature/v1/register":
+			next++
+			featureId := "f" + strconv.Itoa(next)
+			features[featureId] = req["uid"].(string)
+			data = &dgkdxf.RegisterFeatureResponse{FeatureId: featureId}
+		case "/res/feature/v1/update":
+			if _, ok := features[req["feature_id"].(string)]; !ok {
+				_ = json.NewEncoder(w).Encode(&dgkdxf.FeatureResult[string]{Code: "100404", Desc: "feature not exist"})
+				return
+			}
+			data = &dgkdxf.UpdateFeatureResponse{}
+		case "/res/feature/v1/delete":
+			for _, featureId := range req["feature_ids"].([]any) {
+				delete(features, featureId.(string))
+			}
+			data = &dgkdxf.DeleteFeatureResponse{}
+		}
+
+		dataBytes, _ := json.Marshal(data)
+		dataStr := string(dataBytes)
+		_ = json.NewEncoder(w).Encode(&dgkdxf.FeatureResult[string]{Code: "000000", Data: &dataStr})
+	}))
+	defer server.Close()
+
+	ctx := &dgctx.DgContext{TraceId: uuid.NewString()}
+	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{Host: server.URL})
+	store := &dgkdxf.FileFeatureStore{Path: filepath.Join(t.TempDir(), "features.json")}
+	registry := dgkdxf.NewFeatureRegistry(client, store)
+
+	for _, uid := range []string{"u1", "u2", "u1"} {
+		if _, err := registry.Upsert(ctx, uid, "AAAA", dgkdxf.AudioTypeRaw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if featureIds, _ := registry.FeatureIds([]string{"u2", "u1", "u3"}); featureIds != "f2,f1" {
+		t.Fatalf("featureIds: %s", featureIds)
+	}
+
+	if err := registry.Delete(ctx, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	records, _ := store.List()
+	var uids []string
+	for _, record := range records {
+		uids = append(uids, record.Uid)
+	}
+	if strings.Join(uids, ",") != "u2" || len(features) != 1 {
+		t.Fatalf("uids: %v, server features: %d", uids, len(features))
+	}
+
+	// 更新失败时不重新注册，Forget 之后才重新注册
+	throttled = true
+	if _, err := registry.Upsert(ctx, "u2", "AAAA", dgkdxf.AudioTypeRaw); err == nil || next != 2 {
+		t.Fatalf("throttled upsert: %v, registered: %d", err, next)
+	}
+	throttled = false
+	delete(features, "f2")
+	if _, err := registry.Upsert(ctx, "u2", "AAAA", dgkdxf.AudioTypeRaw); err == nil || next != 2 {
+		t.Fatalf("stale upsert: %v, registered: %d", err, next)
+	}
+	if err := registry.Forget("u2"); err != nil {
+		t.Fatal(err)
+	}
+	record, err := registry.Upsert(ctx, "u2", "AAAA", dgkdxf.AudioTypeRaw)
+	if err != nil || record.FeatureId != "f3" {
+		t.Fatalf("record: %+v, err: %v", record, err)
+	}
+}
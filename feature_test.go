@@ -2,10 +2,15 @@ package iflytek_test
 
 import (
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	dgctx "github.com/darwinOrg/go-common/context"
 	dgkdxf "github.com/darwinOrg/go-iflytek"
 	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strconv"
 	"testing"
 )
 
@@ -19,16 +24,8 @@ func TestRegisterFeature(t *testing.T) {
 	}
 
 	host := "https://office-api-personal-dx.iflyaisol.com"
-	appId := os.Getenv("appId")
-	accessKeyId := os.Getenv("accessKeyId")
-	accessKeySecret := os.Getenv("accessKeySecret")
 	ctx := &dgctx.DgContext{TraceId: uuid.NewString()}
-	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{
-		AppId:           appId,
-		Host:            host,
-		AccessKeyId:     accessKeyId,
-		AccessKeySecret: accessKeySecret,
-	})
+	client := newEnvClient(t, host, dgkdxf.ServiceFeature)
 
 	featureId, err := client.RegisterFeature(ctx, req)
 	if err != nil {
@@ -37,3 +34,46 @@ func TestRegisterFeature(t *testing.T) {
 		t.Log(featureId)
 	}
 }
+
+func TestDeleteFeatures(t *testing.T) {
+	var batches int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req dgkdxf.DeleteFeatureRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		batches++
+		if batches == 3 {
+			_ = json.NewEncoder(w).Encode(&dgkdxf.FeatureResult[string]{Code: "100002", Desc: "too many requests"})
+			return
+		}
+		data := `{"del_fail_ids":"f1"}`
+		_ = json.NewEncoder(w).Encode(&dgkdxf.FeatureResult[string]{Code: "000000", Data: &data})
+	}))
+	defer server.Close()
+
+	featureIds := make([]string, 0, 250)
+	for i := 0; i < 250; i++ {
+		featureIds = append(featureIds, "f"+strconv.Itoa(i))
+	}
+
+	ctx := &dgctx.DgContext{TraceId: uuid.NewString()}
+	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{Host: server.URL})
+	result, err := client.DeleteFeatures(ctx, featureIds)
+	if err == nil || batches != 3 {
+		t.Fatalf("batches: %d, err: %v", batches, err)
+	}
+	if len(result.Failed) != 51 || len(result.DeletedIds) != 199 {
+		t.Fatalf("deleted: %d, failed: %d", len(result.DeletedIds), len(result.Failed))
+	}
+	if failure := result.Failed[0]; failure.FeatureId != "f1" || failure.Reason != "删除失败" || !errors.Is(failure.Err, dgkdxf.OperationFailedErr) {
+		t.Fatalf("failed id: %+v", failure)
+	}
+	if failure := result.Failed[1]; failure.Reason != "too many requests" || failure.Err == nil || failure.Err.Code != "100002" {
+		t.Fatalf("failed batch: %+v", failure)
+	}
+	if failedIds := client.DeleteFeature(ctx, []string{"f1", "f5"}); len(failedIds) != 1 || failedIds[0] != "f1" {
+		t.Fatalf("failed ids: %v", failedIds)
+	}
+	if failedIds := client.DeleteFeature(ctx, []string{"f5"}); failedIds == nil || len(failedIds) != 0 {
+		t.Fatalf("failed ids: %v", failedIds)
+	}
+}
@@ -0,0 +1,328 @@
+package iflytek
+
+import (
+	"errors"
+	dgctx "github.com/darwinOrg/go-common/context"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TraceIdHeader 所有出站请求都携带 DgContext.TraceId，ast 连接同时使用 trackId 参数
+const TraceIdHeader = "x-traceid"
+
+// DefaultLatencyBuckets MetricsInstrumentation 默认的耗时分桶，单位毫秒
+var DefaultLatencyBuckets = []float64{50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000}
+
+// Instrumentation 接口调用和 ast 会话的观测钩子，可以在实现中对接 Prometheus 指标和 OpenTelemetry 链路
+type Instrumentation interface {
+	// StartCall 每次调用接口前执行，包括被限流拒绝的调用
+	StartCall(ctx *dgctx.DgContext, call *CallInfo) CallSpan
+	// StartAstSession ast 连接建立后执行，连接关闭时结束
+	StartAstSession(ctx *dgctx.DgContext, session *AstSessionInfo) AstSessionSpan
+}
+
+type CallInfo struct {
+	Api       Api
+	TraceId   string
+	StartTime time.Time
+}
+
+type CallSpan interface {
+	// Headers 需要注入到出站请求的请求头，如 traceparent，可以返回 nil
+	Headers() map[string]string
+	End(result *CallResult)
+}
+
+type CallResult struct {
+	Duration   time.Duration
+	StatusCode int    // http 状态码，未收到响应或无法获取时为 0
+	Code       string // 讯飞返回的业务码
+	BytesSent  int64  // 上传的字节数
+	Err        error
+}
+
+type AstSessionInfo struct {
+	TraceId   string
+	StartTime time.Time
+}
+
+type AstSessionSpan interface {
+	End(stats *AstSessionStats)
+}
+
+type AstSessionStats struct {
+	Duration       time.Duration
+	FramesSent     int64 // 通过 AstSendMessage 发送的文本和二进制消息数，直接写入 *websocket.Conn 的消息不计入
+	FramesReceived int64
+	BytesSent      int64 // 发送的消息内容字节数，不含 websocket 帧头
+	BytesReceived  int64
+}
+
+type nopInstrumentation struct{}
+
+func (nopInstrumentation) StartCall(*dgctx.DgContext, *CallInfo) CallSpan { return nopSpan{} }
+
+func (nopInstrumentation) StartAstSession(*dgctx.DgContext, *AstSessionInfo) AstSessionSpan {
+	return nopAstSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) Headers() map[string]string { return nil }
+func (nopSpan) End(*CallResult)            {}
+
+type nopAstSpan struct{}
+
+func (nopAstSpan) End(*AstSessionStats) {}
+
+func (c *Client) instrumentation() Instrumentation {
+	if c.Config.Instrumentation != nil {
+		return c.Config.Instrumentation
+	}
+
+	return nopInstrumentation{}
+}
+
+// apiCall 一次接口调用的限流名额、观测 span 和错误信息
+type apiCall struct {
+	ctx        *dgctx.DgContext
+	api        Api
+	op         string // 为空时取 api 中的接口名
+	start      time.Time
+	span       CallSpan
+	release    func()
+	statusCode int
+	code       string
+	requestId  string
+	bytesSent  int64
+}
+
+// startCall 开始观测并等待限流，限流失败时 span 已结束，返回 *Error
+func (c *Client) startCall(ctx *dgctx.DgContext, api Api) (*apiCall, error) {
+	return c.startCallOp(ctx, api, "")
+}
+
+// startCallOp 多个接口共用一个限流名时，用 op 区分错误中的接口名
+func (c *Client) startCallOp(ctx *dgctx.DgContext, api Api, op string) (*apiCall, error) {
+	call := &apiCall{ctx: ctx, api: api, op: op, start: time.Now()}
+	call.span = c.instrumentation().StartCall(ctx, &CallInfo{Api: api, TraceId: ctx.TraceId, StartTime: call.start})
+
+	release, err := c.acquire(ctx, api)
+	if err != nil {
+		return nil, call.end(err)
+	}
+	call.release = release
+
+	return call, nil
+}
+
+// headers 出站请求的 trace 请求头
+func (call *apiCall) headers() map[string]string {
+	headers := map[string]string{}
+	for key, value := range call.span.Headers() {
+		headers[key] = value
+	}
+	if call.ctx.TraceId != "" {
+		headers[TraceIdHeader] = call.ctx.TraceId
+	}
+
+	return headers
+}
+
+func (call *apiCall) setHeaders(header http.Header) {
+	for key, value := range call.headers() {
+		header.Set(key, value)
+	}
+}
+
+// detachRelease 由调用方负责归还限流名额，用于响应体需要延后读取的接口
+func (call *apiCall) detachRelease() func() {
+	release := call.release
+	call.release = nil
+
+	return release
+}
+
+// err 按本次调用的状态码、业务码和 requestId 生成 *Error
+func (call *apiCall) err(cause error, message string) *Error {
+	e := newError(call.api, call.op, cause)
+	e.StatusCode, e.Code, e.Message, e.RequestId = call.statusCode, call.code, message, call.requestId
+	e.Retryable = e.retryable()
+
+	return e
+}
+
+// statusErr 非 200 响应，读取并关闭响应体中的错误信息
+func (call *apiCall) statusErr(response *http.Response) *Error {
+	code, message, requestId := readErrorBody(response)
+	if code != "" {
+		call.code = code
+	}
+	if requestId != "" {
+		call.requestId = requestId
+	}
+
+	return call.err(HttpStatusErr, message)
+}
+
+// end 归还限流名额并结束 span，返回包装为 *Error 的 err
+func (call *apiCall) end(err error) error {
+	var e *Error
+	if err != nil && !errors.As(err, &e) {
+		err = call.err(err, "")
+	}
+
+	if call.release != nil {
+		call.release()
+	}
+	call.span.End(&CallResult{
+		Duration:   time.Since(call.start),
+		StatusCode: call.statusCode,
+		Code:       call.code,
+		BytesSent:  call.bytesSent,
+		Err:        err,
+	})
+
+	return err
+}
+
+// MetricsInstrumentation 进程内的指标统计，按接口统计请求数、耗时分布和错误码，
+// 不依赖 Prometheus 客户端，可以定期读取 Snapshot 导出；直接对接 Prometheus 和 OpenTelemetry 见 prominstrument 和 otelinstrument 子包
+type MetricsInstrumentation struct {
+	buckets []float64
+	mu      sync.Mutex
+	apis    map[Api]*ApiMetrics
+	ast     AstMetrics
+}
+
+type ApiMetrics struct {
+	Api            Api              `json:"api"`
+	Requests       int64            `json:"requests"`
+	Errors         int64            `json:"errors"`
+	Codes          map[string]int64 `json:"codes"`          // 按讯飞业务码或 http 状态码统计的失败数
+	LatencyBuckets []int64          `json:"latencyBuckets"` // 落在各耗时分桶内的次数，最后一个为 +Inf
+	LatencySumMs   float64          `json:"latencySumMs"`
+	BytesSent      int64            `json:"bytesSent"`
+}
+
+type AstMetrics struct {
+	Sessions          int64 `json:"sessions"`
+	ActiveSessions    int64 `json:"activeSessions"`
+	DurationSumMillis int64 `json:"durationSumMillis"`
+	FramesSent        int64 `json:"framesSent"`
+	FramesReceived    int64 `json:"framesReceived"`
+	BytesSent         int64 `json:"bytesSent"`
+	BytesReceived     int64 `json:"bytesReceived"`
+}
+
+type MetricsSnapshot struct {
+	LatencyBuckets []float64     `json:"latencyBuckets"`
+	Apis           []*ApiMetrics `json:"apis"`
+	Ast            AstMetrics    `json:"ast"`
+}
+
+// NewMetricsInstrumentation buckets 为耗时分桶的上界，单位毫秒，为空时使用 DefaultLatencyBuckets
+func NewMetricsInstrumentation(buckets []float64) *MetricsInstrumentation {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+
+	return &MetricsInstrumentation{buckets: buckets, apis: map[Api]*ApiMetrics{}}
+}
+
+type metricsCallSpan struct {
+	m   *MetricsInstrumentation
+	api Api
+}
+
+func (m *MetricsInstrumentation) StartCall(_ *dgctx.DgContext, call *CallInfo) CallSpan {
+	return &metricsCallSpan{m: m, api: call.Api}
+}
+
+func (s *metricsCallSpan) Headers() map[string]string { return nil }
+
+func (s *metricsCallSpan) End(result *CallResult) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	metrics, ok := s.m.apis[s.api]
+	if !ok {
+		metrics = &ApiMetrics{Api: s.api, Codes: map[string]int64{}, LatencyBuckets: make([]int64, len(s.m.buckets)+1)}
+		s.m.apis[s.api] = metrics
+	}
+
+	metrics.Requests++
+	metrics.BytesSent += result.BytesSent
+	millis := float64(result.Duration) / float64(time.Millisecond)
+	metrics.LatencySumMs += millis
+	metrics.LatencyBuckets[sort.SearchFloat64s(s.m.buckets, millis)]++
+	if result.Err != nil {
+		metrics.Errors++
+		metrics.Codes[result.ErrorCode()]++
+	}
+}
+
+// ErrorCode 失败调用的讯飞业务码，没有时为 http_状态码，都没有时为 error；成功时为空
+func (r *CallResult) ErrorCode() string {
+	switch {
+	case r.Err == nil:
+		return ""
+	case r.Code != "" && r.Code != apiSuccessCode:
+		return r.Code
+	case r.StatusCode != 0 && r.StatusCode != http.StatusOK:
+		return "http_" + strconv.Itoa(r.StatusCode)
+	}
+
+	return "error"
+}
+
+type metricsAstSpan struct {
+	m *MetricsInstrumentation
+}
+
+func (m *MetricsInstrumentation) StartAstSession(*dgctx.DgContext, *AstSessionInfo) AstSessionSpan {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ast.Sessions++
+	m.ast.ActiveSessions++
+
+	return &metricsAstSpan{m: m}
+}
+
+func (s *metricsAstSpan) End(stats *AstSessionStats) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	s.m.ast.ActiveSessions--
+	s.m.ast.DurationSumMillis += stats.Duration.Milliseconds()
+	s.m.ast.FramesSent += stats.FramesSent
+	s.m.ast.FramesReceived += stats.FramesReceived
+	s.m.ast.BytesSent += stats.BytesSent
+	s.m.ast.BytesReceived += stats.BytesReceived
+}
+
+// Snapshot 当前指标的副本，接口按名称排序
+func (m *MetricsInstrumentation) Snapshot() *MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := &MetricsSnapshot{LatencyBuckets: append([]float64{}, m.buckets...), Ast: m.ast}
+	for _, metrics := range m.apis {
+		copied := *metrics
+		copied.Codes = make(map[string]int64, len(metrics.Codes))
+		for code, count := range metrics.Codes {
+			copied.Codes[code] = count
+		}
+		copied.LatencyBuckets = append([]int64{}, metrics.LatencyBuckets...)
+		snapshot.Apis = append(snapshot.Apis, &copied)
+	}
+	sort.Slice(snapshot.Apis, func(i, j int) bool { return snapshot.Apis[i].Api < snapshot.Apis[j].Api })
+
+	return snapshot
+}
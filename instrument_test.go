@@ -0,0 +1,118 @@
+package iflytek_test
+
+import (
+	dgctx "github.com/darwinOrg/go-common/context"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMetricsInstrumentation(t *testing.T) {
+	var mu sync.Mutex
+	traceIds := map[string]string{}
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		traceIds[r.URL.Path] = r.Header.Get(dgkdxf.TraceIdHeader)
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/cc/callout":
+			_, _ = w.Write([]byte(`{"requestId":"r1"}`))
+		case "/cc/online":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			cn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer cn.Close()
+			_ = cn.WriteMessage(websocket.TextMessage, []byte(`{"action":"started"}`))
+			for {
+				if _, _, err := cn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}
+	}))
+	defer server.Close()
+
+	metrics := dgkdxf.NewMetricsInstrumentation([]float64{1000})
+	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{
+		AppId:           "app",
+		AccessKeyId:     "ak",
+		AccessKeySecret: "sk",
+		CcHost:          server.URL,
+		AstHost:         "ws://" + strings.TrimPrefix(server.URL, "http://"),
+		Instrumentation: metrics,
+	})
+	ctx := &dgctx.DgContext{TraceId: "t1"}
+
+	if _, err := client.Callout(ctx, &dgkdxf.CalloutReq{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Online(ctx, &dgkdxf.OnlineReq{}); err == nil {
+		t.Fatal("online should fail")
+	}
+
+	cn, err := client.AstConnect(ctx, &dgkdxf.AstParamConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dgkdxf.AstWriteStarted(ctx, cn); err != nil {
+		t.Fatal(err)
+	}
+	if err := dgkdxf.AstSendMessage(cn, websocket.BinaryMessage, make([]byte, 70000)); err != nil {
+		t.Fatal(err)
+	}
+	// 直接读写连接的消息不计数
+	if err := cn.WriteMessage(websocket.BinaryMessage, make([]byte, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := dgkdxf.AstReceiveMessage(cn); err != nil {
+		t.Fatal(err)
+	}
+	if active := metrics.Snapshot().Ast.ActiveSessions; active != 1 {
+		t.Fatalf("active sessions: %d", active)
+	}
+	_ = cn.Close()
+
+	mu.Lock()
+	for path, traceId := range traceIds {
+		if traceId != "t1" {
+			t.Fatalf("%s trace id: %q", path, traceId)
+		}
+	}
+	mu.Unlock()
+
+	snapshot := metrics.Snapshot()
+	apis := map[dgkdxf.Api]*dgkdxf.ApiMetrics{}
+	for _, m := range snapshot.Apis {
+		apis[m.Api] = m
+	}
+	if m := apis["cc.callout"]; m == nil || m.Requests != 1 || m.Errors != 0 || m.LatencyBuckets[0] != 1 {
+		t.Fatalf("callout metrics: %+v", m)
+	}
+	if m := apis["cc.online"]; m == nil || m.Errors != 1 || m.Codes["http_500"] != 1 {
+		t.Fatalf("online metrics: %+v", m)
+	}
+	if m := apis[dgkdxf.ApiAstConnect]; m == nil || m.Requests != 1 || m.Errors != 0 {
+		t.Fatalf("ast connect metrics: %+v", m)
+	}
+	ast := snapshot.Ast
+	if ast.Sessions != 1 || ast.ActiveSessions != 0 || ast.FramesSent != 2 || ast.BytesSent != int64(len(`{"action":"started"}`)+70000) ||
+		ast.FramesReceived != 1 || ast.BytesReceived != int64(len(`{"action":"started"}`)) {
+		t.Fatalf("ast metrics: %+v", ast)
+	}
+
+	// 连接关闭后归还并发名额
+	for _, stats := range client.RateLimitStats() {
+		if stats.InFlight != 0 {
+			t.Fatalf("in flight: %+v", stats)
+		}
+	}
+}
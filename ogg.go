@@ -0,0 +1,300 @@
+package iflytek
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	OggHeaderTypeContinued byte = 0x01
+	OggHeaderTypeBOS       byte = 0x02
+	OggHeaderTypeEOS       byte = 0x04
+
+	// OggGranuleNone 页面上没有结束的包时的 granule position
+	OggGranuleNone uint64 = 0xFFFFFFFFFFFFFFFF
+
+	oggCapturePattern  = "OggS"
+	oggPageHeaderSize  = 27
+	oggMaxSegments     = 255
+	oggMaxSegmentSize  = 255
+	oggTargetPageBytes = 4096
+)
+
+var (
+	InvalidOggErr     = errors.New("invalid ogg data")
+	OggCrcMismatchErr = errors.New("ogg page crc mismatch")
+)
+
+var oggCrcTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+func oggCrc(crc uint32, data []byte) uint32 {
+	for _, b := range data {
+		crc = crc<<8 ^ oggCrcTable[byte(crc>>24)^b]
+	}
+
+	return crc
+}
+
+type OggPage struct {
+	HeaderType      byte
+	GranulePosition uint64
+	SerialNumber    uint32
+	SequenceNumber  uint32
+	Segments        []byte // lacing values
+	Body            []byte
+}
+
+func (p *OggPage) IsContinued() bool {
+	return p.HeaderType&OggHeaderTypeContinued != 0
+}
+
+func (p *OggPage) IsBOS() bool {
+	return p.HeaderType&OggHeaderTypeBOS != 0
+}
+
+func (p *OggPage) IsEOS() bool {
+	return p.HeaderType&OggHeaderTypeEOS != 0
+}
+
+// MarshalBinary 按 RFC 3533 序列化页面并计算 CRC
+func (p *OggPage) MarshalBinary() ([]byte, error) {
+	if len(p.Segments) > oggMaxSegments {
+		return nil, InvalidOggErr
+	}
+
+	data := make([]byte, oggPageHeaderSize+len(p.Segments)+len(p.Body))
+	copy(data, oggCapturePattern)
+	data[5] = p.HeaderType
+	binary.LittleEndian.PutUint64(data[6:], p.GranulePosition)
+	binary.LittleEndian.PutUint32(data[14:], p.SerialNumber)
+	binary.LittleEndian.PutUint32(data[18:], p.SequenceNumber)
+	data[26] = byte(len(p.Segments))
+	copy(data[oggPageHeaderSize:], p.Segments)
+	copy(data[oggPageHeaderSize+len(p.Segments):], p.Body)
+	binary.LittleEndian.PutUint32(data[22:], oggCrc(0, data))
+
+	return data, nil
+}
+
+// OggReader 读取单个逻辑流的 Ogg 页面和包，其他 serial number 的页面会被忽略
+type OggReader struct {
+	r       io.Reader
+	serial  uint32
+	started bool
+	pending []byte
+	packets [][]byte
+	granule uint64
+}
+
+func NewOggReader(r io.Reader) *OggReader {
+	return &OggReader{r: r}
+}
+
+// ReadPage 读取下一页并校验 CRC
+func (or *OggReader) ReadPage() (*OggPage, error) {
+	for {
+		page, err := readOggPage(or.r)
+		if err != nil {
+			return nil, err
+		}
+
+		if !or.started {
+			or.serial = page.SerialNumber
+			or.started = true
+		}
+		if page.SerialNumber == or.serial {
+			return page, nil
+		}
+	}
+}
+
+// ReadPacket 读取下一个完整的包，跨页的包会被拼接；granule 为该包结束所在页面的 granule position
+func (or *OggReader) ReadPacket() ([]byte, uint64, error) {
+	for len(or.packets) == 0 {
+		page, err := or.ReadPage()
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(or.pending) > 0 {
+				return nil, 0, io.ErrUnexpectedEOF
+			}
+			return nil, 0, err
+		}
+
+		if !page.IsContinued() {
+			or.pending = nil
+		}
+
+		offset := 0
+		for _, lacing := range page.Segments {
+			or.pending = append(or.pending, page.Body[offset:offset+int(lacing)]...)
+			offset += int(lacing)
+			if lacing < oggMaxSegmentSize {
+				or.packets = append(or.packets, or.pending)
+				or.pending = nil
+			}
+		}
+		or.granule = page.GranulePosition
+	}
+
+	packet := or.packets[0]
+	or.packets = or.packets[1:]
+	if packet == nil {
+		packet = []byte{}
+	}
+
+	return packet, or.granule, nil
+}
+
+func readOggPage(r io.Reader) (*OggPage, error) {
+	header := make([]byte, oggPageHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, InvalidOggErr
+		}
+		return nil, err
+	}
+	if string(header[:4]) != oggCapturePattern || header[4] != 0 {
+		return nil, InvalidOggErr
+	}
+
+	segments := make([]byte, header[26])
+	if _, err := io.ReadFull(r, segments); err != nil {
+		return nil, InvalidOggErr
+	}
+	bodySize := 0
+	for _, lacing := range segments {
+		bodySize += int(lacing)
+	}
+	body := make([]byte, bodySize)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, InvalidOggErr
+	}
+
+	expected := binary.LittleEndian.Uint32(header[22:26])
+	copy(header[22:26], []byte{0, 0, 0, 0})
+	crc := oggCrc(oggCrc(oggCrc(0, header), segments), body)
+	if crc != expected {
+		return nil, OggCrcMismatchErr
+	}
+
+	return &OggPage{
+		HeaderType:      header[5],
+		GranulePosition: binary.LittleEndian.Uint64(header[6:14]),
+		SerialNumber:    binary.LittleEndian.Uint32(header[14:18]),
+		SequenceNumber:  binary.LittleEndian.Uint32(header[18:22]),
+		Segments:        segments,
+		Body:            body,
+	}, nil
+}
+
+// OggWriter 将包写入单个逻辑流，多个包会合并到同一页，超过 255 个分段时跨页
+type OggWriter struct {
+	w         io.Writer
+	serial    uint32
+	sequence  uint32
+	started   bool
+	continued bool
+	completed bool
+	granule   uint64
+	segments  []byte
+	body      bytes.Buffer
+}
+
+func NewOggWriter(w io.Writer, serial uint32) *OggWriter {
+	return &OggWriter{w: w, serial: serial}
+}
+
+// WritePacket 缓冲一个包，granule 为该包结束时的 granule position，页面达到目标大小时自动写出
+func (ow *OggWriter) WritePacket(packet []byte, granule uint64) error {
+	offset := 0
+	for {
+		if len(ow.segments) == oggMaxSegments {
+			if err := ow.writePage(0); err != nil {
+				return err
+			}
+			ow.continued = offset > 0
+		}
+
+		size := min(len(packet)-offset, oggMaxSegmentSize)
+		ow.segments = append(ow.segments, byte(size))
+		ow.body.Write(packet[offset : offset+size])
+		offset += size
+		if size < oggMaxSegmentSize {
+			break
+		}
+	}
+
+	ow.completed = true
+	ow.granule = granule
+	if ow.body.Len() >= oggTargetPageBytes {
+		return ow.Flush()
+	}
+
+	return nil
+}
+
+// Flush 将缓冲的包写为一页，Opus 的头部包需要单独成页
+func (ow *OggWriter) Flush() error {
+	if len(ow.segments) == 0 {
+		return nil
+	}
+
+	return ow.writePage(0)
+}
+
+// Close 写出剩余的包并标记流结束
+func (ow *OggWriter) Close() error {
+	return ow.writePage(OggHeaderTypeEOS)
+}
+
+func (ow *OggWriter) writePage(flags byte) error {
+	page := &OggPage{
+		HeaderType:      flags,
+		GranulePosition: OggGranuleNone,
+		SerialNumber:    ow.serial,
+		SequenceNumber:  ow.sequence,
+		Segments:        ow.segments,
+		Body:            ow.body.Bytes(),
+	}
+	if !ow.started {
+		page.HeaderType |= OggHeaderTypeBOS
+	}
+	if ow.continued {
+		page.HeaderType |= OggHeaderTypeContinued
+	}
+	if ow.completed || len(ow.segments) == 0 {
+		page.GranulePosition = ow.granule
+	}
+
+	data, err := page.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if _, err := ow.w.Write(data); err != nil {
+		return err
+	}
+
+	ow.started = true
+	ow.continued = false
+	ow.completed = false
+	ow.sequence++
+	ow.segments = nil
+	ow.body.Reset()
+
+	return nil
+}
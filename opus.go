@@ -1,22 +1,125 @@
 package iflytek
 
-import "encoding/binary"
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
 
-func ExtractRawOpusData(data []byte) []byte {
-	var rawData []byte
+const (
+	opusFrameHeaderSize = 2
+	// maxOpusPacketSize 单个 opus 包最多 48 帧，每帧最多 1275 字节，见 RFC 6716 3.2.1
+	maxOpusPacketSize = 1275 * 48
+)
+
+var InvalidOpusErr = errors.New("invalid opus data")
+
+// OpusFrameReader 逐帧读取两字节大端长度前缀的 opus 帧序列（opus-wb），长度为 0 的帧视为结束
+type OpusFrameReader struct {
+	r      io.Reader
+	header [opusFrameHeaderSize]byte
+	offset int64
+	ended  bool
+}
+
+func NewOpusFrameReader(r io.Reader) *OpusFrameReader {
+	return &OpusFrameReader{r: r}
+}
+
+// ReadFrame 读取下一帧，不含长度前缀；读完时返回 io.EOF，数据被截断或长度非法时返回 InvalidOpusErr
+func (fr *OpusFrameReader) ReadFrame() ([]byte, error) {
+	if fr.ended {
+		return nil, io.EOF
+	}
+
+	n, err := io.ReadFull(fr.r, fr.header[:])
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			fr.ended = true
+			return nil, io.EOF
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: truncated frame header at offset %d", InvalidOpusErr, fr.offset)
+		}
+		return nil, err
+	}
+
+	length := int(binary.BigEndian.Uint16(fr.header[:]))
+	if length == 0 {
+		fr.ended = true
+		return nil, io.EOF
+	}
+	if length > maxOpusPacketSize {
+		return nil, fmt.Errorf("%w: frame length %d at offset %d exceeds %d", InvalidOpusErr, length, fr.offset, maxOpusPacketSize)
+	}
+
+	frame := make([]byte, length)
+	m, err := io.ReadFull(fr.r, frame)
+	if err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: frame at offset %d has %d of %d bytes", InvalidOpusErr, fr.offset, m, length)
+		}
+		return nil, err
+	}
+	fr.offset += int64(n + m)
+
+	return frame, nil
+}
+
+// OpusFrameWriter 将 opus 帧写为两字节大端长度前缀的帧序列
+type OpusFrameWriter struct {
+	w io.Writer
+}
+
+func NewOpusFrameWriter(w io.Writer) *OpusFrameWriter {
+	return &OpusFrameWriter{w: w}
+}
+
+// WriteFrame 写入一帧，空帧会被读取方视为结束，因此不允许写入
+func (fw *OpusFrameWriter) WriteFrame(frame []byte) error {
+	if len(frame) == 0 {
+		return fmt.Errorf("%w: empty frame", InvalidOpusErr)
+	}
+	if len(frame) > maxOpusPacketSize {
+		return fmt.Errorf("%w: frame length %d exceeds %d", InvalidOpusErr, len(frame), maxOpusPacketSize)
+	}
+
+	data := make([]byte, opusFrameHeaderSize, opusFrameHeaderSize+len(frame))
+	binary.BigEndian.PutUint16(data, uint16(len(frame)))
+	_, err := fw.w.Write(append(data, frame...))
+
+	return err
+}
+
+// ReadOpusFrames 读取全部帧
+func ReadOpusFrames(r io.Reader) ([][]byte, error) {
+	reader := NewOpusFrameReader(r)
+	var frames [][]byte
 	for {
-		// 提取前两个字节并转换为长度
-		length := int(binary.BigEndian.Uint16(data[:2]))
-		if length == 0 {
-			break
+		frame, err := reader.ReadFrame()
+		if errors.Is(err, io.EOF) {
+			return frames, nil
 		}
+		if err != nil {
+			return frames, err
+		}
+		frames = append(frames, frame)
+	}
+}
 
-		rawData = append(rawData, data[2:length]...)
-		if len(data) > (length + 2) {
-			data = data[length+2:]
-		} else {
+// Deprecated: 拼接后的数据丢失了帧边界，无法再解码，请使用 OpusFrameReader 逐帧读取。
+// 数据非法时返回已读取部分，不再 panic。
+func ExtractRawOpusData(data []byte) []byte {
+	var rawData []byte
+	reader := NewOpusFrameReader(bytes.NewReader(data))
+	for {
+		frame, err := reader.ReadFrame()
+		if err != nil {
 			break
 		}
+		rawData = append(rawData, frame...)
 	}
 
 	return rawData
@@ -0,0 +1,290 @@
+package iflytek
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	opusHeadMagic = "OpusHead"
+	opusTagsMagic = "OpusTags"
+	opusVendor    = "go-iflytek"
+
+	opusHeadSize          = 19
+	oggOpusSampleRate     = 48000
+	opusDefaultPreSkip    = 312
+	opusWbInputSampleRate = 16000
+)
+
+// OpusHead Ogg Opus 的标识头，见 RFC 7845 5.1
+type OpusHead struct {
+	Version         uint8  `json:"version"`
+	Channels        uint8  `json:"channels"`
+	PreSkip         uint16 `json:"preSkip"`         // 48kHz 下解码时需丢弃的样本数
+	InputSampleRate uint32 `json:"inputSampleRate"` // 编码前的原始采样率，仅供参考
+	OutputGain      int16  `json:"outputGain"`
+	MappingFamily   uint8  `json:"mappingFamily"`
+	StreamCount     uint8  `json:"streamCount"`
+	CoupledCount    uint8  `json:"coupledCount"`
+	ChannelMapping  []byte `json:"channelMapping"`
+}
+
+// NewOpusWbHead opus-wb（16k 单声道）对应的默认标识头
+func NewOpusWbHead() *OpusHead {
+	return &OpusHead{Version: 1, Channels: 1, PreSkip: opusDefaultPreSkip, InputSampleRate: opusWbInputSampleRate}
+}
+
+func ParseOpusHead(packet []byte) (*OpusHead, error) {
+	if len(packet) < opusHeadSize || string(packet[:8]) != opusHeadMagic {
+		return nil, InvalidOpusErr
+	}
+
+	head := &OpusHead{
+		Version:         packet[8],
+		Channels:        packet[9],
+		PreSkip:         binary.LittleEndian.Uint16(packet[10:12]),
+		InputSampleRate: binary.LittleEndian.Uint32(packet[12:16]),
+		OutputGain:      int16(binary.LittleEndian.Uint16(packet[16:18])),
+		MappingFamily:   packet[18],
+	}
+	if head.Version>>4 != 0 || head.Channels == 0 {
+		return nil, InvalidOpusErr
+	}
+	if head.MappingFamily != 0 {
+		if len(packet) < opusHeadSize+2+int(head.Channels) {
+			return nil, InvalidOpusErr
+		}
+		head.StreamCount = packet[19]
+		head.CoupledCount = packet[20]
+		head.ChannelMapping = append([]byte{}, packet[21:21+int(head.Channels)]...)
+	}
+
+	return head, nil
+}
+
+func (h *OpusHead) MarshalBinary() ([]byte, error) {
+	packet := make([]byte, opusHeadSize, opusHeadSize+2+len(h.ChannelMapping))
+	copy(packet, opusHeadMagic)
+	packet[8] = h.Version
+	packet[9] = h.Channels
+	binary.LittleEndian.PutUint16(packet[10:], h.PreSkip)
+	binary.LittleEndian.PutUint32(packet[12:], h.InputSampleRate)
+	binary.LittleEndian.PutUint16(packet[16:], uint16(h.OutputGain))
+	packet[18] = h.MappingFamily
+	if h.MappingFamily != 0 {
+		if len(h.ChannelMapping) != int(h.Channels) {
+			return nil, InvalidOpusErr
+		}
+		packet = append(packet, h.StreamCount, h.CoupledCount)
+		packet = append(packet, h.ChannelMapping...)
+	}
+
+	return packet, nil
+}
+
+// OpusTags Ogg Opus 的注释头，见 RFC 7845 5.2
+type OpusTags struct {
+	Vendor   string   `json:"vendor"`
+	Comments []string `json:"comments"` // 形如 KEY=value
+}
+
+func ParseOpusTags(packet []byte) (*OpusTags, error) {
+	if len(packet) < 16 || string(packet[:8]) != opusTagsMagic {
+		return nil, InvalidOpusErr
+	}
+
+	readString := func(offset int) (string, int, error) {
+		if offset+4 > len(packet) {
+			return "", 0, InvalidOpusErr
+		}
+		length := int(binary.LittleEndian.Uint32(packet[offset:]))
+		offset += 4
+		if length < 0 || offset+length > len(packet) {
+			return "", 0, InvalidOpusErr
+		}
+		return string(packet[offset : offset+length]), offset + length, nil
+	}
+
+	vendor, offset, err := readString(8)
+	if err != nil {
+		return nil, err
+	}
+	if offset+4 > len(packet) {
+		return nil, InvalidOpusErr
+	}
+	count := int(binary.LittleEndian.Uint32(packet[offset:]))
+	offset += 4
+
+	tags := &OpusTags{Vendor: vendor}
+	for i := 0; i < count; i++ {
+		var comment string
+		comment, offset, err = readString(offset)
+		if err != nil {
+			return nil, err
+		}
+		tags.Comments = append(tags.Comments, comment)
+	}
+
+	return tags, nil
+}
+
+func (t *OpusTags) MarshalBinary() ([]byte, error) {
+	packet := []byte(opusTagsMagic)
+	packet = binary.LittleEndian.AppendUint32(packet, uint32(len(t.Vendor)))
+	packet = append(packet, t.Vendor...)
+	packet = binary.LittleEndian.AppendUint32(packet, uint32(len(t.Comments)))
+	for _, comment := range t.Comments {
+		packet = binary.LittleEndian.AppendUint32(packet, uint32(len(comment)))
+		packet = append(packet, comment...)
+	}
+
+	return packet, nil
+}
+
+// OpusPacketSamples 根据 TOC 字节计算包在 48kHz 下的样本数，见 RFC 6716 3.1
+func OpusPacketSamples(packet []byte) (int, error) {
+	if len(packet) == 0 {
+		return 0, InvalidOpusErr
+	}
+
+	toc := packet[0]
+	config := int(toc >> 3)
+	var frameSamples int
+	switch {
+	case config < 12:
+		frameSamples = []int{480, 960, 1920, 2880}[config%4]
+	case config < 16:
+		frameSamples = []int{480, 960}[config%2]
+	default:
+		frameSamples = []int{120, 240, 480, 960}[config%4]
+	}
+
+	frames := 1
+	switch toc & 0x03 {
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0, InvalidOpusErr
+		}
+		frames = int(packet[1] & 0x3F)
+	}
+
+	return frames * frameSamples, nil
+}
+
+// ProbeOggOpus 读取 Ogg Opus 的标识头，并根据最后一页的 granule position 计算时长（毫秒）
+func ProbeOggOpus(r io.Reader) (*OpusHead, int64, error) {
+	reader := NewOggReader(r)
+	packet, _, err := reader.ReadPacket()
+	if err != nil {
+		return nil, 0, err
+	}
+	head, err := ParseOpusHead(packet)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var granule uint64
+	for {
+		page, err := reader.ReadPage()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if page.GranulePosition != OggGranuleNone {
+			granule = page.GranulePosition
+		}
+	}
+
+	if granule < uint64(head.PreSkip) {
+		return head, 0, nil
+	}
+
+	return head, int64(granule-uint64(head.PreSkip)) * 1000 / oggOpusSampleRate, nil
+}
+
+// ConvertOggOpusToOpusWb 将 Ogg Opus 转换为 AST 和声纹使用的两字节长度前缀的 opus-wb 帧序列
+func ConvertOggOpusToOpusWb(r io.Reader, w io.Writer) (*OpusHead, error) {
+	reader := NewOggReader(r)
+	writer := NewOpusFrameWriter(w)
+	packet, _, err := reader.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	head, err := ParseOpusHead(packet)
+	if err != nil {
+		return nil, err
+	}
+	if packet, _, err = reader.ReadPacket(); err != nil {
+		return nil, err
+	}
+	if _, err = ParseOpusTags(packet); err != nil {
+		return nil, err
+	}
+
+	for {
+		packet, _, err = reader.ReadPacket()
+		if errors.Is(err, io.EOF) {
+			return head, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(packet) == 0 {
+			continue
+		}
+		if err := writer.WriteFrame(packet); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ConvertOpusWbToOggOpus 将两字节长度前缀的 opus-wb 帧序列封装为 Ogg Opus，head 为空时使用 NewOpusWbHead
+func ConvertOpusWbToOggOpus(r io.Reader, w io.Writer, head *OpusHead, serial uint32) error {
+	if head == nil {
+		head = NewOpusWbHead()
+	}
+
+	reader := NewOpusFrameReader(r)
+	writer := NewOggWriter(w, serial)
+	headPacket, err := head.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	tagsPacket, err := (&OpusTags{Vendor: opusVendor}).MarshalBinary()
+	if err != nil {
+		return err
+	}
+	for _, packet := range [][]byte{headPacket, tagsPacket} {
+		if err := writer.WritePacket(packet, 0); err != nil {
+			return err
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+
+	var granule uint64
+	for {
+		frame, err := reader.ReadFrame()
+		if errors.Is(err, io.EOF) {
+			return writer.Close()
+		}
+		if err != nil {
+			return err
+		}
+
+		samples, err := OpusPacketSamples(frame)
+		if err != nil {
+			return err
+		}
+		granule += uint64(samples)
+		if err := writer.WritePacket(frame, granule); err != nil {
+			return err
+		}
+	}
+}
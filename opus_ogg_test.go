@@ -0,0 +1,92 @@
+package iflytek_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"testing"
+)
+
+func TestConvertOpusWbToOggOpus(t *testing.T) {
+	var opusWb bytes.Buffer
+	for i := 0; i < 50; i++ {
+		// config 9: SILK WB 20ms，单帧 960 个样本
+		frame := bytes.Repeat([]byte{byte(i)}, 40+i*7)
+		frame[0] = 9 << 3
+		opusWb.Write(binary.BigEndian.AppendUint16(nil, uint16(len(frame))))
+		opusWb.Write(frame)
+	}
+	expected := append([]byte{}, opusWb.Bytes()...)
+
+	var ogg bytes.Buffer
+	if err := dgkdxf.ConvertOpusWbToOggOpus(&opusWb, &ogg, nil, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	head, durationMillis, err := dgkdxf.ProbeOggOpus(bytes.NewReader(ogg.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Channels != 1 || head.InputSampleRate != 16000 || durationMillis != (50*960-312)/48 {
+		t.Fatalf("head: %+v, duration: %dms", head, durationMillis)
+	}
+
+	var converted bytes.Buffer
+	if _, err := dgkdxf.ConvertOggOpusToOpusWb(bytes.NewReader(ogg.Bytes()), &converted); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(converted.Bytes(), expected) {
+		t.Fatalf("round trip mismatch: %d bytes, expected %d bytes", converted.Len(), len(expected))
+	}
+
+	corrupted := append([]byte{}, ogg.Bytes()...)
+	corrupted[len(corrupted)-1] ^= 0xFF
+	if _, _, err := dgkdxf.ProbeOggOpus(bytes.NewReader(corrupted)); !errors.Is(err, dgkdxf.OggCrcMismatchErr) {
+		t.Fatalf("corrupted err: %v", err)
+	}
+}
+
+func TestOggWriterLargePacket(t *testing.T) {
+	var ogg bytes.Buffer
+	writer := dgkdxf.NewOggWriter(&ogg, 7)
+	large := bytes.Repeat([]byte{1, 2, 3}, 40000)
+	if err := writer.WritePacket(large, 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WritePacket([]byte{4}, 200); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := dgkdxf.NewOggReader(&ogg)
+	packet, granule, err := reader.ReadPacket()
+	if err != nil || !bytes.Equal(packet, large) || granule != 100 {
+		t.Fatalf("large packet: %d bytes, granule %d, err %v", len(packet), granule, err)
+	}
+	packet, granule, err = reader.ReadPacket()
+	if err != nil || !bytes.Equal(packet, []byte{4}) || granule != 200 {
+		t.Fatalf("small packet: %v, granule %d, err %v", packet, granule, err)
+	}
+}
+
+func TestOpusPacketSamples(t *testing.T) {
+	cases := []struct {
+		packet  []byte
+		samples int
+	}{
+		{[]byte{9 << 3}, 960},
+		{[]byte{3 << 3}, 2880},
+		{[]byte{12 << 3}, 480},
+		{[]byte{16<<3 | 1}, 240},
+		{[]byte{31<<3 | 3, 5}, 4800},
+	}
+	for _, c := range cases {
+		samples, err := dgkdxf.OpusPacketSamples(c.packet)
+		if err != nil || samples != c.samples {
+			t.Fatalf("toc %08b: %d samples, expected %d, err %v", c.packet[0], samples, c.samples, err)
+		}
+	}
+}
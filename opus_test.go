@@ -1,6 +1,8 @@
 package iflytek
 
 import (
+	"bytes"
+	"errors"
 	"github.com/darwinOrg/go-common/utils"
 	"os"
 	"testing"
@@ -11,3 +13,82 @@ func TestExtractRawOpusData(t *testing.T) {
 	rawData := ExtractRawOpusData(data)
 	_ = utils.AppendToFile("1.raw.opus", rawData)
 }
+
+func TestOpusFrameReader(t *testing.T) {
+	data := []byte{0, 3, 1, 2, 3, 0, 1, 4}
+	frames, err := ReadOpusFrames(bytes.NewReader(data))
+	if err != nil || len(frames) != 2 || !bytes.Equal(frames[0], []byte{1, 2, 3}) || !bytes.Equal(frames[1], []byte{4}) {
+		t.Fatalf("frames: %v, err: %v", frames, err)
+	}
+	if raw := ExtractRawOpusData(data); !bytes.Equal(raw, []byte{1, 2, 3, 4}) {
+		t.Fatalf("raw: %v", raw)
+	}
+
+	for _, invalid := range [][]byte{{0}, {0, 3, 1}, {0xFF, 0xFF}} {
+		if _, err := ReadOpusFrames(bytes.NewReader(invalid)); !errors.Is(err, InvalidOpusErr) {
+			t.Fatalf("%v err: %v", invalid, err)
+		}
+	}
+}
+
+func FuzzOpusFrameReader(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0})
+	f.Add([]byte{0, 3, 1, 2, 3, 0, 1, 4})
+	f.Add([]byte{0, 5, 1})
+	f.Add([]byte{0xFF, 0xFF, 0})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		frames, err := ReadOpusFrames(bytes.NewReader(data))
+		if err != nil && !errors.Is(err, InvalidOpusErr) {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		_ = ExtractRawOpusData(data)
+
+		var buf bytes.Buffer
+		writer := NewOpusFrameWriter(&buf)
+		for _, frame := range frames {
+			if err := writer.WriteFrame(frame); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !bytes.HasPrefix(data, buf.Bytes()) {
+			t.Fatalf("rewritten frames are not a prefix of input")
+		}
+	})
+}
+
+func FuzzOpusFrameWriter(f *testing.F) {
+	f.Add([]byte{1, 2, 3}, uint8(2))
+	f.Add([]byte{}, uint8(0))
+
+	f.Fuzz(func(t *testing.T, data []byte, split uint8) {
+		// 按 split+1 字节切分为多帧
+		size := int(split) + 1
+		var frames [][]byte
+		for begin := 0; begin < len(data); begin += size {
+			frames = append(frames, data[begin:min(begin+size, len(data))])
+		}
+
+		var buf bytes.Buffer
+		writer := NewOpusFrameWriter(&buf)
+		for _, frame := range frames {
+			if err := writer.WriteFrame(frame); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := writer.WriteFrame(nil); !errors.Is(err, InvalidOpusErr) {
+			t.Fatalf("empty frame err: %v", err)
+		}
+
+		read, err := ReadOpusFrames(&buf)
+		if err != nil || len(read) != len(frames) {
+			t.Fatalf("read %d frames, expected %d, err: %v", len(read), len(frames), err)
+		}
+		for i := range frames {
+			if !bytes.Equal(read[i], frames[i]) {
+				t.Fatalf("frame %d mismatch", i)
+			}
+		}
+	})
+}
@@ -0,0 +1,162 @@
+// Package otelinstrument 将 iflytek.Client 的接口调用和 ast 会话导出为 OpenTelemetry 链路和指标
+package otelinstrument
+
+import (
+	"context"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/darwinOrg/go-iflytek/otelinstrument"
+
+type Options struct {
+	TracerProvider trace.TracerProvider          // 默认 otel.GetTracerProvider()
+	MeterProvider  metric.MeterProvider          // 默认 otel.GetMeterProvider()
+	Propagator     propagation.TextMapPropagator // 注入出站请求头，默认 otel.GetTextMapPropagator()
+}
+
+// Instrumentation 实现 iflytek.Instrumentation，配置到 ClientConfig.Instrumentation。
+// 通过 iflytek.SetCancelContext 关联到 DgContext 的 context 中的 span 会作为父 span
+type Instrumentation struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+
+	requestDuration  metric.Float64Histogram
+	requestBytesSent metric.Int64Counter
+	astActive        metric.Int64UpDownCounter
+	astDuration      metric.Float64Histogram
+	astMessages      metric.Int64Counter
+	astBytes         metric.Int64Counter
+}
+
+func New(opts *Options) (*Instrumentation, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	tracerProvider, meterProvider, propagator := opts.TracerProvider, opts.MeterProvider, opts.Propagator
+	if tracerProvider == nil {
+		tracerProvider = otel.GetTracerProvider()
+	}
+	if meterProvider == nil {
+		meterProvider = otel.GetMeterProvider()
+	}
+	if propagator == nil {
+		propagator = otel.GetTextMapPropagator()
+	}
+
+	meter := meterProvider.Meter(instrumentationName)
+	i := &Instrumentation{tracer: tracerProvider.Tracer(instrumentationName), propagator: propagator}
+	var err error
+	if i.requestDuration, err = meter.Float64Histogram("iflytek.request.duration", metric.WithUnit("s"),
+		metric.WithDescription("iflytek api call latency including rate limit waiting")); err != nil {
+		return nil, err
+	}
+	if i.requestBytesSent, err = meter.Int64Counter("iflytek.request.sent_bytes", metric.WithUnit("By"),
+		metric.WithDescription("bytes uploaded by iflytek api calls")); err != nil {
+		return nil, err
+	}
+	if i.astActive, err = meter.Int64UpDownCounter("iflytek.ast.active_sessions",
+		metric.WithDescription("ast websocket sessions currently open")); err != nil {
+		return nil, err
+	}
+	if i.astDuration, err = meter.Float64Histogram("iflytek.ast.session.duration", metric.WithUnit("s"),
+		metric.WithDescription("ast websocket session duration")); err != nil {
+		return nil, err
+	}
+	if i.astMessages, err = meter.Int64Counter("iflytek.ast.messages",
+		metric.WithDescription("ast text and binary messages by direction")); err != nil {
+		return nil, err
+	}
+	if i.astBytes, err = meter.Int64Counter("iflytek.ast.message_bytes", metric.WithUnit("By"),
+		metric.WithDescription("ast message payload bytes by direction")); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+type callSpan struct {
+	i       *Instrumentation
+	ctx     context.Context
+	span    trace.Span
+	api     attribute.KeyValue
+	headers map[string]string
+}
+
+func (i *Instrumentation) StartCall(ctx *dgctx.DgContext, call *dgkdxf.CallInfo) dgkdxf.CallSpan {
+	spanCtx, span := i.tracer.Start(dgkdxf.GetCancelContext(ctx), "iflytek "+string(call.Api),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(call.StartTime),
+		trace.WithAttributes(attribute.String("iflytek.api", string(call.Api)), attribute.String("iflytek.trace_id", call.TraceId)))
+
+	carrier := propagation.MapCarrier{}
+	i.propagator.Inject(spanCtx, carrier)
+
+	return &callSpan{i: i, ctx: spanCtx, span: span, api: attribute.String("api", string(call.Api)), headers: carrier}
+}
+
+func (s *callSpan) Headers() map[string]string { return s.headers }
+
+func (s *callSpan) End(result *dgkdxf.CallResult) {
+	code := result.ErrorCode()
+	if result.StatusCode != 0 {
+		s.span.SetAttributes(attribute.Int("http.response.status_code", result.StatusCode))
+	}
+	if result.Code != "" {
+		s.span.SetAttributes(attribute.String("iflytek.code", result.Code))
+	}
+	if result.Err != nil {
+		s.span.RecordError(result.Err)
+		s.span.SetStatus(codes.Error, code)
+	}
+	s.span.End()
+
+	s.i.requestDuration.Record(s.ctx, result.Duration.Seconds(), metric.WithAttributes(s.api, attribute.String("code", code)))
+	if result.BytesSent > 0 {
+		s.i.requestBytesSent.Add(s.ctx, result.BytesSent, metric.WithAttributes(s.api))
+	}
+}
+
+var (
+	directionSent     = metric.WithAttributes(attribute.String("direction", "sent"))
+	directionReceived = metric.WithAttributes(attribute.String("direction", "received"))
+)
+
+type astSpan struct {
+	i    *Instrumentation
+	ctx  context.Context
+	span trace.Span
+}
+
+func (i *Instrumentation) StartAstSession(ctx *dgctx.DgContext, session *dgkdxf.AstSessionInfo) dgkdxf.AstSessionSpan {
+	spanCtx, span := i.tracer.Start(dgkdxf.GetCancelContext(ctx), "iflytek ast session",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(session.StartTime),
+		trace.WithAttributes(attribute.String("iflytek.trace_id", session.TraceId)))
+	i.astActive.Add(spanCtx, 1)
+
+	return &astSpan{i: i, ctx: spanCtx, span: span}
+}
+
+func (s *astSpan) End(stats *dgkdxf.AstSessionStats) {
+	s.span.SetAttributes(
+		attribute.Int64("iflytek.ast.messages_sent", stats.FramesSent),
+		attribute.Int64("iflytek.ast.messages_received", stats.FramesReceived),
+		attribute.Int64("iflytek.ast.bytes_sent", stats.BytesSent),
+		attribute.Int64("iflytek.ast.bytes_received", stats.BytesReceived),
+	)
+	s.span.End()
+
+	s.i.astActive.Add(s.ctx, -1)
+	s.i.astDuration.Record(s.ctx, stats.Duration.Seconds())
+	s.i.astMessages.Add(s.ctx, stats.FramesSent, directionSent)
+	s.i.astMessages.Add(s.ctx, stats.FramesReceived, directionReceived)
+	s.i.astBytes.Add(s.ctx, stats.BytesSent, directionSent)
+	s.i.astBytes.Add(s.ctx, stats.BytesReceived, directionReceived)
+}
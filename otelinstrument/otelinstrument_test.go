@@ -0,0 +1,69 @@
+package otelinstrument_test
+
+import (
+	"context"
+	"errors"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"github.com/darwinOrg/go-iflytek/otelinstrument"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+	"time"
+)
+
+func TestInstrumentation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	reader := sdkmetric.NewManualReader()
+	instrumentation, err := otelinstrument.New(&otelinstrument.Options{
+		TracerProvider: tracerProvider,
+		MeterProvider:  sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+		Propagator:     propagation.TraceContext{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// SetCancelContext 关联的 context 中的 span 作为父 span
+	parentCtx, parent := tracerProvider.Tracer("test").Start(context.Background(), "parent")
+	ctx := &dgctx.DgContext{TraceId: "t1"}
+	dgkdxf.SetCancelContext(ctx, parentCtx)
+
+	span := instrumentation.StartCall(ctx, &dgkdxf.CallInfo{Api: dgkdxf.ApiAsrUpload, TraceId: "t1", StartTime: time.Now()})
+	if span.Headers()["traceparent"] == "" {
+		t.Fatalf("headers: %v", span.Headers())
+	}
+	span.End(&dgkdxf.CallResult{Duration: time.Second, StatusCode: 502, Err: errors.New("bad gateway")})
+	instrumentation.StartAstSession(ctx, &dgkdxf.AstSessionInfo{TraceId: "t1", StartTime: time.Now()}).
+		End(&dgkdxf.AstSessionStats{Duration: time.Minute, FramesSent: 3, BytesSent: 30})
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 || spans[0].Name() != "iflytek asr.upload" || spans[1].Name() != "iflytek ast session" {
+		t.Fatalf("spans: %d", len(spans))
+	}
+	if spans[0].Parent().SpanID() != parent.SpanContext().SpanID() || spans[0].Status().Code != codes.Error {
+		t.Fatalf("call span parent: %v, status: %v", spans[0].Parent().SpanID(), spans[0].Status())
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			names[m.Name] = true
+		}
+	}
+	for _, name := range []string{"iflytek.request.duration", "iflytek.ast.active_sessions", "iflytek.ast.messages", "iflytek.ast.message_bytes"} {
+		if !names[name] {
+			t.Fatalf("missing metric %s: %v", name, names)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package iflytek
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	// pcmSampleSize pcm_s16le 每个样本的字节数
+	pcmSampleSize = 2
+
+	resamplerTaps   = 63
+	resamplerCutoff = 0.45 // 相对于较低采样率奈奎斯特频率的截止频率
+)
+
+var (
+	InvalidPcmErr          = errors.New("invalid pcm data")
+	UnsupportedResampleErr = errors.New("unsupported resample rate")
+)
+
+// SplitStereo 将交织的双声道 pcm_s16le 拆分为左右两个单声道，双轨录音中分别对应座席和客户
+func SplitStereo(pcm []byte) ([]byte, []byte, error) {
+	if len(pcm)%(2*pcmSampleSize) != 0 {
+		return nil, nil, fmt.Errorf("%w: stereo length %d is not a multiple of %d", InvalidPcmErr, len(pcm), 2*pcmSampleSize)
+	}
+
+	left := make([]byte, 0, len(pcm)/2)
+	right := make([]byte, 0, len(pcm)/2)
+	for i := 0; i < len(pcm); i += 2 * pcmSampleSize {
+		left = append(left, pcm[i:i+pcmSampleSize]...)
+		right = append(right, pcm[i+pcmSampleSize:i+2*pcmSampleSize]...)
+	}
+
+	return left, right, nil
+}
+
+// ExtractChannel 取出多声道 pcm_s16le 中的一个声道，channel 从 0 开始
+func ExtractChannel(pcm []byte, channels int, channel int) ([]byte, error) {
+	if channels <= 0 || channel < 0 || channel >= channels {
+		return nil, fmt.Errorf("%w: channel %d of %d", InvalidPcmErr, channel, channels)
+	}
+	frameSize := channels * pcmSampleSize
+	if len(pcm)%frameSize != 0 {
+		return nil, fmt.Errorf("%w: length %d is not a multiple of %d", InvalidPcmErr, len(pcm), frameSize)
+	}
+
+	mono := make([]byte, 0, len(pcm)/channels)
+	for i := channel * pcmSampleSize; i < len(pcm); i += frameSize {
+		mono = append(mono, pcm[i:i+pcmSampleSize]...)
+	}
+
+	return mono, nil
+}
+
+// Downmix 将多声道 pcm_s16le 取平均混为单声道
+func Downmix(pcm []byte, channels int) ([]byte, error) {
+	if channels <= 0 {
+		return nil, fmt.Errorf("%w: %d channels", InvalidPcmErr, channels)
+	}
+	frameSize := channels * pcmSampleSize
+	if len(pcm)%frameSize != 0 {
+		return nil, fmt.Errorf("%w: length %d is not a multiple of %d", InvalidPcmErr, len(pcm), frameSize)
+	}
+	if channels == 1 {
+		return pcm, nil
+	}
+
+	mono := make([]byte, len(pcm)/channels)
+	for i, j := 0, 0; i < len(pcm); i, j = i+frameSize, j+pcmSampleSize {
+		var sum int
+		for c := 0; c < channels; c++ {
+			sum += int(int16(binary.LittleEndian.Uint16(pcm[i+c*pcmSampleSize:])))
+		}
+		binary.LittleEndian.PutUint16(mono[j:], uint16(int16(sum/channels)))
+	}
+
+	return mono, nil
+}
+
+// Resampler 单声道 pcm_s16le 的流式重采样，支持 8k 与 16k 互转。
+// 使用 Blackman 窗 sinc 低通滤波，会引入约 2ms 的固定延迟
+type Resampler struct {
+	up      int
+	down    int
+	taps    []float64
+	history []float64 // 高采样率下最近的输入，长度为 len(taps)-1
+	phase   int       // 抽取时距下一个输出样本还需跳过的样本数
+	pending []byte    // 上次写入时不足一个样本的字节
+}
+
+func NewResampler(fromRate int, toRate int) (*Resampler, error) {
+	r := &Resampler{up: 1, down: 1}
+	switch {
+	case fromRate == toRate && fromRate > 0:
+		return r, nil
+	case fromRate == 8000 && toRate == 16000:
+		r.up = 2
+	case fromRate == 16000 && toRate == 8000:
+		r.down = 2
+	default:
+		return nil, fmt.Errorf("%w: %dHz to %dHz", UnsupportedResampleErr, fromRate, toRate)
+	}
+
+	// 截止频率按高采样率归一化
+	factor := max(r.up, r.down)
+	cutoff := resamplerCutoff / float64(factor)
+	center := float64(resamplerTaps-1) / 2
+	r.taps = make([]float64, resamplerTaps)
+	for i := range r.taps {
+		x := float64(i) - center
+		sinc := 2 * cutoff
+		if x != 0 {
+			sinc = math.Sin(2*math.Pi*cutoff*x) / (math.Pi * x)
+		}
+		window := 0.42 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(resamplerTaps-1)) + 0.08*math.Cos(4*math.Pi*float64(i)/float64(resamplerTaps-1))
+		r.taps[i] = sinc * window
+	}
+	// 归一化直流增益，插零上采样需要乘以倍数补偿能量
+	var sum float64
+	for _, tap := range r.taps {
+		sum += tap
+	}
+	for i := range r.taps {
+		r.taps[i] *= float64(r.up) / sum
+	}
+	r.history = make([]float64, resamplerTaps-1)
+
+	return r, nil
+}
+
+// Write 写入任意长度的 pcm 数据，返回已重采样的数据；不足一个样本的字节保留到下次
+func (r *Resampler) Write(pcm []byte) []byte {
+	if len(r.pending) > 0 {
+		pcm = append(r.pending, pcm...)
+		r.pending = nil
+	}
+	if odd := len(pcm) % pcmSampleSize; odd != 0 {
+		r.pending = append([]byte{}, pcm[len(pcm)-odd:]...)
+		pcm = pcm[:len(pcm)-odd]
+	}
+	if r.taps == nil {
+		return pcm
+	}
+
+	samples := len(pcm) / pcmSampleSize
+	out := make([]byte, 0, samples*pcmSampleSize*r.up/r.down+pcmSampleSize)
+	for i := 0; i < samples; i++ {
+		sample := float64(int16(binary.LittleEndian.Uint16(pcm[i*pcmSampleSize:])))
+		for k := 0; k < r.up; k++ {
+			input := 0.0
+			if k == 0 {
+				input = sample
+			}
+			if r.phase == 0 {
+				out = binary.LittleEndian.AppendUint16(out, uint16(clampInt16(r.filter(input))))
+				r.phase = r.down
+			}
+			r.phase--
+			copy(r.history, r.history[1:])
+			r.history[len(r.history)-1] = input
+		}
+	}
+
+	return out
+}
+
+func (r *Resampler) filter(input float64) float64 {
+	// taps 对称，按时间顺序卷积即可
+	acc := r.taps[len(r.taps)-1] * input
+	for i, value := range r.history {
+		acc += r.taps[i] * value
+	}
+
+	return acc
+}
+
+// Resample 一次性重采样单声道 pcm_s16le
+func Resample(pcm []byte, fromRate int, toRate int) ([]byte, error) {
+	if len(pcm)%pcmSampleSize != 0 {
+		return nil, fmt.Errorf("%w: length %d is not a multiple of %d", InvalidPcmErr, len(pcm), pcmSampleSize)
+	}
+	r, err := NewResampler(fromRate, toRate)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Write(pcm), nil
+}
+
+func clampInt16(value float64) int16 {
+	value = math.Round(value)
+	if value > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if value < math.MinInt16 {
+		return math.MinInt16
+	}
+
+	return int16(value)
+}
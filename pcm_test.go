@@ -0,0 +1,185 @@
+package iflytek_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"github.com/gorilla/websocket"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteWav(t *testing.T) {
+	pcm := testTonePcm(8000, 1000, 100)
+	var buf bytes.Buffer
+	if err := dgkdxf.WriteWav(&buf, dgkdxf.NewPcmWavHeader(8000, 1), pcm); err != nil {
+		t.Fatal(err)
+	}
+
+	header, data, err := dgkdxf.ParseWav(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.SampleRate != 8000 || header.Channels != 1 || header.DurationMillis() != 100 || !bytes.Equal(data, pcm) {
+		t.Fatalf("header: %+v", header)
+	}
+}
+
+func TestSplitStereo(t *testing.T) {
+	stereo := []byte{1, 0, 3, 0, 2, 0, 5, 0}
+	left, right, err := dgkdxf.SplitStereo(stereo)
+	if err != nil || !bytes.Equal(left, []byte{1, 0, 2, 0}) || !bytes.Equal(right, []byte{3, 0, 5, 0}) {
+		t.Fatalf("left: %v, right: %v, err: %v", left, right, err)
+	}
+
+	mono, err := dgkdxf.Downmix(stereo, 2)
+	if err != nil || !bytes.Equal(mono, []byte{2, 0, 3, 0}) {
+		t.Fatalf("mono: %v, err: %v", mono, err)
+	}
+
+	if _, _, err := dgkdxf.SplitStereo(stereo[:6]); err == nil {
+		t.Fatal("expected error for truncated stereo")
+	}
+}
+
+func TestResample(t *testing.T) {
+	// 1kHz 在两个采样率下都应保持幅度
+	up, err := dgkdxf.Resample(testTonePcm(8000, 1000, 1000), 8000, 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(up) != 16000*2 {
+		t.Fatalf("up length: %d", len(up))
+	}
+	if rms := testRms(up[1000:]); math.Abs(rms-testToneRms) > testToneRms*0.05 {
+		t.Fatalf("up rms: %f", rms)
+	}
+
+	down, err := dgkdxf.Resample(testTonePcm(16000, 1000, 1000), 16000, 8000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(down) != 8000*2 {
+		t.Fatalf("down length: %d", len(down))
+	}
+	if rms := testRms(down[1000:]); math.Abs(rms-testToneRms) > testToneRms*0.05 {
+		t.Fatalf("down rms: %f", rms)
+	}
+
+	// 6kHz 超过 8k 的奈奎斯特频率，需要被滤除而不是混叠
+	aliased, _ := dgkdxf.Resample(testTonePcm(16000, 6000, 1000), 16000, 8000)
+	if rms := testRms(aliased[1000:]); rms > testToneRms*0.01 {
+		t.Fatalf("aliased rms: %f", rms)
+	}
+
+	// 流式写入的结果应与一次性重采样一致
+	pcm := testTonePcm(8000, 440, 500)
+	resampler, _ := dgkdxf.NewResampler(8000, 16000)
+	var streamed []byte
+	for begin := 0; begin < len(pcm); begin += 333 {
+		streamed = append(streamed, resampler.Write(pcm[begin:min(begin+333, len(pcm))])...)
+	}
+	whole, _ := dgkdxf.Resample(pcm, 8000, 16000)
+	if !bytes.Equal(streamed, whole) {
+		t.Fatal("streamed resample mismatch")
+	}
+
+	if _, err := dgkdxf.Resample(pcm, 8000, 44100); err == nil {
+		t.Fatal("expected unsupported rate error")
+	}
+}
+
+func TestAstAudioWriter(t *testing.T) {
+	messages := make(chan []byte, 100)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			mt, data, err := conn.ReadMessage()
+			if err != nil {
+				close(messages)
+				return
+			}
+			if mt == websocket.BinaryMessage {
+				messages <- data
+			}
+		}
+	}))
+	defer server.Close()
+
+	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{
+		AppId:           "app",
+		AccessKeyId:     "ak",
+		AccessKeySecret: "sk",
+		AstHost:         "ws" + strings.TrimPrefix(server.URL, "http"),
+	})
+
+	// 8k 双声道，左声道为座席
+	left := testTonePcm(8000, 1000, 500)
+	stereo := make([]byte, 0, len(left)*2)
+	for i := 0; i < len(left); i += 2 {
+		stereo = append(stereo, left[i], left[i+1], 0, 0)
+	}
+
+	stream, err := client.AstOpen(&dgctx.DgContext{TraceId: "123"}, &dgkdxf.AstParamConfig{
+		AudioEncode: dgkdxf.AstAudioEncodePcm,
+		Samplerate:  "16000",
+		AudioSource: &dgkdxf.AstAudioSource{SampleRate: 8000, Channels: 2, Channel: dgkdxf.AstChannelLeft},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for begin := 0; begin < len(stereo); begin += 1001 {
+		if _, err := stream.Write(stereo[begin:min(begin+1001, len(stereo))]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.End(&dgctx.DgContext{TraceId: "123"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = stream.Close()
+
+	var received []byte
+	for data := range messages {
+		if len(data) > 1280 {
+			t.Fatalf("chunk size: %d", len(data))
+		}
+		received = append(received, data...)
+	}
+	expected, _ := dgkdxf.Resample(left, 8000, 16000)
+	if !bytes.Equal(received, expected) {
+		t.Fatalf("received %d bytes, expected %d bytes", len(received), len(expected))
+	}
+}
+
+const testToneRms = 10000 / math.Sqrt2
+
+// testTonePcm 生成振幅为 10000 的单声道正弦波
+func testTonePcm(sampleRate int, frequency float64, millis int) []byte {
+	samples := sampleRate * millis / 1000
+	pcm := make([]byte, samples*2)
+	for i := 0; i < samples; i++ {
+		sample := int16(10000 * math.Sin(2*math.Pi*frequency*float64(i)/float64(sampleRate)))
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(sample))
+	}
+
+	return pcm
+}
+
+func testRms(pcm []byte) float64 {
+	var sum float64
+	for i := 0; i+1 < len(pcm); i += 2 {
+		sample := float64(int16(binary.LittleEndian.Uint16(pcm[i:])))
+		sum += sample * sample
+	}
+
+	return math.Sqrt(sum / float64(len(pcm)/2))
+}
@@ -0,0 +1,577 @@
+package iflytek
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type AudioContainer string
+
+const (
+	AudioContainerWav AudioContainer = "wav"
+	AudioContainerMp3 AudioContainer = "mp3"
+	AudioContainerOgg AudioContainer = "ogg"
+	AudioContainerAmr AudioContainer = "amr"
+	AudioContainerM4a AudioContainer = "m4a"
+)
+
+type AudioCodec string
+
+const (
+	AudioCodecPcm   AudioCodec = "pcm"
+	AudioCodecMp3   AudioCodec = "mp3"
+	AudioCodecOpus  AudioCodec = "opus"
+	AudioCodecAmrNb AudioCodec = "amr-nb"
+	AudioCodecAmrWb AudioCodec = "amr-wb"
+	AudioCodecAac   AudioCodec = "aac"
+)
+
+const (
+	amrNbMagic      = "#!AMR\n"
+	amrWbMagic      = "#!AMR-WB\n"
+	amrFrameMillis  = 20
+	mp3SyncScanSize = 64 * 1024
+	maxM4aMoovSize  = 64 * 1024 * 1024
+)
+
+var (
+	UnsupportedAudioErr = errors.New("unsupported audio format")
+	InvalidAudioErr     = errors.New("invalid audio data")
+)
+
+// AudioInfo 音频的基本信息，wav 以外的压缩格式 Codec 为实际编码
+type AudioInfo struct {
+	Container      AudioContainer `json:"container"`
+	Codec          AudioCodec     `json:"codec"`
+	DurationMillis int64          `json:"durationMillis"`
+	SampleRate     int            `json:"sampleRate"`
+	Channels       int            `json:"channels"`
+	Size           int64          `json:"size"` // 字节
+}
+
+// ProbeAudio 识别 wav、mp3、ogg opus、amr 和 m4a 并计算时长，不依赖 ffprobe；返回时 r 的位置不确定，需要时请自行 Seek
+func ProbeAudio(r io.ReadSeeker) (*AudioInfo, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	magic := make([]byte, 12)
+	n, err := io.ReadFull(r, magic)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	magic = magic[:n]
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var info *AudioInfo
+	switch {
+	case IsWav(magic):
+		info, err = probeWav(r, size)
+	case isOgg(magic):
+		info, err = probeOgg(r)
+	case bytes.HasPrefix(magic, []byte(amrNbMagic)) || bytes.HasPrefix(magic, []byte(amrWbMagic)):
+		info, err = probeAmr(r)
+	case len(magic) >= 8 && string(magic[4:8]) == "ftyp":
+		info, err = probeM4a(r, size)
+	case bytes.HasPrefix(magic, []byte("ID3")) || len(magic) >= 2 && magic[0] == 0xFF && magic[1]&0xE0 == 0xE0:
+		info, err = probeMp3(r)
+	default:
+		return nil, UnsupportedAudioErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	info.Size = size
+
+	return info, nil
+}
+
+// probeWav 逐块读取 wav 头，不读取 data 块内容
+func probeWav(r io.ReadSeeker, size int64) (*AudioInfo, error) {
+	if _, err := r.Seek(12, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var header *WavHeader
+	offset := int64(12)
+	chunk := make([]byte, 8)
+	for offset+8 <= size {
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return nil, fmt.Errorf("%w: %v", InvalidWavErr, err)
+		}
+		chunkSize := int64(binary.LittleEndian.Uint32(chunk[4:]))
+		body := offset + 8
+
+		switch string(chunk[:4]) {
+		case "fmt ":
+			if chunkSize < 16 {
+				return nil, InvalidWavErr
+			}
+			fmtChunk := make([]byte, 16)
+			if _, err := io.ReadFull(r, fmtChunk); err != nil {
+				return nil, fmt.Errorf("%w: %v", InvalidWavErr, err)
+			}
+			header = &WavHeader{
+				AudioFormat:   binary.LittleEndian.Uint16(fmtChunk[0:2]),
+				Channels:      int(binary.LittleEndian.Uint16(fmtChunk[2:4])),
+				SampleRate:    int(binary.LittleEndian.Uint32(fmtChunk[4:8])),
+				ByteRate:      int(binary.LittleEndian.Uint32(fmtChunk[8:12])),
+				BlockAlign:    int(binary.LittleEndian.Uint16(fmtChunk[12:14])),
+				BitsPerSample: int(binary.LittleEndian.Uint16(fmtChunk[14:16])),
+			}
+		case "data":
+			if header == nil || header.ByteRate == 0 {
+				return nil, InvalidWavErr
+			}
+			// 流式录制的 wav 常把 data 块长度写为 0 或最大值，按实际长度截断
+			if chunkSize == 0 || body+chunkSize > size {
+				chunkSize = size - body
+			}
+			header.DataSize = int(chunkSize)
+
+			codec := AudioCodecPcm
+			if !header.IsPcm() {
+				codec = AudioCodec(fmt.Sprintf("wav-0x%04x", header.AudioFormat))
+			}
+			return &AudioInfo{
+				Container:      AudioContainerWav,
+				Codec:          codec,
+				DurationMillis: header.DurationMillis(),
+				SampleRate:     header.SampleRate,
+				Channels:       header.Channels,
+			}, nil
+		}
+
+		offset = body + chunkSize + chunkSize%2
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, InvalidWavErr
+}
+
+func probeOgg(r io.Reader) (*AudioInfo, error) {
+	head, durationMillis, err := ProbeOggOpus(r)
+	if errors.Is(err, InvalidOpusErr) {
+		// 只支持 opus 编码的 ogg
+		return nil, UnsupportedAudioErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sampleRate := int(head.InputSampleRate)
+	if sampleRate == 0 {
+		sampleRate = oggOpusSampleRate
+	}
+
+	return &AudioInfo{
+		Container:      AudioContainerOgg,
+		Codec:          AudioCodecOpus,
+		DurationMillis: durationMillis,
+		SampleRate:     sampleRate,
+		Channels:       int(head.Channels),
+	}, nil
+}
+
+// amr 各帧类型的帧长（不含帧头字节），见 RFC 4867 和 3GPP TS 26.101/26.201
+var (
+	amrNbFrameSizes = [16]int{12, 13, 15, 17, 19, 20, 26, 31, 5, 0, 0, 0, 0, 0, 0, 0}
+	amrWbFrameSizes = [16]int{17, 23, 32, 36, 40, 46, 50, 58, 60, 5, 0, 0, 0, 0, 0, 0}
+)
+
+// probeAmr 逐帧读取单声道 amr 文件，每帧 20ms
+func probeAmr(r io.Reader) (*AudioInfo, error) {
+	reader := bufio.NewReader(r)
+	magic, err := reader.Peek(len(amrWbMagic))
+	if err != nil && len(magic) < len(amrNbMagic) {
+		return nil, InvalidAudioErr
+	}
+
+	info := &AudioInfo{Container: AudioContainerAmr, Codec: AudioCodecAmrNb, SampleRate: 8000, Channels: 1}
+	frameSizes := amrNbFrameSizes
+	magicSize := len(amrNbMagic)
+	if string(magic) == amrWbMagic {
+		info.Codec, info.SampleRate = AudioCodecAmrWb, 16000
+		frameSizes = amrWbFrameSizes
+		magicSize = len(amrWbMagic)
+	}
+	if _, err := reader.Discard(magicSize); err != nil {
+		return nil, err
+	}
+
+	var frames int64
+	for {
+		header, err := reader.ReadByte()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		frameSize := frameSizes[header>>3&0x0F]
+		if _, err := reader.Discard(frameSize); err != nil {
+			// 末尾不完整的帧不计入时长
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		frames++
+	}
+	info.DurationMillis = frames * amrFrameMillis
+
+	return info, nil
+}
+
+type mp3FrameHeader struct {
+	version         int // 1: MPEG1, 2: MPEG2, 25: MPEG2.5
+	layer           int
+	bitrate         int // kbps
+	sampleRate      int
+	channels        int
+	samplesPerFrame int
+	frameSize       int
+}
+
+var (
+	mp3Bitrates = map[[2]int][15]int{
+		{1, 1}: {0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+		{1, 2}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		{1, 3}: {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+		{2, 1}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+		{2, 2}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{2, 3}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	}
+	mp3SampleRates = map[int][3]int{
+		1:  {44100, 48000, 32000},
+		2:  {22050, 24000, 16000},
+		25: {11025, 12000, 8000},
+	}
+)
+
+// parseMp3FrameHeader 解析 4 字节的 MPEG 音频帧头，不支持 free format
+func parseMp3FrameHeader(b []byte) (*mp3FrameHeader, bool) {
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return nil, false
+	}
+
+	header := &mp3FrameHeader{}
+	switch b[1] >> 3 & 0x03 {
+	case 0:
+		header.version = 25
+	case 2:
+		header.version = 2
+	case 3:
+		header.version = 1
+	default:
+		return nil, false
+	}
+	header.layer = 4 - int(b[1]>>1&0x03)
+	bitrateIndex := int(b[2] >> 4)
+	sampleRateIndex := int(b[2] >> 2 & 0x03)
+	if header.layer == 4 || bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return nil, false
+	}
+
+	tableVersion := min(header.version, 2)
+	header.bitrate = mp3Bitrates[[2]int{tableVersion, header.layer}][bitrateIndex]
+	header.sampleRate = mp3SampleRates[header.version][sampleRateIndex]
+	header.channels = 2
+	if b[3]>>6 == 3 {
+		header.channels = 1
+	}
+
+	padding := int(b[2] >> 1 & 0x01)
+	switch {
+	case header.layer == 1:
+		header.samplesPerFrame = 384
+		header.frameSize = (12*header.bitrate*1000/header.sampleRate + padding) * 4
+	case header.layer == 3 && header.version != 1:
+		header.samplesPerFrame = 576
+		header.frameSize = 72*header.bitrate*1000/header.sampleRate + padding
+	default:
+		header.samplesPerFrame = 1152
+		header.frameSize = 144*header.bitrate*1000/header.sampleRate + padding
+	}
+
+	return header, true
+}
+
+// xingFrames 读取首帧中 Xing/Info 头记录的总帧数，没有时返回 0
+func (h *mp3FrameHeader) xingFrames(frame []byte) int64 {
+	if h.layer != 3 {
+		return 0
+	}
+
+	offset := 4 + 17
+	switch {
+	case h.version == 1 && h.channels == 2:
+		offset = 4 + 32
+	case h.version != 1 && h.channels == 1:
+		offset = 4 + 9
+	}
+	if len(frame) < offset+12 {
+		return 0
+	}
+	tag := string(frame[offset : offset+4])
+	if tag != "Xing" && tag != "Info" {
+		return 0
+	}
+	if binary.BigEndian.Uint32(frame[offset+4:])&0x01 == 0 {
+		return 0
+	}
+
+	return int64(binary.BigEndian.Uint32(frame[offset+8:]))
+}
+
+// probeMp3 跳过 ID3v2 标签，优先使用 Xing/Info 头的帧数，否则逐帧累计样本数，适用于 VBR
+func probeMp3(r io.ReadSeeker) (*AudioInfo, error) {
+	start, err := skipId3v2(r)
+	if err != nil {
+		return nil, err
+	}
+
+	first, offset, err := findMp3Sync(r, start)
+	if err != nil {
+		return nil, err
+	}
+	info := &AudioInfo{Container: AudioContainerMp3, Codec: AudioCodecMp3, SampleRate: first.sampleRate, Channels: first.channels}
+
+	frame := make([]byte, first.frameSize)
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(r, frame); err == nil {
+		if frames := first.xingFrames(frame); frames > 0 {
+			info.DurationMillis = frames * int64(first.samplesPerFrame) * 1000 / int64(first.sampleRate)
+			return info, nil
+		}
+	}
+
+	var samples int64
+	header := make([]byte, 4)
+	for {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(r, header); err != nil {
+			break
+		}
+		// 遇到 ID3v1 等尾部数据时结束
+		frameHeader, ok := parseMp3FrameHeader(header)
+		if !ok || frameHeader.sampleRate != first.sampleRate {
+			break
+		}
+		samples += int64(frameHeader.samplesPerFrame)
+		offset += int64(frameHeader.frameSize)
+	}
+	info.DurationMillis = samples * 1000 / int64(first.sampleRate)
+
+	return info, nil
+}
+
+func skipId3v2(r io.ReadSeeker) (int64, error) {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, fmt.Errorf("%w: %v", InvalidAudioErr, err)
+	}
+	if string(header[:3]) != "ID3" {
+		return 0, nil
+	}
+
+	// 标签长度为 syncsafe 整数
+	size := int64(header[6]&0x7F)<<21 | int64(header[7]&0x7F)<<14 | int64(header[8]&0x7F)<<7 | int64(header[9]&0x7F)
+	size += 10
+	if header[5]&0x10 != 0 {
+		size += 10
+	}
+
+	return size, nil
+}
+
+// findMp3Sync 在 start 之后查找连续两个合法帧头，返回首帧帧头和位置
+func findMp3Sync(r io.ReadSeeker, start int64) (*mp3FrameHeader, int64, error) {
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	buf := make([]byte, mp3SyncScanSize)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, 0, err
+	}
+	buf = buf[:n]
+
+	for i := 0; i+4 <= len(buf); i++ {
+		header, ok := parseMp3FrameHeader(buf[i:])
+		if !ok {
+			continue
+		}
+		next := i + header.frameSize
+		// 扫描范围内放不下下一帧头时无法校验，直接接受
+		if next+4 > len(buf) {
+			return header, start + int64(i), nil
+		}
+		if nextHeader, ok := parseMp3FrameHeader(buf[next:]); ok && nextHeader.sampleRate == header.sampleRate {
+			return header, start + int64(i), nil
+		}
+	}
+
+	return nil, 0, fmt.Errorf("%w: mp3 frame sync not found", InvalidAudioErr)
+}
+
+// probeM4a 读取 moov 中音频轨道的 mdhd 和 stsd，没有音频轨道时使用 mvhd 的时长
+func probeM4a(r io.ReadSeeker, size int64) (*AudioInfo, error) {
+	var moov []byte
+	for offset := int64(0); offset+8 <= size; {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		boxType, headerSize, boxSize, err := readMp4BoxHeader(r, size-offset)
+		if err != nil {
+			return nil, err
+		}
+		if boxType == "moov" {
+			if boxSize-headerSize > maxM4aMoovSize {
+				return nil, fmt.Errorf("%w: moov box too large", InvalidAudioErr)
+			}
+			moov = make([]byte, boxSize-headerSize)
+			if _, err := io.ReadFull(r, moov); err != nil {
+				return nil, fmt.Errorf("%w: %v", InvalidAudioErr, err)
+			}
+			break
+		}
+		offset += boxSize
+	}
+	if moov == nil {
+		return nil, fmt.Errorf("%w: moov box not found", InvalidAudioErr)
+	}
+
+	info := &AudioInfo{Container: AudioContainerM4a}
+	for _, trak := range mp4Children(moov, "trak") {
+		mdia := mp4Child(trak, "mdia")
+		hdlr := mp4Child(mdia, "hdlr")
+		if len(hdlr) < 12 || string(hdlr[8:12]) != "soun" {
+			continue
+		}
+		timescale, duration := parseMp4Duration(mp4Child(mdia, "mdhd"))
+		if timescale > 0 {
+			info.DurationMillis = int64(duration * 1000 / timescale)
+		}
+
+		stsd := mp4Child(mp4Child(mp4Child(mdia, "minf"), "stbl"), "stsd")
+		// fullbox 头 4 字节 + entry_count 4 字节，之后为 AudioSampleEntry
+		if len(stsd) >= 8+36 {
+			entry := stsd[8:]
+			codec := AudioCodec(entry[4:8])
+			if codec == "mp4a" {
+				codec = AudioCodecAac
+			}
+			info.Codec = codec
+			info.Channels = int(binary.BigEndian.Uint16(entry[24:26]))
+			info.SampleRate = int(binary.BigEndian.Uint16(entry[32:34]))
+		}
+		return info, nil
+	}
+
+	timescale, duration := parseMp4Duration(mp4Child(moov, "mvhd"))
+	if timescale == 0 {
+		return nil, fmt.Errorf("%w: audio track not found", InvalidAudioErr)
+	}
+	info.DurationMillis = int64(duration * 1000 / timescale)
+
+	return info, nil
+}
+
+func readMp4BoxHeader(r io.Reader, remaining int64) (string, int64, int64, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", 0, 0, fmt.Errorf("%w: %v", InvalidAudioErr, err)
+	}
+	boxType := string(header[4:8])
+	headerSize := int64(8)
+	boxSize := int64(binary.BigEndian.Uint32(header))
+	switch boxSize {
+	case 0:
+		boxSize = remaining
+	case 1:
+		if _, err := io.ReadFull(r, header); err != nil {
+			return "", 0, 0, fmt.Errorf("%w: %v", InvalidAudioErr, err)
+		}
+		boxSize = int64(binary.BigEndian.Uint64(header))
+		headerSize = 16
+	}
+	if boxSize < headerSize || boxSize > remaining {
+		return "", 0, 0, fmt.Errorf("%w: invalid %q box size %d", InvalidAudioErr, boxType, boxSize)
+	}
+
+	return boxType, headerSize, boxSize, nil
+}
+
+// mp4Children 返回 data 中类型为 boxType 的直接子 box 的内容，遇到越界的 box 时停止
+func mp4Children(data []byte, boxType string) [][]byte {
+	var children [][]byte
+	for offset := 0; offset+8 <= len(data); {
+		remaining := int64(len(data) - offset)
+		size := int64(binary.BigEndian.Uint32(data[offset:]))
+		headerSize := int64(8)
+		if size == 1 {
+			if remaining < 16 {
+				break
+			}
+			// 64 位 size 超过 int64 时为负数，按越界处理
+			size = int64(binary.BigEndian.Uint64(data[offset+8:]))
+			headerSize = 16
+		}
+		if size == 0 {
+			size = remaining
+		}
+		if size < headerSize || size > remaining {
+			break
+		}
+		if string(data[offset+4:offset+8]) == boxType {
+			children = append(children, data[offset+int(headerSize):offset+int(size)])
+		}
+		offset += int(size)
+	}
+
+	return children
+}
+
+func mp4Child(data []byte, boxType string) []byte {
+	children := mp4Children(data, boxType)
+	if len(children) == 0 {
+		return nil
+	}
+
+	return children[0]
+}
+
+// parseMp4Duration 解析 mvhd 或 mdhd 的 timescale 和 duration
+func parseMp4Duration(box []byte) (uint64, uint64) {
+	if len(box) < 4 {
+		return 0, 0
+	}
+	if box[0] == 1 {
+		if len(box) < 32 {
+			return 0, 0
+		}
+		return uint64(binary.BigEndian.Uint32(box[20:24])), binary.BigEndian.Uint64(box[24:32])
+	}
+	if len(box) < 20 {
+		return 0, 0
+	}
+
+	return uint64(binary.BigEndian.Uint32(box[12:16])), uint64(binary.BigEndian.Uint32(box[16:20]))
+}
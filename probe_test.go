@@ -0,0 +1,105 @@
+package iflytek_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"testing"
+)
+
+func TestProbeAudio(t *testing.T) {
+	var opusWb bytes.Buffer
+	writer := dgkdxf.NewOpusFrameWriter(&opusWb)
+	for i := 0; i < 100; i++ {
+		_ = writer.WriteFrame([]byte{9 << 3, 1, 2, 3})
+	}
+	var ogg bytes.Buffer
+	if err := dgkdxf.ConvertOpusWbToOggOpus(&opusWb, &ogg, nil, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// 空 ID3v2 标签 + 100 帧 MPEG1 Layer III 128kbps 44.1kHz 单声道，每帧 417 字节
+	mp3 := []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 0}
+	for i := 0; i < 100; i++ {
+		frame := make([]byte, 417)
+		copy(frame, []byte{0xFF, 0xFB, 0x90, 0xC4})
+		mp3 = append(mp3, frame...)
+	}
+
+	// 50 帧 AMR-NB 12.2kbps
+	amr := []byte("#!AMR\n")
+	for i := 0; i < 50; i++ {
+		amr = append(amr, 7<<3|0x04)
+		amr = append(amr, make([]byte, 31)...)
+	}
+
+	cases := []struct {
+		name       string
+		data       []byte
+		container  dgkdxf.AudioContainer
+		codec      dgkdxf.AudioCodec
+		duration   int64
+		sampleRate int
+		channels   int
+	}{
+		{"wav", testToneWav(8000, 2, 1500), dgkdxf.AudioContainerWav, dgkdxf.AudioCodecPcm, 1500, 8000, 2},
+		{"ogg", ogg.Bytes(), dgkdxf.AudioContainerOgg, dgkdxf.AudioCodecOpus, (100*960 - 312) / 48, 16000, 1},
+		{"mp3", mp3, dgkdxf.AudioContainerMp3, dgkdxf.AudioCodecMp3, 100 * 1152 * 1000 / 44100, 44100, 1},
+		{"amr", amr, dgkdxf.AudioContainerAmr, dgkdxf.AudioCodecAmrNb, 1000, 8000, 1},
+		{"m4a", testM4a(44100, 2, 44100*3), dgkdxf.AudioContainerM4a, dgkdxf.AudioCodecAac, 3000, 44100, 2},
+	}
+	for _, c := range cases {
+		info, err := dgkdxf.ProbeAudio(bytes.NewReader(c.data))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if info.Container != c.container || info.Codec != c.codec || info.DurationMillis != c.duration ||
+			info.SampleRate != c.sampleRate || info.Channels != c.channels || info.Size != int64(len(c.data)) {
+			t.Fatalf("%s: %+v", c.name, info)
+		}
+	}
+
+	if _, err := dgkdxf.ProbeAudio(bytes.NewReader([]byte("not audio"))); !errors.Is(err, dgkdxf.UnsupportedAudioErr) {
+		t.Fatalf("unsupported err: %v", err)
+	}
+}
+
+func FuzzProbeAudio(f *testing.F) {
+	m4a := testM4a(16000, 1, 16000)
+	f.Add(m4a)
+	f.Add(m4a[:len(m4a)-10])
+	// moov 中 64 位 size 的 trak 超出 int 范围
+	f.Add(append(m4a[:16:16], 0, 0, 0, 32, 'm', 'o', 'o', 'v', 0, 0, 0, 8, 'f', 'r', 'e', 'e', 0, 0, 0, 1, 't', 'r', 'a', 'k', 0x7F, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF))
+	f.Add(append(m4a[:16:16], 0, 0, 0, 32, 'm', 'o', 'o', 'v', 0, 0, 0, 8, 'f', 'r', 'e', 'e', 0, 0, 0, 1, 't', 'r', 'a', 'k', 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF))
+	f.Add([]byte("RIFF\x00\x00\x00\x00WAVE"))
+	f.Add([]byte("#!AMR\n"))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		info, err := dgkdxf.ProbeAudio(bytes.NewReader(data))
+		if err == nil && info == nil {
+			t.Fatal("nil info without err")
+		}
+	})
+}
+
+// testM4a 生成只包含 ftyp 和 moov 的 m4a，音频轨道的 mdhd 使用采样率作为 timescale
+func testM4a(sampleRate int, channels int, samples int) []byte {
+	box := func(boxType string, payload ...[]byte) []byte {
+		body := bytes.Join(payload, nil)
+		data := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+		return append(append(data, boxType...), body...)
+	}
+	u32 := func(v int) []byte { return binary.BigEndian.AppendUint32(nil, uint32(v)) }
+	u16 := func(v int) []byte { return binary.BigEndian.AppendUint16(nil, uint16(v)) }
+
+	mdhd := box("mdhd", make([]byte, 12), u32(sampleRate), u32(samples), make([]byte, 4))
+	hdlr := box("hdlr", make([]byte, 8), []byte("soun"), make([]byte, 12))
+	mp4a := box("mp4a", make([]byte, 6), u16(1), make([]byte, 8), u16(channels), u16(16), make([]byte, 4), u16(sampleRate), u16(0))
+	stsd := box("stsd", make([]byte, 4), u32(1), mp4a)
+	trak := box("trak", box("mdia", mdhd, hdlr, box("minf", box("stbl", stsd))))
+	mvhd := box("mvhd", make([]byte, 12), u32(1000), u32(samples*1000/sampleRate))
+
+	return append(box("ftyp", []byte("M4A "), u32(0)), box("moov", mvhd, trak)...)
+}
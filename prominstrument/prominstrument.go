@@ -0,0 +1,128 @@
+// Package prominstrument 将 iflytek.Client 的接口调用和 ast 会话导出为 Prometheus 指标
+package prominstrument
+
+import (
+	dgctx "github.com/darwinOrg/go-common/context"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "iflytek"
+
+// Instrumentation 实现 iflytek.Instrumentation，配置到 ClientConfig.Instrumentation
+type Instrumentation struct {
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	requestBytesSent *prometheus.CounterVec
+	astSessions      prometheus.Counter
+	astActive        prometheus.Gauge
+	astDuration      prometheus.Histogram
+	astMessages      *prometheus.CounterVec
+	astBytes         *prometheus.CounterVec
+}
+
+// New 创建并向 registerer 注册指标，registerer 为空时使用 prometheus.DefaultRegisterer；
+// buckets 为请求耗时分桶的上界，单位秒，为空时使用 iflytek.DefaultLatencyBuckets
+func New(registerer prometheus.Registerer, buckets []float64) (*Instrumentation, error) {
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	if len(buckets) == 0 {
+		for _, millis := range dgkdxf.DefaultLatencyBuckets {
+			buckets = append(buckets, millis/1000)
+		}
+	}
+
+	i := &Instrumentation{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "iflytek api calls by api and error code, code is empty on success",
+		}, []string{"api", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "iflytek api call latency including rate limit waiting",
+			Buckets:   buckets,
+		}, []string{"api"}),
+		requestBytesSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "request_sent_bytes_total",
+			Help:      "bytes uploaded by iflytek api calls",
+		}, []string{"api"}),
+		astSessions: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "ast_sessions_total",
+			Help:      "ast websocket sessions established",
+		}),
+		astActive: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "ast_active_sessions",
+			Help:      "ast websocket sessions currently open",
+		}),
+		astDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "ast_session_duration_seconds",
+			Help:      "ast websocket session duration",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+		}),
+		astMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "ast_messages_total",
+			Help:      "ast text and binary messages by direction",
+		}, []string{"direction"}),
+		astBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "ast_message_bytes_total",
+			Help:      "ast message payload bytes by direction",
+		}, []string{"direction"}),
+	}
+
+	collectors := []prometheus.Collector{i.requests, i.requestDuration, i.requestBytesSent, i.astSessions, i.astActive, i.astDuration, i.astMessages, i.astBytes}
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return i, nil
+}
+
+type callSpan struct {
+	i   *Instrumentation
+	api string
+}
+
+func (i *Instrumentation) StartCall(_ *dgctx.DgContext, call *dgkdxf.CallInfo) dgkdxf.CallSpan {
+	return &callSpan{i: i, api: string(call.Api)}
+}
+
+func (s *callSpan) Headers() map[string]string { return nil }
+
+func (s *callSpan) End(result *dgkdxf.CallResult) {
+	s.i.requests.WithLabelValues(s.api, result.ErrorCode()).Inc()
+	s.i.requestDuration.WithLabelValues(s.api).Observe(result.Duration.Seconds())
+	if result.BytesSent > 0 {
+		s.i.requestBytesSent.WithLabelValues(s.api).Add(float64(result.BytesSent))
+	}
+}
+
+type astSpan struct {
+	i *Instrumentation
+}
+
+func (i *Instrumentation) StartAstSession(*dgctx.DgContext, *dgkdxf.AstSessionInfo) dgkdxf.AstSessionSpan {
+	i.astSessions.Inc()
+	i.astActive.Inc()
+
+	return &astSpan{i: i}
+}
+
+func (s *astSpan) End(stats *dgkdxf.AstSessionStats) {
+	s.i.astActive.Dec()
+	s.i.astDuration.Observe(stats.Duration.Seconds())
+	s.i.astMessages.WithLabelValues("sent").Add(float64(stats.FramesSent))
+	s.i.astMessages.WithLabelValues("received").Add(float64(stats.FramesReceived))
+	s.i.astBytes.WithLabelValues("sent").Add(float64(stats.BytesSent))
+	s.i.astBytes.WithLabelValues("received").Add(float64(stats.BytesReceived))
+}
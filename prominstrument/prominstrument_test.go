@@ -0,0 +1,67 @@
+package prominstrument_test
+
+import (
+	"errors"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"github.com/darwinOrg/go-iflytek/prominstrument"
+	"github.com/prometheus/client_golang/prometheus"
+	"testing"
+	"time"
+)
+
+func TestInstrumentation(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	instrumentation, err := prominstrument.New(registry, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := prominstrument.New(registry, nil); err == nil {
+		t.Fatal("duplicate registration should fail")
+	}
+
+	ctx := &dgctx.DgContext{TraceId: "t1"}
+	instrumentation.StartCall(ctx, &dgkdxf.CallInfo{Api: dgkdxf.ApiAsrUpload}).End(&dgkdxf.CallResult{Duration: time.Second, BytesSent: 100})
+	instrumentation.StartCall(ctx, &dgkdxf.CallInfo{Api: dgkdxf.ApiAsrUpload}).End(&dgkdxf.CallResult{StatusCode: 502, Err: errors.New("bad gateway")})
+	instrumentation.StartAstSession(ctx, &dgkdxf.AstSessionInfo{}).End(&dgkdxf.AstSessionStats{Duration: time.Minute, FramesSent: 3, BytesSent: 30, FramesReceived: 2})
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]float64{}
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			name := family.GetName()
+			for _, label := range m.GetLabel() {
+				name += "," + label.GetName() + "=" + label.GetValue()
+			}
+			switch {
+			case m.GetCounter() != nil:
+				values[name] = m.GetCounter().GetValue()
+			case m.GetGauge() != nil:
+				values[name] = m.GetGauge().GetValue()
+			case m.GetHistogram() != nil:
+				values[name] = float64(m.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	expected := map[string]float64{
+		"iflytek_requests_total,api=asr.upload,code=":         1,
+		"iflytek_requests_total,api=asr.upload,code=http_502": 1,
+		"iflytek_request_duration_seconds,api=asr.upload":     2,
+		"iflytek_request_sent_bytes_total,api=asr.upload":     100,
+		"iflytek_ast_sessions_total":                          1,
+		"iflytek_ast_active_sessions":                         0,
+		"iflytek_ast_messages_total,direction=sent":           3,
+		"iflytek_ast_messages_total,direction=received":       2,
+		"iflytek_ast_message_bytes_total,direction=sent":      30,
+		"iflytek_ast_session_duration_seconds":                1,
+	}
+	for name, value := range expected {
+		if values[name] != value {
+			t.Fatalf("%s: %v, all: %v", name, values[name], values)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package iflytek
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	dgctx "github.com/darwinOrg/go-common/context"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Api 限流和统计的接口名，呼叫中心每个接口单独限流，如 cc.callout、cc.list_cdr_obs
+type Api string
+
+const (
+	ApiAsrUpload    Api = "asr.upload"
+	ApiAsrGetResult Api = "asr.getResult"
+	ApiAstConnect   Api = "ast.connect"
+	ApiFeature      Api = "feature"
+	ApiCc           Api = "cc" // 作为限流配置时对未单独配置的 cc 接口生效
+)
+
+// CancelContextKey DgContext 中保存 context.Context 的键，见 SetCancelContext
+const CancelContextKey = "cancelContext"
+
+// maxUsageOrders Usage 按订单去重时最多记住的订单数，超过时淘汰最早的订单
+const maxUsageOrders = 10000
+
+var RateLimitedErr = errors.New("rate limited")
+
+// SetCancelContext 关联 c 与 ctx，c 取消后使用 ctx 排队等待限流的调用立即返回 c.Err()
+func SetCancelContext(ctx *dgctx.DgContext, c context.Context) {
+	ctx.SetExtraKeyValue(CancelContextKey, c)
+}
+
+// GetCancelContext 未设置时返回 context.Background()
+func GetCancelContext(ctx *dgctx.DgContext) context.Context {
+	if ctx != nil {
+		if c, ok := ctx.GetExtraValue(CancelContextKey).(context.Context); ok && c != nil {
+			return c
+		}
+	}
+
+	return context.Background()
+}
+
+// CcApi 呼叫中心接口的限流名，callUrl 如 /cc/callout?
+func CcApi(callUrl string) Api {
+	name := strings.TrimSuffix(strings.TrimPrefix(callUrl, "/cc/"), "?")
+	return Api(string(ApiCc) + "." + name)
+}
+
+// service 接口所属的服务，用于查找服务级别的限流配置
+func (api Api) service() Api {
+	service, _, _ := strings.Cut(string(api), ".")
+	return Api(service)
+}
+
+type RateLimit struct {
+	QPS           float64 `json:"qps" yaml:"qps"`                     // 每秒发放的令牌数，<=0 时不限制
+	Burst         int     `json:"burst" yaml:"burst"`                 // 令牌桶容量，默认为 QPS 向上取整
+	MaxConcurrent int     `json:"maxConcurrent" yaml:"maxConcurrent"` // 最大并发数，ast.connect 为同时打开的连接数，<=0 时不限制
+	MaxWaitMillis int64   `json:"maxWaitMillis" yaml:"maxWaitMillis"` // 最长排队时间，超过时返回 RateLimitedErr，<=0 时一直等待
+}
+
+type RateLimitStats struct {
+	Api               Api   `json:"api"`
+	Requests          int64 `json:"requests"` // 通过限流的请求数
+	Queued            int64 `json:"queued"`   // 需要排队的请求数
+	Rejected          int64 `json:"rejected"` // 排队超时被拒绝的请求数
+	Waiting           int   `json:"waiting"`  // 当前排队数
+	InFlight          int   `json:"inFlight"` // 当前并发数
+	TotalWaitMillis   int64 `json:"totalWaitMillis"`
+	LongestWaitMillis int64 `json:"longestWaitMillis"`
+}
+
+// RateLimiter 按接口的令牌桶和并发限制。讯飞按账号限流，同一账号的多个 Client 可以共用一个 RateLimiter
+type RateLimiter struct {
+	limits  map[Api]RateLimit
+	mu      sync.Mutex
+	apis    map[Api]*apiLimiter
+	nowFunc func() time.Time
+}
+
+func NewRateLimiter(limits map[Api]RateLimit) *RateLimiter {
+	return &RateLimiter{limits: limits, apis: map[Api]*apiLimiter{}, nowFunc: time.Now}
+}
+
+type apiLimiter struct {
+	limit  RateLimit
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	slots  chan struct{}
+	stats  RateLimitStats
+}
+
+func (l *RateLimiter) limiter(api Api) *apiLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if al, ok := l.apis[api]; ok {
+		return al
+	}
+
+	limit, ok := l.limits[api]
+	if !ok {
+		limit = l.limits[api.service()]
+	}
+	if limit.Burst <= 0 {
+		limit.Burst = max(int(math.Ceil(limit.QPS)), 1)
+	}
+	al := &apiLimiter{limit: limit, tokens: float64(limit.Burst), last: l.nowFunc(), stats: RateLimitStats{Api: api}}
+	if limit.MaxConcurrent > 0 {
+		al.slots = make(chan struct{}, limit.MaxConcurrent)
+	}
+	l.apis[api] = al
+
+	return al
+}
+
+// Acquire 等待令牌和并发名额，成功后需要调用返回的 release 归还并发名额，release 可重复调用；
+// ctx 通过 SetCancelContext 关联的 context 取消时停止等待
+func (l *RateLimiter) Acquire(ctx *dgctx.DgContext, api Api) (func(), error) {
+	done := GetCancelContext(ctx)
+	if err := done.Err(); err != nil {
+		return nil, err
+	}
+	al := l.limiter(api)
+	start := l.nowFunc()
+	maxWait := time.Duration(al.limit.MaxWaitMillis) * time.Millisecond
+
+	wait, err := al.reserve(start, maxWait)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s waits %s for token", err, api, wait)
+	}
+	al.updateStats(func(stats *RateLimitStats) { stats.Waiting++ })
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-done.Done():
+			timer.Stop()
+			al.unreserve()
+			return nil, done.Err()
+		}
+	}
+	if al.slots != nil {
+		if err := al.acquireSlot(done, maxWait-wait); err != nil {
+			al.unreserve()
+			if !errors.Is(err, RateLimitedErr) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %s has %d requests in flight", err, api, al.limit.MaxConcurrent)
+		}
+	}
+
+	waited := l.nowFunc().Sub(start)
+	al.updateStats(func(stats *RateLimitStats) {
+		stats.Waiting--
+		stats.InFlight++
+		stats.Requests++
+		if waited >= time.Millisecond {
+			stats.Queued++
+			stats.TotalWaitMillis += waited.Milliseconds()
+			stats.LongestWaitMillis = max(stats.LongestWaitMillis, waited.Milliseconds())
+		}
+	})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if al.slots != nil {
+				<-al.slots
+			}
+			al.updateStats(func(stats *RateLimitStats) { stats.InFlight-- })
+		})
+	}, nil
+}
+
+// reserve 预占一个令牌，返回需要等待的时间；令牌不足时允许透支，由等待时间补偿
+func (al *apiLimiter) reserve(now time.Time, maxWait time.Duration) (time.Duration, error) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	if al.limit.QPS <= 0 {
+		return 0, nil
+	}
+
+	al.tokens = math.Min(al.tokens+now.Sub(al.last).Seconds()*al.limit.QPS, float64(al.limit.Burst))
+	al.last = now
+
+	var wait time.Duration
+	if al.tokens < 1 {
+		wait = time.Duration((1 - al.tokens) / al.limit.QPS * float64(time.Second))
+	}
+	if maxWait > 0 && wait > maxWait {
+		al.stats.Rejected++
+		return wait, RateLimitedErr
+	}
+	al.tokens--
+
+	return wait, nil
+}
+
+// unreserve 未发出请求时归还 reserve 预占的令牌，避免取消和排队超时的请求占用后续请求的配额
+func (al *apiLimiter) unreserve() {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	al.stats.Waiting--
+	if al.limit.QPS > 0 {
+		al.tokens = math.Min(al.tokens+1, float64(al.limit.Burst))
+	}
+}
+
+func (al *apiLimiter) acquireSlot(done context.Context, maxWait time.Duration) error {
+	select {
+	case al.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	// 不限制排队时间时 timeout 为 nil，只等待名额或取消
+	var timeout <-chan time.Time
+	if al.limit.MaxWaitMillis > 0 {
+		timer := time.NewTimer(max(maxWait, 0))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case al.slots <- struct{}{}:
+		return nil
+	case <-done.Done():
+		return done.Err()
+	case <-timeout:
+		al.updateStats(func(stats *RateLimitStats) { stats.Rejected++ })
+		return RateLimitedErr
+	}
+}
+
+func (al *apiLimiter) updateStats(update func(stats *RateLimitStats)) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	update(&al.stats)
+}
+
+// Stats 已调用过的接口的排队统计，按接口名排序
+func (l *RateLimiter) Stats() []RateLimitStats {
+	l.mu.Lock()
+	apis := make([]*apiLimiter, 0, len(l.apis))
+	for _, al := range l.apis {
+		apis = append(apis, al)
+	}
+	l.mu.Unlock()
+
+	stats := make([]RateLimitStats, 0, len(apis))
+	for _, al := range apis {
+		al.mu.Lock()
+		stats = append(stats, al.stats)
+		al.mu.Unlock()
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Api < stats[j].Api })
+
+	return stats
+}
+
+// rateLimiter 未配置 RateLimiter 时按 RateLimits 创建，RateLimits 也为空时不限流
+func (c *Client) rateLimiter() *RateLimiter {
+	c.limiterOnce.Do(func() {
+		c.limiter = c.Config.RateLimiter
+		if c.limiter == nil {
+			c.limiter = NewRateLimiter(c.Config.RateLimits)
+		}
+	})
+
+	return c.limiter
+}
+
+func (c *Client) acquire(ctx *dgctx.DgContext, api Api) (func(), error) {
+	return c.rateLimiter().Acquire(ctx, api)
+}
+
+// RateLimitStats 各接口的排队统计
+func (c *Client) RateLimitStats() []RateLimitStats {
+	return c.rateLimiter().Stats()
+}
+
+// Usage 累计用量，转写时长取订单完成时的 OrderInfo.RealDuration
+type Usage struct {
+	TranscribedOrders int64 `json:"transcribedOrders"`
+	TranscribedMillis int64 `json:"transcribedMillis"`
+}
+
+func (u Usage) TranscribedMinutes() float64 {
+	return float64(u.TranscribedMillis) / float64(time.Minute/time.Millisecond)
+}
+
+type usageCounter struct {
+	mu     sync.Mutex
+	usage  Usage
+	orders map[string]bool
+	queue  []string // 按完成顺序记录的订单，用于淘汰
+}
+
+// addOrder 同一订单多次查询到完成状态时只计一次，只记住最近 maxUsageOrders 个订单
+func (u *usageCounter) addOrder(orderId string, realDuration int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.orders == nil {
+		u.orders = map[string]bool{}
+	}
+	if u.orders[orderId] {
+		return
+	}
+	if len(u.queue) >= maxUsageOrders {
+		delete(u.orders, u.queue[0])
+		u.queue = u.queue[1:]
+	}
+	u.orders[orderId] = true
+	u.queue = append(u.queue, orderId)
+	u.usage.TranscribedOrders++
+	u.usage.TranscribedMillis += int64(realDuration)
+}
+
+// Usage 本 Client 累计的转写用量
+func (c *Client) Usage() Usage {
+	c.usage.mu.Lock()
+	defer c.usage.mu.Unlock()
+
+	return c.usage.usage
+}
+
+type releaseOnCloseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseOnCloseBody) Close() error {
+	b.release()
+	return b.ReadCloser.Close()
+}
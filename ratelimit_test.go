@@ -0,0 +1,125 @@
+package iflytek_test
+
+import (
+	"context"
+	"errors"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := dgkdxf.NewRateLimiter(map[dgkdxf.Api]dgkdxf.RateLimit{
+		dgkdxf.ApiAsrGetResult: {QPS: 20, Burst: 1},
+		dgkdxf.ApiAsrUpload:    {QPS: 1, MaxWaitMillis: 10},
+		dgkdxf.ApiCc:           {MaxConcurrent: 1, MaxWaitMillis: 20},
+		dgkdxf.ApiAstConnect:   {MaxConcurrent: 1},
+		dgkdxf.ApiFeature:      {QPS: 1, Burst: 2, MaxConcurrent: 1, MaxWaitMillis: 20},
+	})
+	ctx := &dgctx.DgContext{TraceId: "t1"}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := limiter.Acquire(ctx, dgkdxf.ApiAsrGetResult)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("3 requests at 20 qps took %s", elapsed)
+	}
+
+	if _, err := limiter.Acquire(ctx, dgkdxf.ApiAsrUpload); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limiter.Acquire(ctx, dgkdxf.ApiAsrUpload); !errors.Is(err, dgkdxf.RateLimitedErr) {
+		t.Fatalf("token wait over max: %v", err)
+	}
+
+	// cc 的配置对每个接口单独生效
+	release, err := limiter.Acquire(ctx, dgkdxf.CcApi("/cc/callout?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limiter.Acquire(ctx, dgkdxf.CcApi("/cc/online?")); err != nil {
+		t.Fatalf("other cc api: %v", err)
+	}
+	if _, err := limiter.Acquire(ctx, dgkdxf.CcApi("/cc/callout?")); !errors.Is(err, dgkdxf.RateLimitedErr) {
+		t.Fatalf("concurrency over max: %v", err)
+	}
+	release()
+	release()
+	if _, err := limiter.Acquire(ctx, dgkdxf.CcApi("/cc/callout?")); err != nil {
+		t.Fatalf("after release: %v", err)
+	}
+
+	// 不限制排队时间时，取消 context 后停止等待
+	if _, err := limiter.Acquire(ctx, dgkdxf.ApiAstConnect); err != nil {
+		t.Fatal(err)
+	}
+	cancelCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	waitCtx := &dgctx.DgContext{TraceId: "t2"}
+	dgkdxf.SetCancelContext(waitCtx, cancelCtx)
+	if _, err := limiter.Acquire(waitCtx, dgkdxf.ApiAstConnect); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("cancelled wait: %v", err)
+	}
+
+	// 等待并发名额超时时归还令牌，不影响后续请求
+	release, err = limiter.Acquire(ctx, dgkdxf.ApiFeature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limiter.Acquire(ctx, dgkdxf.ApiFeature); !errors.Is(err, dgkdxf.RateLimitedErr) {
+		t.Fatalf("feature concurrency over max: %v", err)
+	}
+	release()
+	if _, err := limiter.Acquire(ctx, dgkdxf.ApiFeature); err != nil {
+		t.Fatalf("token not returned: %v", err)
+	}
+
+	stats := map[dgkdxf.Api]dgkdxf.RateLimitStats{}
+	for _, s := range limiter.Stats() {
+		stats[s.Api] = s
+	}
+	if s := stats[dgkdxf.ApiAsrGetResult]; s.Requests != 3 || s.Queued != 2 || s.InFlight != 0 || s.TotalWaitMillis < 80 {
+		t.Fatalf("getResult stats: %+v", s)
+	}
+	if s := stats[dgkdxf.ApiAsrUpload]; s.Requests != 1 || s.Rejected != 1 {
+		t.Fatalf("upload stats: %+v", s)
+	}
+	if s := stats["cc.callout"]; s.Requests != 2 || s.Rejected != 1 || s.InFlight != 1 {
+		t.Fatalf("callout stats: %+v", s)
+	}
+	if s := stats[dgkdxf.ApiAstConnect]; s.Requests != 1 || s.Waiting != 0 {
+		t.Fatalf("ast stats: %+v", s)
+	}
+}
+
+func TestTranscriptionUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		orderId := r.URL.Query().Get("orderId")
+		_, _ = w.Write([]byte(`{"code":"000000","content":{"orderInfo":{"orderId":"` + orderId + `","status":4,"realDuration":90000},"orderResult":"{}"}}`))
+	}))
+	defer server.Close()
+
+	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{AccessKeyId: "ak", AccessKeySecret: "sk", Host: server.URL})
+	ctx := &dgctx.DgContext{TraceId: "t1"}
+	for _, orderId := range []string{"o1", "o1", "o2"} {
+		if _, err := client.GetAsrResult(ctx, orderId); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usage := client.Usage()
+	if usage.TranscribedOrders != 2 || usage.TranscribedMinutes() != 3 {
+		t.Fatalf("usage: %+v", usage)
+	}
+	if stats := client.RateLimitStats(); len(stats) != 1 || stats[0].Api != dgkdxf.ApiAsrGetResult || stats[0].Requests != 3 {
+		t.Fatalf("stats: %+v", stats)
+	}
+}
@@ -0,0 +1,283 @@
+package iflytek
+
+import (
+	"github.com/darwinOrg/go-common/utils"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type HiddenType int32
+
+// 与 ListCdrObsReq.HiddenType 的取值一致
+const (
+	HiddenTypeNone       HiddenType = 0 // 不隐藏
+	HiddenTypeMiddleFour HiddenType = 1 // 中间四位
+	HiddenTypeLastEight  HiddenType = 2 // 最后八位
+	HiddenTypeAll        HiddenType = 3 // 全部号码
+	HiddenTypeLastFour   HiddenType = 4 // 最后四位
+
+	// LogHiddenTypeNone 只用于 ClientConfig.LogHiddenType，显式关闭日志中的号码隐藏
+	LogHiddenTypeNone HiddenType = -1
+
+	maskChar = '*'
+)
+
+// 日志中需要整体去除的签名参数
+var secretUrlParams = []string{"Signature", "signature", "AccessKeyId", "accessKeyId", "authString", "signatureRandom"}
+
+// 日志中需要按 HiddenType 隐藏的号码参数和 json 字段
+var phoneFieldNames = []string{"customerNumber", "bindTel", "tel", "clid", "customerNumberEncrypt"}
+
+var (
+	phoneJsonFieldRegexp = regexp.MustCompile(`"(` + strings.Join(phoneFieldNames, "|") + `)"(\s*:\s*)"([^"]*)"`)
+	phoneTextRegexp      = regexp.MustCompile(`(?:\+?86[- ]?)?1[3-9]\d{9}|0\d{2,3}-?\d{7,8}`)
+	idCardTextRegexp     = regexp.MustCompile(`[1-9]\d{16}[\dXx]|[1-9]\d{14}`)
+	emailTextRegexp      = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+)
+
+// MaskPhoneNumber 按 hiddenType 将号码中的数字替换为 *，不改变长度
+func MaskPhoneNumber(number string, hiddenType HiddenType) string {
+	runes := []rune(number)
+	var digits []int
+	for i, r := range runes {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, i)
+		}
+	}
+
+	begin, end := 0, 0
+	switch hiddenType {
+	case HiddenTypeMiddleFour:
+		if len(digits) <= 4 {
+			begin, end = 0, len(digits)
+		} else {
+			begin = (len(digits) - 4) / 2
+			end = begin + 4
+		}
+	case HiddenTypeLastEight:
+		begin, end = max(len(digits)-8, 0), len(digits)
+	case HiddenTypeAll:
+		begin, end = 0, len(digits)
+	case HiddenTypeLastFour:
+		begin, end = max(len(digits)-4, 0), len(digits)
+	default:
+		return number
+	}
+
+	for _, i := range digits[begin:end] {
+		runes[i] = maskChar
+	}
+
+	return string(runes)
+}
+
+// RedactUrl 去除 url 中的签名和 AccessKeyId，并按 hiddenType 隐藏号码参数
+func RedactUrl(uri string, hiddenType HiddenType) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.RawQuery == "" {
+		return uri
+	}
+
+	query := u.Query()
+	for _, name := range secretUrlParams {
+		if query.Has(name) {
+			query.Set(name, "***")
+		}
+	}
+	for _, name := range phoneFieldNames {
+		if query.Has(name) {
+			query.Set(name, MaskPhoneNumber(query.Get(name), hiddenType))
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// RedactJson 按 hiddenType 隐藏 json 中号码字段的值
+func RedactJson(data string, hiddenType HiddenType) string {
+	if hiddenType == HiddenTypeNone {
+		return data
+	}
+
+	return phoneJsonFieldRegexp.ReplaceAllStringFunc(data, func(field string) string {
+		matches := phoneJsonFieldRegexp.FindStringSubmatch(field)
+		return `"` + matches[1] + `"` + matches[2] + `"` + MaskPhoneNumber(matches[3], hiddenType) + `"`
+	})
+}
+
+// logHiddenType 未配置时隐藏中间四位，配置为 LogHiddenTypeNone 时不隐藏
+func (c *Client) logHiddenType() HiddenType {
+	switch c.Config.LogHiddenType {
+	case HiddenTypeNone:
+		return HiddenTypeMiddleFour
+	case LogHiddenTypeNone:
+		return HiddenTypeNone
+	default:
+		return c.Config.LogHiddenType
+	}
+}
+
+func (c *Client) redactUrl(uri string) string {
+	return RedactUrl(uri, c.logHiddenType())
+}
+
+func (c *Client) redactJson(data []byte) string {
+	return RedactJson(string(data), c.logHiddenType())
+}
+
+func (c *Client) redactBean(bean any) string {
+	return RedactJson(utils.MustConvertBeanToJsonString(bean), c.logHiddenType())
+}
+
+// TranscriptRedactor 隐藏转写文本中的电话号码、身份证号和邮箱
+type TranscriptRedactor struct {
+	PhoneHiddenType HiddenType // 电话号码的隐藏方式，默认中间四位
+}
+
+func NewTranscriptRedactor() *TranscriptRedactor {
+	return &TranscriptRedactor{PhoneHiddenType: HiddenTypeMiddleFour}
+}
+
+// RedactText 隐藏文本中的敏感信息，不改变文本长度
+func (tr *TranscriptRedactor) RedactText(text string) string {
+	return strings.Join(tr.RedactWords([]string{text}), "")
+}
+
+// RedactWords 将分词结果拼接后识别敏感信息，再按原有分词边界写回，号码被拆分到多个词时也能隐藏
+func (tr *TranscriptRedactor) RedactWords(words []string) []string {
+	runes := []rune(strings.Join(words, ""))
+	text := string(runes)
+	masked := make([]rune, len(runes))
+	copy(masked, runes)
+
+	apply := func(re *regexp.Regexp, mask func(string) string) {
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			begin := len([]rune(text[:loc[0]]))
+			for i, r := range []rune(mask(text[loc[0]:loc[1]])) {
+				if r == maskChar {
+					masked[begin+i] = maskChar
+				}
+			}
+		}
+	}
+
+	apply(idCardTextRegexp, maskIdCard)
+	apply(phoneTextRegexp, func(number string) string {
+		hiddenType := tr.PhoneHiddenType
+		if hiddenType == HiddenTypeNone {
+			hiddenType = HiddenTypeMiddleFour
+		}
+		return MaskPhoneNumber(number, hiddenType)
+	})
+	apply(emailTextRegexp, maskEmail)
+
+	result := make([]string, len(words))
+	offset := 0
+	for i, word := range words {
+		length := len([]rune(word))
+		result[i] = string(masked[offset : offset+length])
+		offset += length
+	}
+
+	return result
+}
+
+// RedactOrderResult 隐藏录音转写结果中的敏感信息，json_1best 会按 Json1best 结构重新序列化
+func (tr *TranscriptRedactor) RedactOrderResult(orderResult *OrderResult) error {
+	for i, lattice := range orderResult.Lattice {
+		if lattice.Json1best == "" {
+			continue
+		}
+		json1best, err := utils.ConvertJsonStringToBean[Json1best](lattice.Json1best)
+		if err != nil {
+			return err
+		}
+
+		var words []string
+		for _, rt := range json1best.St.Rt {
+			for _, ws := range rt.Ws {
+				for _, cw := range ws.Cw {
+					words = append(words, cw.W)
+				}
+			}
+		}
+
+		redacted := tr.RedactWords(words)
+		index := 0
+		for _, rt := range json1best.St.Rt {
+			for _, ws := range rt.Ws {
+				for k := range ws.Cw {
+					ws.Cw[k].W = redacted[index]
+					index++
+				}
+			}
+		}
+
+		orderResult.Lattice[i].Json1best, err = utils.ConvertBeanToJsonString(json1best)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RedactAstResult 隐藏实时转写结果中的敏感信息
+func (tr *TranscriptRedactor) RedactAstResult(astResult *AstResult) {
+	var words []string
+	for _, rt := range astResult.Cn.St.Rt {
+		for _, ws := range rt.Ws {
+			for _, cw := range ws.Cw {
+				words = append(words, cw.W)
+			}
+		}
+	}
+
+	redacted := tr.RedactWords(words)
+	index := 0
+	for _, rt := range astResult.Cn.St.Rt {
+		for _, ws := range rt.Ws {
+			for k := range ws.Cw {
+				ws.Cw[k].W = redacted[index]
+				index++
+			}
+		}
+	}
+}
+
+// RedactSubtitles 隐藏字幕中的敏感信息
+func (tr *TranscriptRedactor) RedactSubtitles(subtitlesList []*Subtitles) {
+	for _, subtitles := range subtitlesList {
+		subtitles.Words = tr.RedactText(subtitles.Words)
+	}
+}
+
+// RedactCallTranscript 隐藏通话转写文本和字幕中的敏感信息
+func (tr *TranscriptRedactor) RedactCallTranscript(transcript *CallTranscript) {
+	for _, segment := range transcript.Segments {
+		segment.Text = tr.RedactText(segment.Text)
+	}
+	tr.RedactSubtitles(transcript.Subtitles)
+}
+
+// maskIdCard 保留前六位和最后四位
+func maskIdCard(idCard string) string {
+	runes := []rune(idCard)
+	for i := 6; i < len(runes)-4; i++ {
+		runes[i] = maskChar
+	}
+
+	return string(runes)
+}
+
+// maskEmail 保留用户名的第一个字符和域名
+func maskEmail(email string) string {
+	runes := []rune(email)
+	for i := 1; i < len(runes) && runes[i] != '@'; i++ {
+		runes[i] = maskChar
+	}
+
+	return string(runes)
+}
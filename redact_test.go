@@ -0,0 +1,45 @@
+package iflytek_test
+
+import (
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"strings"
+	"testing"
+)
+
+func TestMaskPhoneNumber(t *testing.T) {
+	cases := map[dgkdxf.HiddenType]string{
+		dgkdxf.HiddenTypeNone:       "13812345678",
+		dgkdxf.HiddenTypeMiddleFour: "138****5678",
+		dgkdxf.HiddenTypeLastEight:  "138********",
+		dgkdxf.HiddenTypeAll:        "***********",
+		dgkdxf.HiddenTypeLastFour:   "1381234****",
+	}
+	for hiddenType, expected := range cases {
+		if masked := dgkdxf.MaskPhoneNumber("13812345678", hiddenType); masked != expected {
+			t.Errorf("hiddenType %d: %s", hiddenType, masked)
+		}
+	}
+}
+
+func TestRedactUrlAndJson(t *testing.T) {
+	uri := dgkdxf.RedactUrl("https://api.iflyrec.com/cc/list_cdr_obs?AccessKeyId=ak&Signature=abc%3D&customerNumber=13812345678&cno=1001", dgkdxf.HiddenTypeMiddleFour)
+	if strings.Contains(uri, "ak&") || strings.Contains(uri, "abc") || !strings.Contains(uri, "customerNumber=138%2A%2A%2A%2A5678") {
+		t.Errorf("uri: %s", uri)
+	}
+
+	data := dgkdxf.RedactJson(`{"client":{"cno":"1001","bindTel":"13812345678"}}`, dgkdxf.HiddenTypeLastFour)
+	if data != `{"client":{"cno":"1001","bindTel":"1381234****"}}` {
+		t.Errorf("json: %s", data)
+	}
+}
+
+func TestTranscriptRedactor(t *testing.T) {
+	redactor := dgkdxf.NewTranscriptRedactor()
+	words := redactor.RedactWords([]string{"我的电话是", "138", "1234", "5678", "，邮箱 zhang.san@example.com，身份证110101199003071234"})
+	expected := []string{"我的电话是", "138", "****", "5678", "，邮箱 z********@example.com，身份证110101********1234"}
+	for i := range expected {
+		if words[i] != expected[i] {
+			t.Errorf("word %d: %s", i, words[i])
+		}
+	}
+}
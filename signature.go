@@ -0,0 +1,258 @@
+package iflytek
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	dgcoll "github.com/darwinOrg/go-common/collection"
+	"github.com/darwinOrg/go-common/model"
+	"github.com/darwinOrg/go-common/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	astAuthVersion = "v1.0"
+	astAuthParts   = 6
+
+	defaultMaxClockSkew   = 5 * time.Minute
+	defaultNonceStoreSize = 100000
+)
+
+var (
+	SignatureInvalidErr  = errors.New("signature invalid")
+	SignatureExpiredErr  = errors.New("signature expired")
+	SignatureReplayedErr = errors.New("signature replayed")
+)
+
+// NonceStore 记录已使用的随机串，用于防重放
+type NonceStore interface {
+	// Use 登记 nonce，已登记且未过期时返回 false
+	Use(nonce string, expireAt time.Time) (bool, error)
+}
+
+// MemoryNonceStore 进程内的 NonceStore，超过容量时先清理过期记录，仍然超限时拒绝新的 nonce
+type MemoryNonceStore struct {
+	mu      sync.Mutex
+	size    int
+	nonces  map[string]time.Time
+	nowFunc func() time.Time
+}
+
+func NewMemoryNonceStore(size int) *MemoryNonceStore {
+	if size <= 0 {
+		size = defaultNonceStoreSize
+	}
+
+	return &MemoryNonceStore{size: size, nonces: map[string]time.Time{}, nowFunc: time.Now}
+}
+
+func (s *MemoryNonceStore) Use(nonce string, expireAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.nowFunc()
+	if existing, ok := s.nonces[nonce]; ok && now.Before(existing) {
+		return false, nil
+	}
+	if len(s.nonces) >= s.size {
+		for key, existing := range s.nonces {
+			if !now.Before(existing) {
+				delete(s.nonces, key)
+			}
+		}
+		if len(s.nonces) >= s.size {
+			return false, errors.New("nonce store is full")
+		}
+	}
+	s.nonces[nonce] = expireAt
+
+	return true, nil
+}
+
+type SignatureVerifyOptions struct {
+	MaxClockSkew time.Duration    // 允许的时钟偏差，默认5分钟
+	MaxAge       time.Duration    // 签名的最长有效期，签名自带的有效期更长时以此为准，<=0 时不限制
+	NonceStore   NonceStore       // 为空时不做防重放校验
+	Now          func() time.Time // 当前时间，默认 ClientConfig.Clock
+}
+
+func (c *Client) verifyOptions(o *SignatureVerifyOptions) *SignatureVerifyOptions {
+	opts := SignatureVerifyOptions{}
+	if o != nil {
+		opts = *o
+	}
+	if opts.MaxClockSkew <= 0 {
+		opts.MaxClockSkew = defaultMaxClockSkew
+	}
+	if opts.Now == nil {
+		opts.Now = c.now
+	}
+
+	return &opts
+}
+
+// checkTime 校验签名时间在 [signedAt-skew, signedAt+ttl+skew] 范围内，并登记 nonce
+func (o *SignatureVerifyOptions) checkTime(signedAt time.Time, ttl time.Duration, nonce string) error {
+	now := o.Now()
+	if signedAt.After(now.Add(o.MaxClockSkew)) {
+		return fmt.Errorf("%w: signed at %s is in the future", SignatureInvalidErr, signedAt.Format(time.RFC3339))
+	}
+	if o.MaxAge > 0 && ttl > o.MaxAge {
+		ttl = o.MaxAge
+	}
+	expireAt := signedAt.Add(ttl + o.MaxClockSkew)
+	if now.After(expireAt) {
+		return SignatureExpiredErr
+	}
+
+	if o.NonceStore == nil {
+		return nil
+	}
+	if nonce == "" {
+		return fmt.Errorf("%w: missing nonce", SignatureInvalidErr)
+	}
+	ok, err := o.NonceStore.Use(nonce, expireAt)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return SignatureReplayedErr
+	}
+
+	return nil
+}
+
+// asrStringToSign 录音文件转写和声纹接口的签名串：排序后的参数
+func asrStringToSign(params []*model.KeyValuePair[string, any]) string {
+	return utils.FormUrlEncodedParams(params)
+}
+
+// ccStringToSign 呼叫中心接口的签名串：METHOD + host + path? + 排序后的参数，callUrl 以 ? 结尾
+func ccStringToSign(method string, host string, callUrl string, params []*model.KeyValuePair[string, any]) string {
+	return method + signHost(host) + callUrl + utils.FormUrlEncodedParams(params)
+}
+
+// astStringToSign 实时转写 authString 的签名串：逗号拼接后的 url 编码
+func astStringToSign(parts []string) string {
+	return url.QueryEscape(strings.Join(parts, ","))
+}
+
+func (c *Client) buildAstAuthString(creds *Credentials, dateTime string, nonce string) string {
+	parts := []string{astAuthVersion, c.Config.AppId, creds.AccessKeyId, dateTime, nonce}
+	signature := creds.sign(astStringToSign(parts))
+
+	return strings.Join(append(parts, signature), ",")
+}
+
+// canonicalParams 将查询参数转换为排序后的签名参数，exclude 中的参数不参与签名；重复的参数有歧义，视为签名无效
+func canonicalParams(query url.Values, exclude ...string) ([]*model.KeyValuePair[string, any], error) {
+	var params []*model.KeyValuePair[string, any]
+	for key, values := range query {
+		if len(values) == 0 || dgcoll.Contains(exclude, key) {
+			continue
+		}
+		if len(values) > 1 {
+			return nil, fmt.Errorf("%w: duplicated parameter %s", SignatureInvalidErr, key)
+		}
+		params = append(params, &model.KeyValuePair[string, any]{Key: key, Value: values[0]})
+	}
+	sortParams(params)
+
+	return params, nil
+}
+
+// checkSignature 依次使用 accessKeyId 对应的当前密钥和轮换重叠期内的旧密钥校验签名
+func (c *Client) checkSignature(accessKeyId string, stringToSign string, signature string) error {
+	if signature == "" {
+		return SignatureInvalidErr
+	}
+	candidates, err := c.verifyCredentials(accessKeyId)
+	if err != nil {
+		return err
+	}
+	for _, creds := range candidates {
+		// 常量时间比较，避免通过响应耗时逐字节猜出签名
+		if hmac.Equal([]byte(creds.sign(stringToSign)), []byte(signature)) {
+			return nil
+		}
+	}
+
+	return SignatureInvalidErr
+}
+
+// VerifyAsrSignature 校验录音文件转写和声纹接口的请求签名，签名在 signature 请求头中，
+// dateTime 与当前时间的偏差不能超过 MaxClockSkew，signatureRandom 作为防重放的 nonce
+func (c *Client) VerifyAsrSignature(req *http.Request, opts *SignatureVerifyOptions) error {
+	opts = c.verifyOptions(opts)
+	query := req.URL.Query()
+	params, err := canonicalParams(query)
+	if err != nil {
+		return err
+	}
+	if err := c.checkSignature(query.Get("accessKeyId"), asrStringToSign(params), req.Header.Get("signature")); err != nil {
+		return err
+	}
+
+	dateTime, err := time.Parse(dateTimeFormat, query.Get("dateTime"))
+	if err != nil {
+		return fmt.Errorf("%w: invalid dateTime %q", SignatureInvalidErr, query.Get("dateTime"))
+	}
+
+	return opts.checkTime(dateTime, 0, query.Get("signatureRandom"))
+}
+
+// VerifyCCSignature 校验呼叫中心接口及其推送的请求签名，signHost 为空时取请求的 Host；
+// 请求在 Timestamp 之后 Expires 秒内有效，Signature 本身作为防重放的 nonce
+func (c *Client) VerifyCCSignature(req *http.Request, signHost string, opts *SignatureVerifyOptions) error {
+	opts = c.verifyOptions(opts)
+	query := req.URL.Query()
+	signature := query.Get("Signature")
+	if signHost == "" {
+		signHost = req.Host
+	}
+	params, err := canonicalParams(query, "Signature")
+	if err != nil {
+		return err
+	}
+	if err := c.checkSignature(query.Get("AccessKeyId"), ccStringToSign(req.Method, signHost, req.URL.Path+"?", params), signature); err != nil {
+		return err
+	}
+
+	timestamp, err := time.ParseInLocation(timestampFormat, query.Get("Timestamp"), time.Local)
+	if err != nil {
+		return fmt.Errorf("%w: invalid Timestamp %q", SignatureInvalidErr, query.Get("Timestamp"))
+	}
+	expires, err := strconv.ParseInt(query.Get("Expires"), 10, 64)
+	if err != nil || expires < 0 {
+		return fmt.Errorf("%w: invalid Expires %q", SignatureInvalidErr, query.Get("Expires"))
+	}
+
+	return opts.checkTime(timestamp, time.Duration(expires)*time.Second, signature)
+}
+
+// VerifyAstAuthString 校验实时转写连接的 authString，格式为 v1.0,appId,accessKeyId,dateTime,uuid,signature，uuid 作为防重放的 nonce
+func (c *Client) VerifyAstAuthString(authString string, opts *SignatureVerifyOptions) error {
+	opts = c.verifyOptions(opts)
+	parts := strings.Split(authString, ",")
+	if len(parts) != astAuthParts || parts[0] != astAuthVersion {
+		return fmt.Errorf("%w: malformed authString", SignatureInvalidErr)
+	}
+	if parts[1] != c.Config.AppId {
+		return fmt.Errorf("%w: appId mismatch", SignatureInvalidErr)
+	}
+	if err := c.checkSignature(parts[2], astStringToSign(parts[:astAuthParts-1]), parts[astAuthParts-1]); err != nil {
+		return err
+	}
+
+	dateTime, err := time.Parse(dateTimeFormat, parts[3])
+	if err != nil {
+		return fmt.Errorf("%w: invalid dateTime %q", SignatureInvalidErr, parts[3])
+	}
+
+	return opts.checkTime(dateTime, 0, parts[4])
+}
@@ -0,0 +1,131 @@
+package iflytek
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	dgctx "github.com/darwinOrg/go-common/context"
+	"github.com/darwinOrg/go-common/utils"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+// signatureGoldenFile 由 -update 根据当前实现生成，只用于发现签名的意外变化。
+// 讯飞各接口文档只描述了签名步骤，没有公布固定密钥和时间下的签名示例，无法与官方示例逐字比对；
+// 签名算法由 TestSignatureScheme 按文档步骤独立计算校验，HmacSHA1 本身以 RFC 2202 的测试向量校验
+const signatureGoldenFile = "testdata/signatures.golden.json"
+
+// goldenClient 固定时间和随机串，使签名可重复
+func goldenClient() *Client {
+	return NewClient(&ClientConfig{
+		AppId:           "app-id",
+		AccessKeyId:     "access-key-id",
+		AccessKeySecret: "access-key-secret",
+		Clock: func() time.Time {
+			return time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("CST", 8*3600))
+		},
+		RandomId: func() string { return "00000000-0000-0000-0000-000000000001" },
+	})
+}
+
+func TestSignatureGolden(t *testing.T) {
+	c := goldenClient()
+	ctx := &dgctx.DgContext{TraceId: "trace-id"}
+
+	creds, err := c.credentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustUri := func(uri string, err error) string {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return uri
+	}
+
+	uploadParams := c.buildUploadParams(creds, "call.wav", 1024, 3000, "https://example.com/callback")
+	resultParams := c.buildGetResultParams(creds, "order-id")
+	featureParams, featureHeader, err := c.buildFeatureParamsAndHeader(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := map[string]string{
+		"asr.upload":            c.Config.ServiceHost(ServiceAsr) + "/v2/upload?" + utils.FormUrlEncodedParams(uploadParams) + "#" + mustUri(c.SignParams(uploadParams)),
+		"asr.getResult":         c.Config.ServiceHost(ServiceAsr) + "/v2/getResult?" + utils.FormUrlEncodedParams(resultParams) + "#" + mustUri(c.SignParams(resultParams)),
+		"feature":               c.Config.ServiceHost(ServiceFeature) + "/res/feature/v1/register?" + utils.FormUrlEncodedParams(featureParams) + "#" + featureHeader["signature"],
+		"ast":                   mustUri(c.AstUri(ctx, &AstParamConfig{Lang: "cn", Codec: "pcm", AudioEncode: AstAudioEncodePcm, Samplerate: "16000"})),
+		"cc.callout":            mustUri(c.buildPostUri("/cc/callout?")),
+		"cc.describeClient":     mustUri(c.buildDetailByCnoUri("1001")),
+		"cc.listCdrObs":         mustUri(c.buildListCdrObsUri(&ListCdrObsReq{Cno: "1001", CustomerNumber: "13800000000", Status: 3})),
+		"cc.downloadRecordFile": mustUri(c.buildDownloadRecordFileUri(&DownloadRecordFileReq{MainUniqueId: "main-unique-id", RecordSide: 2, RecordType: "record"})),
+	}
+
+	if *updateGolden {
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(actual)
+		if err := os.WriteFile(signatureGoldenFile, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(signatureGoldenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected map[string]string
+	if err := json.Unmarshal(data, &expected); err != nil {
+		t.Fatal(err)
+	}
+	for name, uri := range actual {
+		if expected[name] != uri {
+			t.Errorf("%s:\n got: %s\nwant: %s", name, uri, expected[name])
+		}
+	}
+}
+
+// TestSignatureScheme 按文档描述的签名方式独立计算 HmacSHA1 + Base64，校验签名串的拼接
+func TestSignatureScheme(t *testing.T) {
+	c := goldenClient()
+	hmacSha1 := func(s string) string {
+		mac := hmac.New(sha1.New, []byte("access-key-secret"))
+		mac.Write([]byte(s))
+		return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	// RFC 2202 test case 2
+	if signature := (&Credentials{AccessKeySecret: "Jefe"}).sign("what do ya want for nothing?"); signature != "7/zfauXrL6LSdBbV8YTfnCWafHk=" {
+		t.Fatalf("hmac-sha1 signature: %s", signature)
+	}
+
+	creds, err := c.credentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := c.buildGetResultParams(creds, "order-id")
+	asrBase := "accessKeyId=access-key-id&dateTime=2024-01-02T03%3A04%3A05%2B0800&orderId=order-id&signatureRandom=00000000-0000-0000-0000-000000000001"
+	if signature, err := c.SignParams(params); err != nil || signature != hmacSha1(asrBase) {
+		t.Fatalf("asr signature mismatch: %v", err)
+	}
+
+	ccBase := http.MethodGet + "api.iflyrec.com/cc/describe_client?AccessKeyId=access-key-id&Expires=86400&Timestamp=2024-01-02T03%3A04%3A05Z&cno=1001"
+	if uri, _ := c.buildDetailByCnoUri("1001"); uri != DefaultCcHost+"/cc/describe_client?AccessKeyId=access-key-id&Expires=86400&Timestamp=2024-01-02T03%3A04%3A05Z&cno=1001&Signature="+url.QueryEscape(hmacSha1(ccBase)) {
+		t.Fatalf("cc uri: %s", uri)
+	}
+
+	astBase := "v1.0%2Capp-id%2Caccess-key-id%2C2024-01-02T03%3A04%3A05%2B0800%2C00000000-0000-0000-0000-000000000001"
+	if authString := c.buildAstAuthString(creds, "2024-01-02T03:04:05+0800", "00000000-0000-0000-0000-000000000001"); authString != "v1.0,app-id,access-key-id,2024-01-02T03:04:05+0800,00000000-0000-0000-0000-000000000001,"+hmacSha1(astBase) {
+		t.Fatalf("ast authString: %s", authString)
+	}
+}
@@ -0,0 +1,67 @@
+package iflytek_test
+
+import (
+	"errors"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestVerifySignatures(t *testing.T) {
+	var captured *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured = r
+		_, _ = w.Write([]byte(`{"code":"000000","content":{"orderInfo":{"status":3}},"requestId":"r1"}`))
+	}))
+	defer server.Close()
+
+	ctx := &dgctx.DgContext{TraceId: "t1"}
+	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{AppId: "app", AccessKeyId: "ak", AccessKeySecret: "sk", Host: server.URL})
+	opts := &dgkdxf.SignatureVerifyOptions{NonceStore: dgkdxf.NewMemoryNonceStore(0)}
+
+	if _, err := client.GetAsrResult(ctx, "o1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.VerifyAsrSignature(captured, opts); err != nil {
+		t.Fatalf("asr: %v", err)
+	}
+	if err := client.VerifyAsrSignature(captured, opts); !errors.Is(err, dgkdxf.SignatureReplayedErr) {
+		t.Fatalf("asr replay: %v", err)
+	}
+	captured.URL.RawQuery = captured.URL.RawQuery + "&orderId=o2"
+	if err := client.VerifyAsrSignature(captured, nil); !errors.Is(err, dgkdxf.SignatureInvalidErr) {
+		t.Fatalf("asr tampered: %v", err)
+	}
+
+	if _, err := client.DetailByCno(ctx, &dgkdxf.CnoReq{Cno: "1001"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.VerifyCCSignature(captured, "", opts); err != nil {
+		t.Fatalf("cc: %v", err)
+	}
+	if err := client.VerifyCCSignature(captured, "", opts); !errors.Is(err, dgkdxf.SignatureReplayedErr) {
+		t.Fatalf("cc replay: %v", err)
+	}
+	expired := &dgkdxf.SignatureVerifyOptions{Now: func() time.Time { return time.Now().Add(25 * time.Hour) }}
+	if err := client.VerifyCCSignature(captured, "", expired); !errors.Is(err, dgkdxf.SignatureExpiredErr) {
+		t.Fatalf("cc expired: %v", err)
+	}
+
+	astUri, _ := client.AstUri(ctx, &dgkdxf.AstParamConfig{})
+	uri, _ := url.Parse(astUri)
+	authString := uri.Query().Get("authString")
+	if err := client.VerifyAstAuthString(authString, opts); err != nil {
+		t.Fatalf("ast: %v", err)
+	}
+	expired = &dgkdxf.SignatureVerifyOptions{Now: func() time.Time { return time.Now().Add(10 * time.Minute) }}
+	if err := client.VerifyAstAuthString(authString, expired); !errors.Is(err, dgkdxf.SignatureExpiredErr) {
+		t.Fatalf("ast expired: %v", err)
+	}
+	if err := dgkdxf.NewClient(&dgkdxf.ClientConfig{AppId: "app", AccessKeyId: "ak", AccessKeySecret: "other"}).VerifyAstAuthString(authString, nil); !errors.Is(err, dgkdxf.SignatureInvalidErr) {
+		t.Fatalf("ast wrong secret: %v", err)
+	}
+}
@@ -1,17 +1,31 @@
 package iflytek
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	dgerr "github.com/darwinOrg/go-common/enums/error"
+	"io"
 	"os"
 	"strings"
 )
 
+type SubtitlesFormat string
+
+const (
+	SubtitlesFormatSrt SubtitlesFormat = "srt"
+	SubtitlesFormatVtt SubtitlesFormat = "vtt"
+	SubtitlesFormatTxt SubtitlesFormat = "txt" // 每行一句，有说话人时以 "说话人: " 开头
+)
+
+var UnsupportedSubtitlesFormatErr = errors.New("unsupported subtitles format")
+
 type Subtitles struct {
 	Begin     int    `json:"begin"`
 	End       int    `json:"end"`
 	Separator string `json:"separator"`
 	Words     string `json:"words"`
+	Speaker   string `json:"speaker,omitempty"`
 }
 
 func ConvertSubtitles2SrtFormat(subtitlesList []*Subtitles, srtFile string) error {
@@ -40,3 +54,36 @@ func formatMilliSecond2SubtitlesTime(milliSecond int) string {
 
 	return fmt.Sprintf("%02d:%02d:%02d,%03d", h, m, s, ms)
 }
+
+// WriteSubtitles 按 format 将字幕写入 w，字幕为空时只写 vtt 的文件头
+func WriteSubtitles(w io.Writer, subtitlesList []*Subtitles, format SubtitlesFormat) error {
+	bw := bufio.NewWriter(w)
+	switch format {
+	case SubtitlesFormatSrt:
+		for i, subtitles := range subtitlesList {
+			fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n\n", i+1, formatMilliSecond2SubtitlesTime(subtitles.Begin), formatMilliSecond2SubtitlesTime(subtitles.End), subtitles.Words)
+		}
+	case SubtitlesFormatVtt:
+		bw.WriteString("WEBVTT\n\n")
+		for _, subtitles := range subtitlesList {
+			begin := strings.Replace(formatMilliSecond2SubtitlesTime(subtitles.Begin), ",", ".", 1)
+			end := strings.Replace(formatMilliSecond2SubtitlesTime(subtitles.End), ",", ".", 1)
+			words := subtitles.Words
+			if subtitles.Speaker != "" {
+				words = "<v " + subtitles.Speaker + ">" + words
+			}
+			fmt.Fprintf(bw, "%s --> %s\n%s\n\n", begin, end, words)
+		}
+	case SubtitlesFormatTxt:
+		for _, subtitles := range subtitlesList {
+			if subtitles.Speaker != "" {
+				bw.WriteString(subtitles.Speaker + ": ")
+			}
+			bw.WriteString(subtitles.Words + "\n")
+		}
+	default:
+		return fmt.Errorf("%w: %s", UnsupportedSubtitlesFormatErr, format)
+	}
+
+	return bw.Flush()
+}
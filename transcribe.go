@@ -0,0 +1,217 @@
+package iflytek
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	dgcoll "github.com/darwinOrg/go-common/collection"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dglogger "github.com/darwinOrg/go-logger"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type TranscriptSpeaker string
+
+const (
+	TranscriptSpeakerAgent    TranscriptSpeaker = "agent"
+	TranscriptSpeakerCustomer TranscriptSpeaker = "customer"
+
+	recordSideCustomer = 1
+	recordSideAgent    = 2
+
+	defaultTranscribePollInterval = 10 * time.Second
+	defaultTranscribeTimeout      = 2 * time.Hour
+)
+
+var TranscribeTimeoutErr = errors.New("transcribe call timeout")
+
+type TranscribeCallOptions struct {
+	AsrClient    *Client       // 语音转写使用的 Client，默认与下载录音使用同一个 Client，ClientConfig.AsrHost 已可单独配置转写地址
+	RecordType   string        // "record": 通话录音，"voicemail": 留言。默认值为 "record"
+	CallbackUrl  string        // 转写完成的回调地址
+	PollInterval time.Duration // 查询转写结果的间隔，默认10秒
+	Timeout      time.Duration // 等待转写结果的最长时间，默认2小时
+}
+
+type TranscriptSegment struct {
+	Speaker TranscriptSpeaker `json:"speaker"`
+	Begin   int               `json:"begin"` // 毫秒
+	End     int               `json:"end"`   // 毫秒
+	Text    string            `json:"text"`
+}
+
+type CallTranscript struct {
+	MainUniqueId    string               `json:"mainUniqueId"`
+	AgentOrderId    string               `json:"agentOrderId"`
+	CustomerOrderId string               `json:"customerOrderId"`
+	Segments        []*TranscriptSegment `json:"segments"`
+	Subtitles       []*Subtitles         `json:"subtitles"`
+}
+
+func (t *CallTranscript) String() string {
+	var content strings.Builder
+	for _, segment := range t.Segments {
+		content.WriteString(fmt.Sprintf("%s: %s\n", segment.Speaker, segment.Text))
+	}
+
+	return content.String()
+}
+
+type transcribeSide struct {
+	speaker    TranscriptSpeaker
+	recordSide int32
+	orderId    string
+	result     *OrderResult
+}
+
+// TranscribeCall 下载通话的双轨录音，分别转写座席侧和客户侧后按时间合并为区分说话人的文本
+func (c *Client) TranscribeCall(ctx *dgctx.DgContext, mainUniqueId string, opts *TranscribeCallOptions) (*CallTranscript, error) {
+	if opts == nil {
+		opts = &TranscribeCallOptions{}
+	}
+	asrClient := opts.AsrClient
+	if asrClient == nil {
+		asrClient = c
+	}
+	pollInterval := opts.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultTranscribePollInterval
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultTranscribeTimeout
+	}
+
+	sides := []*transcribeSide{
+		{speaker: TranscriptSpeakerAgent, recordSide: recordSideAgent},
+		{speaker: TranscriptSpeakerCustomer, recordSide: recordSideCustomer},
+	}
+
+	for _, side := range sides {
+		orderId, err := c.uploadRecordSide(ctx, asrClient, mainUniqueId, side.recordSide, opts)
+		if err != nil {
+			dglogger.Errorf(ctx, "TranscribeCall[%s] upload %s side err: %v", mainUniqueId, side.speaker, err)
+			return nil, err
+		}
+		side.orderId = orderId
+	}
+
+	deadline := time.Now().Add(timeout)
+	for _, side := range sides {
+		result, err := asrClient.waitAsrResult(ctx, side.orderId, pollInterval, deadline)
+		if err != nil {
+			dglogger.Errorf(ctx, "TranscribeCall[%s] wait %s side result err: %v", mainUniqueId, side.speaker, err)
+			return nil, err
+		}
+		side.result = result
+	}
+
+	transcript := &CallTranscript{
+		MainUniqueId:    mainUniqueId,
+		AgentOrderId:    sides[0].orderId,
+		CustomerOrderId: sides[1].orderId,
+	}
+	for _, side := range sides {
+		transcript.Segments = append(transcript.Segments, side.result.convert2Segments(side.speaker)...)
+		for _, subtitles := range side.result.Convert2Subtitles() {
+			subtitles.Speaker = string(side.speaker)
+			transcript.Subtitles = append(transcript.Subtitles, subtitles)
+		}
+	}
+	dgcoll.SortAsc(transcript.Segments, func(s *TranscriptSegment) int { return s.Begin })
+	dgcoll.SortAsc(transcript.Subtitles, func(s *Subtitles) int { return s.Begin })
+
+	return transcript, nil
+}
+
+func (c *Client) uploadRecordSide(ctx *dgctx.DgContext, asrClient *Client, mainUniqueId string, recordSide int32, opts *TranscribeCallOptions) (string, error) {
+	var buf bytes.Buffer
+	fileName, err := c.DownloadRecordFileTo(ctx, &DownloadRecordFileReq{
+		MainUniqueId: mainUniqueId,
+		RecordSide:   recordSide,
+		RecordType:   opts.RecordType,
+	}, &buf)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := ProbeAudio(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return "", err
+	}
+	if fileName == "" {
+		fileName = fmt.Sprintf("%s_%d.%s", mainUniqueId, recordSide, info.Container)
+	}
+	duration := info.DurationMillis
+
+	fileSize := int64(buf.Len())
+	ret, err := asrClient.AsrUploadReader(ctx, &buf, fileName, duration, fileSize, opts.CallbackUrl)
+	if err != nil {
+		return "", err
+	}
+
+	return ret.Content.OrderId, nil
+}
+
+// WaitAsrResult 轮询转写结果直到订单完成，pollInterval 默认10秒，timeout 默认2小时，超时返回 TranscribeTimeoutErr
+func (c *Client) WaitAsrResult(ctx *dgctx.DgContext, orderId string, pollInterval time.Duration, timeout time.Duration) (*OrderResult, error) {
+	if pollInterval <= 0 {
+		pollInterval = defaultTranscribePollInterval
+	}
+	if timeout <= 0 {
+		timeout = defaultTranscribeTimeout
+	}
+
+	return c.waitAsrResult(ctx, orderId, pollInterval, time.Now().Add(timeout))
+}
+
+// waitAsrResult 可重试的错误（限流、5xx、超时）继续轮询，DgContext 取消时返回
+func (c *Client) waitAsrResult(ctx *dgctx.DgContext, orderId string, pollInterval time.Duration, deadline time.Time) (*OrderResult, error) {
+	cancelCtx := GetCancelContext(ctx)
+	for {
+		ret, err := c.GetAsrResult(ctx, orderId)
+		switch {
+		case err != nil && !IsRetryable(err):
+			return nil, err
+		case err != nil:
+			dglogger.Warnf(ctx, "WaitAsrResult order[%s] retry after err: %v", orderId, err)
+		case ret.Content.OrderInfo.Status == orderFinishedStatus:
+			if ret.Content.OrderResult == nil {
+				return &OrderResult{}, nil
+			}
+			return ret.Content.OrderResult, nil
+		}
+
+		if time.Now().Add(pollInterval).After(deadline) {
+			if err != nil {
+				return nil, err
+			}
+			return nil, TranscribeTimeoutErr
+		}
+		timer := time.NewTimer(pollInterval)
+		select {
+		case <-cancelCtx.Done():
+			timer.Stop()
+			return nil, cancelCtx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (o *OrderResult) convert2Segments(speaker TranscriptSpeaker) []*TranscriptSegment {
+	var segments []*TranscriptSegment
+	for _, json1best := range o.json1bests() {
+		text := json1best.text()
+		if text == "" {
+			continue
+		}
+		begin, _ := strconv.Atoi(json1best.St.Bg)
+		end, _ := strconv.Atoi(json1best.St.Ed)
+
+		segments = append(segments, &TranscriptSegment{Speaker: speaker, Begin: begin, End: end, Text: text})
+	}
+
+	return segments
+}
@@ -0,0 +1,118 @@
+package iflytek_test
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	dgctx "github.com/darwinOrg/go-common/context"
+	dgkdxf "github.com/darwinOrg/go-iflytek"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWaitAsrResult(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		switch {
+		case r.URL.Query().Get("orderId") == "pending":
+			_, _ = w.Write([]byte(`{"code":"000000","content":{"orderInfo":{"status":3}}}`))
+		case polls == 1:
+			// 连接中断，可以重试
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			_ = conn.Close()
+		default:
+			_, _ = w.Write([]byte(`{"code":"000000","content":{"orderInfo":{"status":4},"orderResult":"{\"lattice\":[]}"}}`))
+		}
+	}))
+	defer server.Close()
+
+	ctx := &dgctx.DgContext{TraceId: "123"}
+	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{Host: server.URL})
+	// 可重试的错误继续轮询
+	if _, err := client.WaitAsrResult(ctx, "o1", time.Millisecond, time.Minute); err != nil || polls != 2 {
+		t.Fatalf("polls: %d, err: %v", polls, err)
+	}
+
+	cancelCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	dgkdxf.SetCancelContext(ctx, cancelCtx)
+	if _, err := client.WaitAsrResult(ctx, "pending", time.Hour, 2*time.Hour); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("cancelled wait: %v", err)
+	}
+}
+
+func TestTranscribeCall(t *testing.T) {
+	lattice := func(bg, ed, word string) map[string]string {
+		json1best, _ := json.Marshal(map[string]any{"st": map[string]any{
+			"bg": bg, "ed": ed, "rt": []any{map[string]any{"ws": []any{map[string]any{"cw": []any{map[string]any{"w": word}}}}}},
+		}})
+		return map[string]string{"json_1best": string(json1best)}
+	}
+	results := map[string][]map[string]string{
+		"agent":    {lattice("0", "1000", "您好"), lattice("3000", "4000", "再见")},
+		"customer": {lattice("1500", "2500", "你好")},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cc/download_record_file":
+			w.Header().Set("Content-Disposition", "attachment; filename=side"+r.URL.Query().Get("recordSide")+".wav")
+			_, _ = w.Write(testWav(8000, 16000))
+		case "/v2/upload":
+			orderId := "customer"
+			if r.URL.Query().Get("fileName") == "side2.wav" {
+				orderId = "agent"
+			}
+			if r.URL.Query().Get("duration") != "2000" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"code":"000000","content":{"orderId":"` + orderId + `"}}`))
+		case "/v2/getResult":
+			orderResult, _ := json.Marshal(map[string]any{"lattice": results[r.URL.Query().Get("orderId")]})
+			resp, _ := json.Marshal(map[string]any{"code": "000000", "content": map[string]any{
+				"orderInfo":   map[string]any{"status": 4},
+				"orderResult": string(orderResult),
+			}})
+			_, _ = w.Write(resp)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := &dgctx.DgContext{TraceId: "123"}
+	client := dgkdxf.NewClient(&dgkdxf.ClientConfig{Host: server.URL})
+	transcript, err := client.TranscribeCall(ctx, "m1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "agent: 您好\ncustomer: 你好\nagent: 再见\n"
+	if transcript.String() != expected {
+		t.Fatalf("transcript: %q", transcript.String())
+	}
+}
+
+// testWav 生成 16bit 单声道的静音 wav
+func testWav(sampleRate int, samples int) []byte {
+	dataSize := samples * 2
+	data := make([]byte, 44+dataSize)
+	copy(data[0:], "RIFF")
+	binary.LittleEndian.PutUint32(data[4:], uint32(36+dataSize))
+	copy(data[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(data[16:], 16)
+	binary.LittleEndian.PutUint16(data[20:], 1)
+	binary.LittleEndian.PutUint16(data[22:], 1)
+	binary.LittleEndian.PutUint32(data[24:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(data[28:], uint32(sampleRate*2))
+	binary.LittleEndian.PutUint16(data[32:], 2)
+	binary.LittleEndian.PutUint16(data[34:], 16)
+	copy(data[36:], "data")
+	binary.LittleEndian.PutUint32(data[40:], uint32(dataSize))
+	return data
+}
@@ -0,0 +1,143 @@
+package iflytek
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	wavFormatPcm        = 1
+	wavFormatExtensible = 0xFFFE
+	wavHeaderSize       = 44
+)
+
+var InvalidWavErr = errors.New("invalid wav data")
+
+type WavHeader struct {
+	AudioFormat   uint16 `json:"audioFormat"` // 1: PCM
+	Channels      int    `json:"channels"`
+	SampleRate    int    `json:"sampleRate"`
+	ByteRate      int    `json:"byteRate"`
+	BlockAlign    int    `json:"blockAlign"`
+	BitsPerSample int    `json:"bitsPerSample"`
+	DataSize      int    `json:"dataSize"`
+}
+
+// IsPcm 是否为未压缩的 PCM 数据
+func (h *WavHeader) IsPcm() bool {
+	return h.AudioFormat == wavFormatPcm || h.AudioFormat == wavFormatExtensible
+}
+
+// DurationMillis 根据 data 块长度计算的时长，毫秒
+func (h *WavHeader) DurationMillis() int64 {
+	if h.ByteRate == 0 {
+		return 0
+	}
+
+	return int64(h.DataSize) * 1000 / int64(h.ByteRate)
+}
+
+// IsWav 是否以 RIFF/WAVE 头开始
+func IsWav(data []byte) bool {
+	return len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WAVE"
+}
+
+// ParseWav 解析 wav 的 fmt 块和 data 块，返回头信息和 data 块内容；data 块长度超出实际数据时按实际数据截断
+func ParseWav(data []byte) (*WavHeader, []byte, error) {
+	if !IsWav(data) {
+		return nil, nil, InvalidWavErr
+	}
+
+	var header *WavHeader
+	var pcm []byte
+	for offset := 12; offset+8 <= len(data); {
+		chunkId := string(data[offset : offset+4])
+		chunkSize := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		body := offset + 8
+		end := body + chunkSize
+		if end > len(data) || end < body {
+			end = len(data)
+		}
+
+		switch chunkId {
+		case "fmt ":
+			if end-body < 16 {
+				return nil, nil, InvalidWavErr
+			}
+			fmtChunk := data[body:end]
+			header = &WavHeader{
+				AudioFormat:   binary.LittleEndian.Uint16(fmtChunk[0:2]),
+				Channels:      int(binary.LittleEndian.Uint16(fmtChunk[2:4])),
+				SampleRate:    int(binary.LittleEndian.Uint32(fmtChunk[4:8])),
+				ByteRate:      int(binary.LittleEndian.Uint32(fmtChunk[8:12])),
+				BlockAlign:    int(binary.LittleEndian.Uint16(fmtChunk[12:14])),
+				BitsPerSample: int(binary.LittleEndian.Uint16(fmtChunk[14:16])),
+			}
+		case "data":
+			pcm = data[body:end]
+		}
+
+		if pcm != nil && header != nil {
+			break
+		}
+		offset = end + chunkSize%2
+	}
+
+	if header == nil || pcm == nil || header.ByteRate == 0 {
+		return nil, nil, InvalidWavErr
+	}
+	header.DataSize = len(pcm)
+
+	return header, pcm, nil
+}
+
+// NewPcmWavHeader 16bit PCM 的 wav 头，DataSize 由 WriteWav 根据实际数据填充
+func NewPcmWavHeader(sampleRate int, channels int) *WavHeader {
+	return &WavHeader{
+		AudioFormat:   wavFormatPcm,
+		Channels:      channels,
+		SampleRate:    sampleRate,
+		ByteRate:      sampleRate * channels * pcmSampleSize,
+		BlockAlign:    channels * pcmSampleSize,
+		BitsPerSample: pcmSampleSize * 8,
+	}
+}
+
+// MarshalBinary 序列化为 44 字节的标准 wav 头
+func (h *WavHeader) MarshalBinary() ([]byte, error) {
+	if h.Channels <= 0 || h.SampleRate <= 0 || h.BitsPerSample <= 0 {
+		return nil, InvalidWavErr
+	}
+
+	data := make([]byte, wavHeaderSize)
+	copy(data[0:], "RIFF")
+	binary.LittleEndian.PutUint32(data[4:], uint32(wavHeaderSize-8+h.DataSize))
+	copy(data[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(data[16:], 16)
+	binary.LittleEndian.PutUint16(data[20:], h.AudioFormat)
+	binary.LittleEndian.PutUint16(data[22:], uint16(h.Channels))
+	binary.LittleEndian.PutUint32(data[24:], uint32(h.SampleRate))
+	binary.LittleEndian.PutUint32(data[28:], uint32(h.ByteRate))
+	binary.LittleEndian.PutUint16(data[32:], uint16(h.BlockAlign))
+	binary.LittleEndian.PutUint16(data[34:], uint16(h.BitsPerSample))
+	copy(data[36:], "data")
+	binary.LittleEndian.PutUint32(data[40:], uint32(h.DataSize))
+
+	return data, nil
+}
+
+// WriteWav 写入 wav 头和 data 块，header.DataSize 会被更新为 pcm 的长度
+func WriteWav(w io.Writer, header *WavHeader, pcm []byte) error {
+	header.DataSize = len(pcm)
+	data, err := header.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	_, err = w.Write(pcm)
+
+	return err
+}